@@ -47,6 +47,7 @@ require (
 	github.com/plantoncloud/kubernetes-crd-pulumi-types v0.0.0-20240903231550-b86827cb2eb9
 	github.com/plantoncloud/pulumi-module-golang-commons v0.0.0-20241003110703-1d27875e1587
 	github.com/pulumi/pulumi-gcp/sdk/v7 v7.29.0
+	gopkg.in/yaml.v3 v3.0.1
 )
 
 require (
@@ -133,7 +134,6 @@ require (
 	google.golang.org/genproto/googleapis/rpc v0.0.0-20240604185151-ef581f913117 // indirect
 	google.golang.org/protobuf v1.34.2 // indirect
 	gopkg.in/warnings.v0 v0.1.2 // indirect
-	gopkg.in/yaml.v3 v3.0.1 // indirect
 	lukechampine.com/frand v1.4.2 // indirect
 	sigs.k8s.io/yaml v1.4.0 // indirect
 )
@@ -0,0 +1,82 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// Input contains everything an addon may need for its installation.
+type Input struct {
+	Locals             *localz.Locals
+	CreatedCluster     *container.Cluster
+	GcpProvider        *gcp.Provider
+	KubernetesProvider *pulumikubernetes.Provider
+	// Installed holds the components of the addons installed before the addon, keyed by the addon name.
+	Installed map[string]pulumi.Resource
+	// ResourceOptions are added to the component of the addon with the name of the key, ex: transformations of all
+	//the resources of the addon.
+	ResourceOptions map[string][]pulumi.ResourceOption
+
+	//opts are the resource options of the addon being installed
+	opts []pulumi.ResourceOption
+}
+
+// Requirement is an input that an addon needs to be present for its installation.
+type Requirement string
+
+const (
+	RequiresCreatedCluster     Requirement = "created-cluster"
+	RequiresGcpProvider        Requirement = "gcp-provider"
+	RequiresKubernetesProvider Requirement = "kubernetes-provider"
+)
+
+// Addon describes a kubernetes addon that can be installed on the cluster.
+//
+// addons register themselves with the registry from the file that implements them, so a new addon can be added
+// without changes to the code that installs the addons.
+type Addon struct {
+	Name string
+	// IsEnabled reports whether the addon is to be installed for the cluster.
+	IsEnabled func(locals *localz.Locals) bool
+	// Requires lists the inputs which must be present for the installation.
+	Requires []Requirement
+	// DependsOn lists the addons which must be enabled and installed before this addon.
+	DependsOn []string
+	// After lists the addons which, only when enabled, are installed before this addon.
+	After []string
+	// HelmCharts lists the names of the helm charts installed by the addon.
+	HelmCharts []string
+	// ProtectedValues lists the dotted paths of helm values the module depends on, ex: "serviceAccount.name".
+	//the values can not be overridden from the addon settings.
+	ProtectedValues []string
+	// Install creates the component of the addon along with its resources and returns the component.
+	Install func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error)
+	// Removal guards the removal of the addon once it is disabled. nil for addons without custom resources that
+	//need to be checked before the addon is removed.
+	Removal *Removal
+}
+
+// Removal describes the custom resources of an addon, ex: the postgres clusters of an operator, which block the
+// removal of the addon while they exist.
+type Removal struct {
+	// Crds are the names of the crds of the addon in the order in which their custom resources, and then the crds
+	//themselves, are deleted. crds of custom resources that depend on the ones of other crds come first,
+	//ex: "kafkatopics.kafka.strimzi.io" before "kafkas.kafka.strimzi.io".
+	Crds []string
+}
+
+// isAvailable reports whether the input required by the requirement is present.
+func (i *Input) isAvailable(r Requirement) bool {
+	switch r {
+	case RequiresCreatedCluster:
+		return i.CreatedCluster != nil
+	case RequiresGcpProvider:
+		return i.GcpProvider != nil
+	case RequiresKubernetesProvider:
+		return i.KubernetesProvider != nil
+	}
+	return false
+}
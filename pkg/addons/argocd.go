@@ -0,0 +1,342 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	externalsecretsv1 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/externalsecrets/externalsecrets/v1beta1"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+const (
+	argoCdAddonName = "argo-cd"
+	// ArgoCdResourceType is the pulumi type token of the argo-cd addon component.
+	ArgoCdResourceType = "planton:gke:ArgoCdAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: argoCdAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.ArgoCd != nil
+		},
+		Requires: []Requirement{RequiresKubernetesProvider},
+		//the addons used for the ingress and the sso of argo cd, when enabled
+		After:           []string{istioAddonName, ingressNginxAddonName, certManagerAddonName, externalSecretsAddonName},
+		HelmCharts:      []string{vars.ArgoCd.HelmChartName},
+		ProtectedValues: []string{"fullnameOverride"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ArgoCd(ctx, input.Locals, input.KubernetesProvider, input.Installed, input.opts...)
+		},
+	})
+}
+
+// ArgoCdAddon is the component of the argo-cd addon.
+type ArgoCdAddon struct {
+	pulumi.ResourceState
+
+	// Url of the argo cd web interface, empty when argo cd is not exposed on an ingress.
+	Url pulumi.StringOutput
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+	// Ingress is only created when an ingress is configured.
+	Ingress *IngressExposure
+	// OidcSecret syncs the google oauth client from secret manager, only created when sso is configured.
+	OidcSecret *externalsecretsv1.ExternalSecret
+	// RootApplication is only created when a root application is configured.
+	RootApplication *apiextensions.CustomResource
+}
+
+// ArgoCd installs Argo CD in the Kubernetes cluster using Helm, optionally exposes it on one of the ingress dns
+// domains with sign-in through Google, and bootstraps the applications of the cluster from a root application.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - installedAddons: The components of the installed addons keyed by the addon name, used for the ingress and sso.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *ArgoCdAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates a namespace for Argo CD and labels it with metadata from locals.
+//  3. When sso is configured, creates an external-secret which syncs the google oauth client from secret manager.
+//  4. Deploys the Argo CD Helm chart into the created namespace with the url, oidc and rbac configuration.
+//  5. When an ingress is configured, exposes the argo cd server on the hostname through istio or ingress-nginx.
+//  6. When a root application is configured, waits for argo cd to be ready and creates the application.
+func ArgoCd(ctx *pulumi.Context, locals *localz.Locals, kubernetesProvider *pulumikubernetes.Provider,
+	installedAddons map[string]pulumi.Resource, opts ...pulumi.ResourceOption) (*ArgoCdAddon, error) {
+	settings := locals.Options.ArgoCd
+
+	createdAddon := &ArgoCdAddon{Url: pulumi.String("").ToStringOutput()}
+	err := ctx.RegisterComponentResource(ArgoCdResourceType, argoCdAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register argo-cd addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.ArgoCd.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.ArgoCd.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.ArgoCd.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create argo-cd namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	var ingress *resolvedIngress
+	if settings.Ingress != nil {
+		ingress, err = resolveIngress(locals, vars.ArgoCd.HostnamePrefix, settings.Ingress)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ingress")
+		}
+		createdAddon.Url = pulumi.String(ingress.url()).ToStringOutput()
+	}
+
+	releaseDependencies := make([]pulumi.Resource, 0)
+	if settings.Sso != nil {
+		installedExternalSecrets, ok := installedAddons[externalSecretsAddonName]
+		if !ok {
+			return nil, errors.Errorf("sso requires the %s addon", externalSecretsAddonName)
+		}
+		//the secret is labeled as part of argo cd, so that its keys can be referenced from the argo cd config
+		createdOidcSecret, err := externalsecretsv1.NewExternalSecret(ctx,
+			vars.ArgoCd.OidcSecretName,
+			&externalsecretsv1.ExternalSecretArgs{
+				Metadata: metav1.ObjectMetaArgs{
+					Name:      pulumi.String(vars.ArgoCd.OidcSecretName),
+					Namespace: createdNamespace.Metadata.Name().Elem(),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				},
+				Spec: externalsecretsv1.ExternalSecretSpecArgs{
+					RefreshInterval: pulumi.String("1h"),
+					SecretStoreRef: externalsecretsv1.ExternalSecretSpecSecretStoreRefArgs{
+						Kind: pulumi.String("ClusterSecretStore"),
+						Name: pulumi.String(vars.ExternalSecrets.GcpSecretsManagerClusterSecretStoreName),
+					},
+					Target: externalsecretsv1.ExternalSecretSpecTargetArgs{
+						Name: pulumi.String(vars.ArgoCd.OidcSecretName),
+						Template: externalsecretsv1.ExternalSecretSpecTargetTemplateArgs{
+							Metadata: externalsecretsv1.ExternalSecretSpecTargetTemplateMetadataArgs{
+								Labels: pulumi.StringMap{
+									"app.kubernetes.io/part-of": pulumi.String("argocd"),
+								},
+							},
+						},
+					},
+					Data: externalsecretsv1.ExternalSecretSpecDataArray{
+						externalsecretsv1.ExternalSecretSpecDataArgs{
+							SecretKey: pulumi.String("clientId"),
+							RemoteRef: externalsecretsv1.ExternalSecretSpecDataRemoteRefArgs{
+								Key: pulumi.String(settings.Sso.ClientIdSecret),
+							},
+						},
+						externalsecretsv1.ExternalSecretSpecDataArgs{
+							SecretKey: pulumi.String("clientSecret"),
+							RemoteRef: externalsecretsv1.ExternalSecretSpecDataRemoteRefArgs{
+								Key: pulumi.String(settings.Sso.ClientSecretSecret),
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(createdNamespace),
+			pulumi.DependsOn([]pulumi.Resource{installedExternalSecrets}))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create external-secret for the google oauth client")
+		}
+		createdAddon.OidcSecret = createdOidcSecret
+		releaseDependencies = append(releaseDependencies, createdOidcSecret)
+	}
+
+	releaseSettings := helmReleaseSettings(locals, argoCdAddonName)
+	chartSource := helmChartSource(locals, argoCdAddonName, vars.ArgoCd.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "argo-cd",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.ArgoCd.FullName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.ArgoCd.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, argoCdAddonName, vars.ArgoCd.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, argoCdAddonName, vars.ArgoCd.HelmChartName,
+				argoCdValues(settings, ingress)),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.DependsOn(releaseDependencies),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if ingress != nil {
+		createdIngress, err := exposeService(ctx, locals, ingress,
+			&exposedService{
+				addonName: argoCdAddonName,
+				namespace: vars.ArgoCd.Namespace,
+				name:      fmt.Sprintf("%s-server", vars.ArgoCd.FullName),
+				port:      vars.ArgoCd.ServerPort,
+			}, installedAddons, createdHelmRelease)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to expose argo-cd server")
+		}
+		createdAddon.Ingress = createdIngress
+	}
+
+	if settings.RootApplication != nil {
+		//wait for the application controller as the root application is synced right away
+		createdReadinessJob, err := waitForReadiness(ctx, locals,
+			&readinessGate{
+				addonName: argoCdAddonName,
+				namespace: vars.ArgoCd.Namespace,
+				crds:      []string{"applications.argoproj.io", "appprojects.argoproj.io"},
+				deployments: []string{
+					fmt.Sprintf("%s-server", vars.ArgoCd.FullName),
+					fmt.Sprintf("%s-repo-server", vars.ArgoCd.FullName),
+				},
+				statefulsets: []string{fmt.Sprintf("%s-application-controller", vars.ArgoCd.FullName)},
+			}, createdHelmRelease)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to wait for argo-cd readiness")
+		}
+
+		path := settings.RootApplication.Path
+		if path == "" {
+			path = "."
+		}
+		targetRevision := settings.RootApplication.TargetRevision
+		if targetRevision == "" {
+			targetRevision = "HEAD"
+		}
+		createdRootApplication, err := apiextensions.NewCustomResource(ctx,
+			vars.ArgoCd.RootApplicationName,
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String("argoproj.io/v1alpha1"),
+				Kind:       pulumi.String("Application"),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String(vars.ArgoCd.RootApplicationName),
+						Namespace: createdNamespace.Metadata.Name(),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": pulumi.Map{
+						"project": pulumi.String("default"),
+						"source": pulumi.Map{
+							"repoURL":        pulumi.String(settings.RootApplication.RepoUrl),
+							"path":           pulumi.String(path),
+							"targetRevision": pulumi.String(targetRevision),
+						},
+						"destination": pulumi.Map{
+							"server":    pulumi.String("https://kubernetes.default.svc"),
+							"namespace": createdNamespace.Metadata.Name(),
+						},
+						"syncPolicy": pulumi.Map{
+							"automated": pulumi.Map{
+								"prune":    pulumi.Bool(true),
+								"selfHeal": pulumi.Bool(true),
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(createdHelmRelease),
+			pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create argo-cd root application")
+		}
+		createdAddon.RootApplication = createdRootApplication
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+		"url":       createdAddon.Url,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register argo-cd addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// argoCdValues returns the helm values of argo cd with the url, oidc and rbac configuration. the ingress is nil when
+// argo cd is not exposed, and sso is only configured along with the ingress.
+func argoCdValues(settings *options.ArgoCd, ingress *resolvedIngress) pulumi.Map {
+	//tls is terminated by the ingress, dex is not needed as argo cd signs users in with google directly
+	values := pulumi.Map{
+		"fullnameOverride": pulumi.String(vars.ArgoCd.FullName),
+		"dex": pulumi.Map{
+			"enabled": pulumi.Bool(false),
+		},
+	}
+	configs := pulumi.Map{
+		"params": pulumi.Map{
+			"server.insecure": pulumi.Bool(true),
+		},
+	}
+	values["configs"] = configs
+
+	if ingress == nil {
+		return values
+	}
+	values["global"] = pulumi.Map{
+		"domain": pulumi.String(ingress.hostname),
+	}
+	cm := pulumi.Map{
+		"url": pulumi.String(ingress.url()),
+	}
+	configs["cm"] = cm
+
+	if settings.Sso != nil {
+		cm["oidc.config"] = pulumi.String(argoCdOidcConfig())
+		configs["rbac"] = pulumi.Map{
+			"policy.default": pulumi.String("role:readonly"),
+			"policy.csv":     pulumi.String(argoCdRbacPolicy(settings.Sso.AdminEmails)),
+			"scopes":         pulumi.String("[email]"),
+		}
+	}
+	return values
+}
+
+// argoCdOidcConfig returns the oidc config of argo cd for signing in with google. the client id and secret are
+// referenced from the secret synced from secret manager.
+func argoCdOidcConfig() string {
+	return fmt.Sprintf(`name: Google
+issuer: https://accounts.google.com
+clientID: $%[1]s:clientId
+clientSecret: $%[1]s:clientSecret
+requestedScopes: ["openid", "profile", "email"]
+`, vars.ArgoCd.OidcSecretName)
+}
+
+// argoCdRbacPolicy returns the rbac policy of argo cd which grants the admin role to the emails.
+func argoCdRbacPolicy(adminEmails []string) string {
+	lines := make([]string, 0)
+	for _, email := range adminEmails {
+		lines = append(lines, fmt.Sprintf("g, %s, role:admin", email))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,90 @@
+package addons
+
+import (
+	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestArgoCdValues(t *testing.T) {
+	ingress := &resolvedIngress{
+		hostname:   "argocd.example.com",
+		controller: options.IngressControllerIstio,
+		domain:     &gkeclusterv1.IngressDnsDomain{Name: "example.com", IsTlsEnabled: true},
+	}
+	sso := &options.ArgoCdSso{ClientIdSecret: "client-id", ClientSecretSecret: "client-secret",
+		AdminEmails: []string{"alice@example.com", "bob@example.com"}}
+	values := func(configs pulumi.Map, global pulumi.Map) pulumi.Map {
+		want := pulumi.Map{
+			"fullnameOverride": pulumi.String(vars.ArgoCd.FullName),
+			"dex":              pulumi.Map{"enabled": pulumi.Bool(false)},
+			"configs":          configs,
+		}
+		if global != nil {
+			want["global"] = global
+		}
+		return want
+	}
+	params := pulumi.Map{"server.insecure": pulumi.Bool(true)}
+	tests := []struct {
+		name     string
+		settings *options.ArgoCd
+		ingress  *resolvedIngress
+		want     pulumi.Map
+	}{
+		{
+			name:     "not exposed",
+			settings: &options.ArgoCd{},
+			want:     values(pulumi.Map{"params": params}, nil),
+		},
+		{
+			name:     "exposed on the ingress",
+			settings: &options.ArgoCd{Ingress: &options.Ingress{Domain: "example.com"}},
+			ingress:  ingress,
+			want: values(pulumi.Map{
+				"params": params,
+				"cm":     pulumi.Map{"url": pulumi.String("https://argocd.example.com")},
+			}, pulumi.Map{"domain": pulumi.String("argocd.example.com")}),
+		},
+		{
+			name:     "sso with admins",
+			settings: &options.ArgoCd{Ingress: &options.Ingress{Domain: "example.com"}, Sso: sso},
+			ingress:  ingress,
+			want: values(pulumi.Map{
+				"params": params,
+				"cm": pulumi.Map{
+					"url":         pulumi.String("https://argocd.example.com"),
+					"oidc.config": pulumi.String(argoCdOidcConfig()),
+				},
+				"rbac": pulumi.Map{
+					"policy.default": pulumi.String("role:readonly"),
+					"policy.csv":     pulumi.String("g, alice@example.com, role:admin\ng, bob@example.com, role:admin"),
+					"scopes":         pulumi.String("[email]"),
+				},
+			}, pulumi.Map{"domain": pulumi.String("argocd.example.com")}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := argoCdValues(tt.settings, tt.ingress); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("argoCdValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgoCdOidcConfig(t *testing.T) {
+	//the client credentials are referenced from the secret synced by external-secrets, not inlined in the config map
+	for _, want := range []string{
+		"clientID: $" + vars.ArgoCd.OidcSecretName + ":clientId\n",
+		"clientSecret: $" + vars.ArgoCd.OidcSecretName + ":clientSecret\n",
+	} {
+		if got := argoCdOidcConfig(); !strings.Contains(got, want) {
+			t.Errorf("argoCdOidcConfig() = %q, want a line %q", got, want)
+		}
+	}
+}
@@ -6,10 +6,10 @@ import (
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
 	certmanagerv1 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/certmanager/certmanager/v1"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
-	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
 	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
@@ -17,6 +17,42 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	certManagerAddonName = "cert-manager"
+	// CertManagerResourceType is the pulumi type token of the cert-manager addon component.
+	CertManagerResourceType = "planton:gke:CertManagerAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: certManagerAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallCertManager()
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		After:           []string{istioAddonName, ingressNginxAddonName},
+		HelmCharts:      []string{vars.CertManager.HelmChartName},
+		ProtectedValues: []string{"installCRDs", "serviceAccount.create", "serviceAccount.name"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return CertManager(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// CertManagerAddon is the component of the cert-manager addon.
+type CertManagerAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	HelmRelease      *helm.Release
+	// ClusterIssuers are keyed by the name of the ingress domain they issue certificates for.
+	ClusterIssuers map[string]*certmanagerv1.ClusterIssuer
+}
+
 // CertManager installs Cert Manager in the Kubernetes cluster using Helm, sets up the necessary Google Service Account (GSA),
 // Kubernetes Service Account (KSA), and creates a self-signed ClusterIssuer.
 //
@@ -26,55 +62,32 @@ import (
 // - createdCluster: The GKE cluster where Cert Manager will be installed.
 // - gcpProvider: The GCP provider for Pulumi.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *CertManagerAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a Google Service Account (GSA) for Cert Manager with a description and display name.
-// 2. Exports the email of the created GSA.
-// 3. Creates a Workload Identity binding for the GSA to allow it to act as the Kubernetes Service Account (KSA).
-// 4. Creates a namespace for Cert Manager and labels it with metadata from locals.
-// 5. Creates a Kubernetes Service Account (KSA) and adds the Google Workload Identity annotation with the GSA email.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for Cert Manager and labels it with metadata from locals.
+// 3. Creates the workload identity for Cert Manager, i.e. a Google Service Account (GSA), the Workload Identity binding
+// and a Kubernetes Service Account (KSA) annotated with the GSA email.
+// 4. Exports the email of the created GSA.
+// 5. Grants the GSA the Cloud DNS admin role in each distinct project hosting the DNS zones of TLS enabled domains.
 // 6. Deploys the Cert Manager Helm chart into the created namespace with specific values for CRDs, service account.
+// 7. Waits for the CRDs to be established and the controller, webhook and cainjector to be available.
+// 8. Creates a ClusterIssuer for each TLS enabled ingress domain.
 func CertManager(ctx *pulumi.Context, locals *localz.Locals,
 	createdCluster *container.Cluster,
 	gcpProvider *gcp.Provider,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*CertManagerAddon, error) {
 
-	//create google service account required to create workload identity binding
-	createdGoogleServiceAccount, err := serviceaccount.NewAccount(ctx,
-		vars.CertManager.KsaName,
-		&serviceaccount.AccountArgs{
-			Project:     createdCluster.Project,
-			Description: pulumi.String("cert-manager service account for solving dns challenges to issue certificates"),
-			AccountId:   pulumi.String(vars.CertManager.KsaName),
-			DisplayName: pulumi.String(vars.CertManager.KsaName),
-		}, pulumi.Parent(createdCluster), pulumi.Provider(gcpProvider))
+	createdAddon := &CertManagerAddon{ClusterIssuers: make(map[string]*certmanagerv1.ClusterIssuer)}
+	err := ctx.RegisterComponentResource(CertManagerResourceType, certManagerAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
 	if err != nil {
-		return errors.Wrap(err, "failed to create cert-manager google service account")
-	}
-
-	//export cert-manager gsa email
-	ctx.Export(outputs.CertManagerGsaEmail, createdGoogleServiceAccount.Email)
-
-	//create workload-identity binding
-	_, err = serviceaccount.NewIAMBinding(ctx,
-		fmt.Sprintf("%s-workload-identity", vars.CertManager.KsaName),
-		&serviceaccount.IAMBindingArgs{
-			ServiceAccountId: createdGoogleServiceAccount.Name,
-			Role:             pulumi.String("roles/iam.workloadIdentityUser"),
-			Members: pulumi.StringArray{
-				pulumi.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]",
-					createdCluster.Project,
-					vars.CertManager.Namespace,
-					vars.CertManager.KsaName),
-			},
-		},
-		pulumi.Parent(createdGoogleServiceAccount),
-		pulumi.DependsOn([]pulumi.Resource{createdCluster}))
-	if err != nil {
-		return errors.Wrap(err, "failed to create workload-identity binding for cert-manager")
+		return nil, errors.Wrap(err, "failed to register cert-manager addon component")
 	}
 
 	//create namespace resource
@@ -84,47 +97,68 @@ func CertManager(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.CertManager.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.CertManager.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create cert-manager namespace")
+		return nil, errors.Wrapf(err, "failed to create cert-manager namespace")
 	}
+	createdAddon.Namespace = createdNamespace
 
+	//create the google service account and the kubernetes service account to be used by cert-manager.
 	//it is not straight forward to add the gsa email as one of the helm values.
-	// so, instead, disable service account creation in helm release and create it separately add
-	// the Google workload identity annotation to the service account which requires the email id
-	// of the Google service account added as part of IAM module.
-	createdKubernetesServiceAccount, err := corev1.NewServiceAccount(ctx,
+	// so, instead, disable service account creation in helm release and create it separately with
+	// the Google workload identity annotation which requires the email id of the Google service account.
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
 		vars.CertManager.KsaName,
-		&corev1.ServiceAccountArgs{
-			Metadata: metav1.ObjectMetaPtrInput(
-				&metav1.ObjectMetaArgs{
-					Name:      pulumi.String(vars.CertManager.KsaName),
-					Namespace: createdNamespace.Metadata.Name(),
-					Annotations: pulumi.StringMap{
-						vars.WorkloadIdentityKubeAnnotationKey: createdGoogleServiceAccount.Email,
-					},
-				}),
-		}, pulumi.Parent(createdNamespace))
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.CertManager.KsaName),
+			GsaAccountId:   locals.LegacyGsaAccountId(vars.CertManager.KsaName),
+			GsaDescription: "cert-manager service account for solving dns challenges to issue certificates",
+			MovedFrom: &workloadidentity.MovedFrom{
+				GsaParent:    createdCluster,
+				KsaParentUrn: rootNamespaceUrn(ctx, vars.CertManager.Namespace),
+			},
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for cert-manager")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export cert-manager gsa email
+	ctx.Export(outputs.CertManagerGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	//grant dns admin role in the projects of the dns-zones for which dns01 challenges need to be solved
+	createdDnsZoneIamMembers, err := dnsZoneIamMembers(ctx,
+		vars.CertManager.KsaName,
+		createdWorkloadIdentity.GoogleServiceAccount,
+		dnsZoneProjectIds(locals.GkeCluster.Spec.IngressDnsDomains, true),
+		gcpProvider)
 	if err != nil {
-		return errors.Wrap(err, "failed to create kubernetes service account")
+		return nil, errors.Wrap(err, "failed to grant dns permissions for cert-manager")
 	}
 
+	releaseSettings := helmReleaseSettings(locals, certManagerAddonName)
+	chartSource := helmChartSource(locals, certManagerAddonName, vars.CertManager.HelmChartRepo)
+
 	//created helm-release
 	createdCertManagerHelmRelease, err := helm.NewRelease(ctx, "cert-manager",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.CertManager.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.CertManager.HelmChartName),
-			Version:         pulumi.String(vars.CertManager.HelmChartVersion),
+			Chart:           chartSource.chart(vars.CertManager.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, certManagerAddonName, vars.CertManager.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, certManagerAddonName, vars.CertManager.HelmChartName, pulumi.Map{
 				"installCRDs": pulumi.Bool(true),
 				//https://cert-manager.io/docs/configuration/acme/dns01/#setting-nameservers-for-dns01-self-check
 				//https://github.com/cert-manager/cert-manager/issues/1163#issuecomment-484171354
@@ -136,15 +170,31 @@ func CertManager(ctx *pulumi.Context, locals *localz.Locals,
 					"create": pulumi.Bool(false),
 					"name":   pulumi.String(vars.CertManager.KsaName),
 				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.CertManager.HelmChartRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.DependsOn([]pulumi.Resource{createdKubernetesServiceAccount}),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.DependsOn(append([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}, createdDnsZoneIamMembers...)),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create cert-manager helm release")
+		return nil, errors.Wrap(err, "failed to create cert-manager helm release")
+	}
+	createdAddon.HelmRelease = createdCertManagerHelmRelease
+
+	//wait for the webhook to be serving as cluster-issuers are validated by it
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName: certManagerAddonName,
+			namespace: vars.CertManager.Namespace,
+			crds:      []string{"clusterissuers.cert-manager.io", "certificates.cert-manager.io"},
+			deployments: []string{
+				vars.CertManager.HelmChartName,
+				fmt.Sprintf("%s-webhook", vars.CertManager.HelmChartName),
+				fmt.Sprintf("%s-cainjector", vars.CertManager.HelmChartName),
+			},
+		}, createdCertManagerHelmRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for cert-manager readiness")
 	}
 
 	//for each ingress-domain, create a cluster-issuer
@@ -153,7 +203,7 @@ func CertManager(ctx *pulumi.Context, locals *localz.Locals,
 		if !i.IsTlsEnabled {
 			continue
 		}
-		_, err := certmanagerv1.NewClusterIssuer(ctx,
+		createdClusterIssuer, err := certmanagerv1.NewClusterIssuer(ctx,
 			i.Name,
 			&certmanagerv1.ClusterIssuerArgs{
 				Metadata: metav1.ObjectMetaArgs{
@@ -185,11 +235,19 @@ func CertManager(ctx *pulumi.Context, locals *localz.Locals,
 						},
 					},
 				},
-			}, pulumi.Parent(createdCertManagerHelmRelease))
+			}, pulumi.Parent(createdCertManagerHelmRelease),
+			pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
 		if err != nil {
-			return errors.Wrapf(err, "failed to create cluster-issuer for %s ingress-domain", i.Name)
+			return nil, errors.Wrapf(err, "failed to create cluster-issuer for %s ingress-domain", i.Name)
 		}
+		createdAddon.ClusterIssuers[i.Name] = createdClusterIssuer
 	}
 
-	return nil
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"gsaEmail":  createdAddon.GsaEmail,
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register cert-manager addon outputs")
+	}
+	return createdAddon, nil
 }
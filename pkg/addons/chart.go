@@ -0,0 +1,145 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+// chartVersion returns the chart version pinned in the addon settings or the default version from vars.
+func chartVersion(locals *localz.Locals, addonName, defaultVersion string) string {
+	if settings := locals.Options.Addon(addonName); settings != nil && settings.ChartVersion != "" {
+		return settings.ChartVersion
+	}
+	return defaultVersion
+}
+
+// resolvedChartSource is where the helm charts of an addon are pulled from.
+type resolvedChartSource struct {
+	repo string
+	//charts of an oci registry are referenced by their url instead of by their name in a repository
+	oci      bool
+	username string
+	password string
+}
+
+// helmChartSource resolves the source of the helm charts of the addon from the chart repository configured in the
+// addon settings, the chart mirror and the default repository from vars, in that order.
+func helmChartSource(locals *localz.Locals, addonName, defaultRepo string) *resolvedChartSource {
+	if settings := locals.Options.Addon(addonName); settings != nil && settings.ChartRepo != "" {
+		return &resolvedChartSource{
+			repo: strings.TrimSuffix(settings.ChartRepo, "/"),
+			oci:  strings.HasPrefix(settings.ChartRepo, "oci://"),
+		}
+	}
+	if mirror := mirrorSettings(locals); mirror != nil && mirror.ChartRepo != "" {
+		return &resolvedChartSource{
+			repo:     strings.TrimSuffix(mirror.ChartRepo, "/"),
+			oci:      mirror.IsOciChartRepo(),
+			username: mirror.Username,
+			password: mirror.Password,
+		}
+	}
+	return &resolvedChartSource{repo: defaultRepo}
+}
+
+// chart returns the chart to be set in the helm release.
+func (c *resolvedChartSource) chart(chartName string) pulumi.StringInput {
+	if c.oci {
+		return pulumi.String(fmt.Sprintf("%s/%s", c.repo, chartName))
+	}
+	return pulumi.String(chartName)
+}
+
+// repositoryOpts returns the repository options to be set in the helm release.
+func (c *resolvedChartSource) repositoryOpts() helm.RepositoryOptsArgs {
+	repositoryOpts := helm.RepositoryOptsArgs{}
+	if !c.oci {
+		repositoryOpts.Repo = pulumi.String(c.repo)
+	}
+	if c.username != "" {
+		repositoryOpts.Username = pulumi.String(c.username)
+		repositoryOpts.Password = pulumi.ToSecret(pulumi.String(c.password)).(pulumi.StringOutput)
+	}
+	return repositoryOpts
+}
+
+// mirrorSettings returns the mirror settings or nil if no mirror is configured.
+func mirrorSettings(locals *localz.Locals) *options.Mirror {
+	if locals.Options == nil {
+		return nil
+	}
+	return locals.Options.Mirror
+}
+
+// resolvedHelmRelease has the settings of the helm releases of an addon.
+type resolvedHelmRelease struct {
+	atomic         bool
+	cleanupOnFail  bool
+	wait           bool
+	waitForJobs    bool
+	timeoutSeconds int
+}
+
+// helmReleaseSettings resolves the helm release settings of the addon from the settings of the addon, the
+// helm release defaults of the cluster and the defaults of the module, in that order.
+func helmReleaseSettings(locals *localz.Locals, addonName string) *resolvedHelmRelease {
+	r := &resolvedHelmRelease{
+		atomic:         vars.HelmRelease.Atomic,
+		cleanupOnFail:  vars.HelmRelease.CleanupOnFail,
+		wait:           vars.HelmRelease.Wait,
+		waitForJobs:    vars.HelmRelease.WaitForJobs,
+		timeoutSeconds: vars.HelmRelease.TimeoutSeconds,
+	}
+	var addonHelmRelease *options.HelmRelease
+	if settings := locals.Options.Addon(addonName); settings != nil {
+		addonHelmRelease = settings.HelmRelease
+	}
+	var defaultHelmRelease *options.HelmRelease
+	if locals.Options != nil {
+		defaultHelmRelease = locals.Options.HelmReleaseDefaults
+	}
+	for _, h := range []*options.HelmRelease{defaultHelmRelease, addonHelmRelease} {
+		if h == nil {
+			continue
+		}
+		if h.Atomic != nil {
+			r.atomic = *h.Atomic
+		}
+		if h.CleanupOnFail != nil {
+			r.cleanupOnFail = *h.CleanupOnFail
+		}
+		if h.Wait != nil {
+			r.wait = *h.Wait
+		}
+		if h.WaitForJobs != nil {
+			r.waitForJobs = *h.WaitForJobs
+		}
+		if h.TimeoutSeconds > 0 {
+			r.timeoutSeconds = h.TimeoutSeconds
+		}
+	}
+	//helm always waits for atomic releases
+	if r.atomic {
+		r.wait = true
+	}
+	return r
+}
+
+// skipAwait returns nil unless waiting is disabled, so that releases that wait are not changed by the setting.
+func (r *resolvedHelmRelease) skipAwait() pulumi.BoolInput {
+	if r.wait {
+		return nil
+	}
+	return pulumi.Bool(true)
+}
+
+// customTimeouts makes pulumi wait for a release for as long as helm does, plus a margin.
+func (r *resolvedHelmRelease) customTimeouts() pulumi.ResourceOption {
+	timeout := fmt.Sprintf("%ds", r.timeoutSeconds+vars.HelmRelease.TimeoutMarginSeconds)
+	return pulumi.Timeouts(&pulumi.CustomTimeouts{Create: timeout, Update: timeout, Delete: timeout})
+}
@@ -0,0 +1,95 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"reflect"
+	"testing"
+)
+
+func TestHelmReleaseSettings(t *testing.T) {
+	enabled, disabled := true, false
+	moduleDefaults := resolvedHelmRelease{
+		atomic:         vars.HelmRelease.Atomic,
+		cleanupOnFail:  vars.HelmRelease.CleanupOnFail,
+		wait:           vars.HelmRelease.Wait || vars.HelmRelease.Atomic,
+		waitForJobs:    vars.HelmRelease.WaitForJobs,
+		timeoutSeconds: vars.HelmRelease.TimeoutSeconds,
+	}
+	tests := []struct {
+		name   string
+		locals *localz.Locals
+		want   resolvedHelmRelease
+	}{
+		{
+			name:   "module defaults without options",
+			locals: &localz.Locals{},
+			want:   moduleDefaults,
+		},
+		{
+			name:   "module defaults without settings",
+			locals: testLocals(nil),
+			want:   moduleDefaults,
+		},
+		{
+			name: "cluster defaults",
+			locals: &localz.Locals{Options: &options.Options{
+				HelmReleaseDefaults: &options.HelmRelease{Atomic: &disabled, Wait: &disabled, TimeoutSeconds: 900},
+			}},
+			want: resolvedHelmRelease{
+				atomic:         false,
+				cleanupOnFail:  vars.HelmRelease.CleanupOnFail,
+				wait:           false,
+				waitForJobs:    vars.HelmRelease.WaitForJobs,
+				timeoutSeconds: 900,
+			},
+		},
+		{
+			name: "addon settings over cluster defaults",
+			locals: &localz.Locals{Options: &options.Options{
+				HelmReleaseDefaults: &options.HelmRelease{Atomic: &disabled, Wait: &disabled, TimeoutSeconds: 900},
+				Addons: map[string]*options.AddonSettings{
+					istioAddonName: {HelmRelease: &options.HelmRelease{Wait: &enabled, CleanupOnFail: &enabled}},
+				},
+			}},
+			want: resolvedHelmRelease{
+				atomic:         false,
+				cleanupOnFail:  true,
+				wait:           true,
+				waitForJobs:    vars.HelmRelease.WaitForJobs,
+				timeoutSeconds: 900,
+			},
+		},
+		{
+			name: "settings of other addons ignored",
+			locals: testLocals(map[string]*options.AddonSettings{
+				certManagerAddonName: {HelmRelease: &options.HelmRelease{TimeoutSeconds: 60}},
+			}),
+			want: moduleDefaults,
+		},
+		{
+			name: "atomic releases wait",
+			locals: &localz.Locals{Options: &options.Options{
+				HelmReleaseDefaults: &options.HelmRelease{Wait: &disabled},
+				Addons: map[string]*options.AddonSettings{
+					istioAddonName: {HelmRelease: &options.HelmRelease{Atomic: &enabled}},
+				},
+			}},
+			want: resolvedHelmRelease{
+				atomic:         true,
+				cleanupOnFail:  vars.HelmRelease.CleanupOnFail,
+				wait:           true,
+				waitForJobs:    vars.HelmRelease.WaitForJobs,
+				timeoutSeconds: vars.HelmRelease.TimeoutSeconds,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := helmReleaseSettings(tt.locals, istioAddonName); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("helmReleaseSettings() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
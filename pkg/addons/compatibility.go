@@ -0,0 +1,193 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+// compatibilityCheck returns an error when the addon, as configured, is known not to work on the cluster.
+type compatibilityCheck func(ctx *pulumi.Context, input *Input) error
+
+// compatibilityChecks is the table of known-bad combinations keyed by the name of the addon they apply to.
+// the checks of the enabled addons run before any addon is installed.
+var compatibilityChecks = map[string][]compatibilityCheck{
+	istioAddonName:           {istioKubernetesVersionCheck},
+	solrOperatorAddonName:    {solrOperatorCrdVersionCheck},
+	argoCdAddonName:          {argoCdDependenciesCheck},
+	keycloakAddonName:        {keycloakIngressCheck},
+	policyEngineAddonName:    {policyEngineBaselinePoliciesCheck},
+	vaultAddonName:           {vaultIngressCheck},
+	configConnectorAddonName: {configConnectorContextNamespacesCheck},
+}
+
+// istioSupportedKubernetesVersions lists the kubernetes minor versions supported by each istio minor version.
+// istio versions missing from the table are not checked.
+// https://istio.io/latest/docs/releases/supported-releases/#support-status-of-istio-releases
+var istioSupportedKubernetesVersions = map[string][]string{
+	"1.20": {"1.25", "1.26", "1.27", "1.28", "1.29"},
+	"1.21": {"1.26", "1.27", "1.28", "1.29"},
+	"1.22": {"1.27", "1.28", "1.29", "1.30"},
+	"1.23": {"1.27", "1.28", "1.29", "1.30"},
+	"1.24": {"1.28", "1.29", "1.30", "1.31"},
+	"1.25": {"1.29", "1.30", "1.31", "1.32"},
+	"1.26": {"1.29", "1.30", "1.31", "1.32", "1.33"},
+	"1.27": {"1.29", "1.30", "1.31", "1.32", "1.33"},
+}
+
+// checkCompatibility runs the compatibility checks of the addon.
+func checkCompatibility(ctx *pulumi.Context, addon *Addon, input *Input) error {
+	for _, check := range compatibilityChecks[addon.Name] {
+		if err := check(ctx, input); err != nil {
+			return errors.Wrapf(err, "%s addon is not compatible", addon.Name)
+		}
+	}
+	return nil
+}
+
+// istioKubernetesVersionCheck rejects istio versions that do not support the kubernetes minor version of the
+// default version of the cluster's release channel. the actual master version of the cluster is only known once the
+// cluster is created or updated, so it is checked before the istio charts are installed, see istioChartVersion.
+func istioKubernetesVersionCheck(ctx *pulumi.Context, input *Input) error {
+	istioVersion := minorVersion(chartVersion(input.Locals, istioAddonName, vars.Istio.HelmChartsVersion))
+	kubernetesVersion, err := releaseChannelKubernetesVersion(ctx, input)
+	if err != nil {
+		return errors.Wrap(err, "failed to get kubernetes version of the cluster")
+	}
+	if err := istioSupportsKubernetesVersion(istioVersion, kubernetesVersion); err != nil {
+		return errors.Wrapf(err, "%s release channel is on kubernetes %s", vars.GkeReleaseChannel, kubernetesVersion)
+	}
+	return nil
+}
+
+// istioSupportsKubernetesVersion returns an error when the istio minor version does not support the kubernetes
+// minor version.
+func istioSupportsKubernetesVersion(istioVersion, kubernetesVersion string) error {
+	supportedVersions, ok := istioSupportedKubernetesVersions[istioVersion]
+	if !ok {
+		return nil
+	}
+	if !contains(supportedVersions, kubernetesVersion) {
+		return errors.Errorf("istio %s supports kubernetes %s but not %s",
+			istioVersion, strings.Join(supportedVersions, ", "), kubernetesVersion)
+	}
+	return nil
+}
+
+// istioChartVersion returns the version of the istio charts, which resolves only after the master version of the
+// created cluster is checked to be supported by the istio version. the charts are not installed on a cluster whose
+// master version is not supported, ex: a cluster created with, or upgraded to, a newer version than the default
+// version of its release channel.
+func istioChartVersion(locals *localz.Locals, createdCluster *container.Cluster) pulumi.StringOutput {
+	version := chartVersion(locals, istioAddonName, vars.Istio.HelmChartsVersion)
+	return createdCluster.MasterVersion.ApplyT(func(masterVersion string) (string, error) {
+		if err := istioSupportsKubernetesVersion(minorVersion(version), minorVersion(masterVersion)); err != nil {
+			return "", errors.Wrapf(err, "istio addon is not compatible with master version %s of the cluster",
+				masterVersion)
+		}
+		return version, nil
+	}).(pulumi.StringOutput)
+}
+
+// solrOperatorCrdVersionCheck rejects solr-operator chart versions that do not match the version of the crds
+// vendored into the module.
+func solrOperatorCrdVersionCheck(ctx *pulumi.Context, input *Input) error {
+	version := strings.TrimPrefix(chartVersion(input.Locals, solrOperatorAddonName, vars.SolrOperator.HelmChartVersion), "v")
+	if "v"+version != vars.SolrOperator.CrdVersion {
+		return errors.Errorf("solr-operator chart version %s does not match the version %s of the vendored crds",
+			version, vars.SolrOperator.CrdVersion)
+	}
+	return nil
+}
+
+// argoCdDependenciesCheck rejects an ingress of argo cd on a domain of another cluster or without the addons that
+// serve it, and sso without the external-secrets addon which syncs the google oauth client.
+func argoCdDependenciesCheck(ctx *pulumi.Context, input *Input) error {
+	settings := input.Locals.Options.ArgoCd
+	if settings.Ingress != nil {
+		if _, err := resolveIngress(input.Locals, vars.ArgoCd.HostnamePrefix, settings.Ingress); err != nil {
+			return errors.Wrap(err, "invalid ingress")
+		}
+	}
+	if settings.Sso != nil && !input.Locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallExternalSecrets() {
+		return errors.Errorf("sso requires the %s addon", externalSecretsAddonName)
+	}
+	return nil
+}
+
+// keycloakIngressCheck rejects an ingress of keycloak on a domain of another cluster or without the addons that
+// serve it.
+func keycloakIngressCheck(ctx *pulumi.Context, input *Input) error {
+	settings := input.Locals.Options.Keycloak
+	if settings.Ingress == nil {
+		return nil
+	}
+	if _, err := resolveIngress(input.Locals, vars.Keycloak.HostnamePrefix, settings.Ingress); err != nil {
+		return errors.Wrap(err, "invalid ingress")
+	}
+	return nil
+}
+
+// policyEngineBaselinePoliciesCheck rejects a version of the baseline policy set that is not shipped with the module
+// and modes of policies that are not part of the set.
+func policyEngineBaselinePoliciesCheck(ctx *pulumi.Context, input *Input) error {
+	_, err := baselinePolicies(input.Locals.Options.PolicyEngine)
+	return err
+}
+
+// vaultIngressCheck rejects an ingress of vault on a domain of another cluster or without the addons that serve it.
+func vaultIngressCheck(ctx *pulumi.Context, input *Input) error {
+	settings := input.Locals.Options.Vault
+	if settings.Ingress == nil {
+		return nil
+	}
+	if _, err := resolveIngress(input.Locals, vars.Vault.HostnamePrefix, settings.Ingress); err != nil {
+		return errors.Wrap(err, "invalid ingress")
+	}
+	return nil
+}
+
+// configConnectorContextNamespacesCheck rejects contexts in the namespaces created for the workload identities, as
+// the namespace of a context is created by the config-connector addon.
+func configConnectorContextNamespacesCheck(ctx *pulumi.Context, input *Input) error {
+	for _, c := range input.Locals.Options.ConfigConnector.Contexts {
+		for _, w := range input.Locals.Options.WorkloadIdentities {
+			if w.Namespace == c.Namespace {
+				return errors.Errorf("%s namespace of the context is also created for the %s workload identity",
+					c.Namespace, w.Key())
+			}
+		}
+	}
+	return nil
+}
+
+// releaseChannelKubernetesVersion returns the kubernetes minor version, ex: "1.30", of the default gke version of
+// the release channel of the cluster, which is the version new clusters are created with and existing clusters
+// are upgraded to.
+func releaseChannelKubernetesVersion(ctx *pulumi.Context, input *Input) (string, error) {
+	engineVersions, err := container.GetEngineVersions(ctx,
+		&container.GetEngineVersionsArgs{
+			Project:  pulumi.StringRef(input.Locals.GkeCluster.Spec.ClusterProjectId),
+			Location: pulumi.StringRef(input.Locals.GkeCluster.Spec.Zone),
+		}, pulumi.Provider(input.GcpProvider))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to get gke engine versions")
+	}
+	version, ok := engineVersions.ReleaseChannelDefaultVersion[vars.GkeReleaseChannel]
+	if !ok {
+		return "", errors.Errorf("no default version found for %s release channel", vars.GkeReleaseChannel)
+	}
+	return minorVersion(version), nil
+}
+
+// minorVersion returns the major and minor parts of a version, ex: "1.30" for "1.30.5-gke.1014001" or "v1.30.5".
+func minorVersion(version string) string {
+	parts := strings.SplitN(strings.TrimPrefix(version, "v"), ".", 3)
+	if len(parts) < 2 {
+		return version
+	}
+	return parts[0] + "." + parts[1]
+}
@@ -0,0 +1,138 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"strings"
+	"testing"
+)
+
+// testLocals returns locals with the addon settings, keyed by the addon name.
+func testLocals(addons map[string]*options.AddonSettings) *localz.Locals {
+	return &localz.Locals{Options: &options.Options{Addons: addons}}
+}
+
+func TestCheckCompatibility(t *testing.T) {
+	solrOperator, _ := defaultRegistry.Get(solrOperatorAddonName)
+	tests := []struct {
+		name    string
+		addon   *Addon
+		locals  *localz.Locals
+		wantErr string
+	}{
+		{
+			name:   "addon without checks",
+			addon:  testAddon("test", true, nil, nil),
+			locals: testLocals(nil),
+		},
+		{
+			name:   "solr-operator with the default chart version",
+			addon:  solrOperator,
+			locals: testLocals(nil),
+		},
+		{
+			name:  "solr-operator with the chart version of the crds",
+			addon: solrOperator,
+			locals: testLocals(map[string]*options.AddonSettings{
+				solrOperatorAddonName: {ChartVersion: vars.SolrOperator.CrdVersion},
+			}),
+		},
+		{
+			name:  "solr-operator with another chart version",
+			addon: solrOperator,
+			locals: testLocals(map[string]*options.AddonSettings{
+				solrOperatorAddonName: {ChartVersion: "0.8.1"},
+			}),
+			wantErr: "solr-operator addon is not compatible",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkCompatibility(nil, tt.addon, &Input{Locals: tt.locals})
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Errorf("checkCompatibility() error = %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("checkCompatibility() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestIstioSupportsKubernetesVersion(t *testing.T) {
+	tests := []struct {
+		istioVersion      string
+		kubernetesVersion string
+		wantErr           bool
+	}{
+		{istioVersion: minorVersion(vars.Istio.HelmChartsVersion), kubernetesVersion: "1.30"},
+		{istioVersion: "1.22", kubernetesVersion: "1.30"},
+		{istioVersion: "1.22", kubernetesVersion: "1.31", wantErr: true},
+		{istioVersion: "1.27", kubernetesVersion: "1.33"},
+		{istioVersion: "1.27", kubernetesVersion: "1.28", wantErr: true},
+		//versions missing from the table are not checked
+		{istioVersion: "1.19", kubernetesVersion: "1.33"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.istioVersion+"-"+tt.kubernetesVersion, func(t *testing.T) {
+			err := istioSupportsKubernetesVersion(tt.istioVersion, tt.kubernetesVersion)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("istioSupportsKubernetesVersion() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestMinorVersion(t *testing.T) {
+	tests := []struct {
+		version string
+		want    string
+	}{
+		{version: "1.30.5-gke.1014001", want: "1.30"},
+		{version: "v1.30.5", want: "1.30"},
+		{version: "1.27", want: "1.27"},
+		{version: "latest", want: "latest"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.version, func(t *testing.T) {
+			if got := minorVersion(tt.version); got != tt.want {
+				t.Errorf("minorVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChartVersion(t *testing.T) {
+	tests := []struct {
+		name   string
+		locals *localz.Locals
+		want   string
+	}{
+		{
+			name:   "default version",
+			locals: testLocals(nil),
+			want:   vars.Istio.HelmChartsVersion,
+		},
+		{
+			name:   "settings without version",
+			locals: testLocals(map[string]*options.AddonSettings{istioAddonName: {}}),
+			want:   vars.Istio.HelmChartsVersion,
+		},
+		{
+			name:   "pinned version",
+			locals: testLocals(map[string]*options.AddonSettings{istioAddonName: {ChartVersion: "1.26.4"}}),
+			want:   "1.26.4",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := chartVersion(tt.locals, istioAddonName, vars.Istio.HelmChartsVersion); got != tt.want {
+				t.Errorf("chartVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package addons
+
+import (
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// each addon is a component resource of its own type, ex: "planton:gke:IstioAddon", which is the parent of all the
+// resources of the addon. the resources of an addon used to be created without a parent or as children of the
+// cluster, so the resources at the top of an addon component are aliased to where they were created before.
+// resources further down the tree inherit the aliases of their parents and keep their urns as well.
+
+// movedFromRoot aliases a resource that was created without a parent, so that it is not replaced now that it is a
+// child of the component of its addon.
+func movedFromRoot() pulumi.ResourceOption {
+	return pulumi.Aliases([]pulumi.Alias{{NoParent: pulumi.Bool(true)}})
+}
+
+// movedFrom aliases a resource that was created as a child of the parent, so that it is not replaced now that it is
+// a child of the component of its addon.
+func movedFrom(parent pulumi.Resource) pulumi.ResourceOption {
+	return pulumi.Aliases([]pulumi.Alias{{Parent: parent}})
+}
+
+// rootNamespaceUrn returns the urn of a namespace created without a parent, which is the previous parent of the
+// resources that were created as children of an addon namespace and have moved elsewhere since.
+func rootNamespaceUrn(ctx *pulumi.Context, name string) pulumi.URNOutput {
+	return pulumi.CreateURN(pulumi.String(name), pulumi.String("kubernetes:core/v1:Namespace"), nil,
+		pulumi.String(ctx.Project()), pulumi.String(ctx.Stack()))
+}
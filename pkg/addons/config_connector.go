@@ -0,0 +1,286 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+const (
+	configConnectorAddonName = "config-connector"
+	// ConfigConnectorResourceType is the pulumi type token of the config-connector addon component.
+	ConfigConnectorResourceType = "planton:gke:ConfigConnectorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: configConnectorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.ConfigConnector != nil
+		},
+		Requires: []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ConfigConnector(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// ConfigConnectorAddon is the component of the config-connector addon.
+type ConfigConnectorAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+
+	GoogleServiceAccount *serviceaccount.Account
+	ConfigConnector      *apiextensions.CustomResource
+	// ContextNamespaces and Contexts are keyed by the namespace, they are only created in namespaced mode.
+	ContextNamespaces map[string]*corev1.Namespace
+	Contexts          map[string]*apiextensions.CustomResource
+}
+
+// ConfigConnector configures the Config Connector addon of GKE, which is enabled on the cluster along with this
+// addon, so that teams manage GCP resources as Kubernetes objects.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where Config Connector is enabled.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *ConfigConnectorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates the controller Google Service Account (GSA), grants it the configured project roles and exports its
+//     email.
+//  3. Creates the Workload Identity binding for the Kubernetes Service Accounts (KSA) of the controllers, which are
+//     created by GKE in the cnrm-system namespace, one for the cluster or one for each namespace of a context.
+//  4. Waits for the CRDs of the operator installed by GKE to be established.
+//  5. Creates the ConfigConnector resource in cluster or namespaced mode.
+//  6. In namespaced mode, creates the namespace of each context, annotated with the project of its resources, and a
+//     ConfigConnectorContext in it.
+func ConfigConnector(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*ConfigConnectorAddon, error) {
+	settings := locals.Options.ConfigConnector
+
+	createdAddon := &ConfigConnectorAddon{
+		ContextNamespaces: make(map[string]*corev1.Namespace),
+		Contexts:          make(map[string]*apiextensions.CustomResource),
+	}
+	err := ctx.RegisterComponentResource(ConfigConnectorResourceType, configConnectorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register config-connector addon component")
+	}
+
+	//the ksa of the controllers are created by gke, so the workload identity component which creates the ksa is not
+	//used
+	createdGoogleServiceAccount, err := serviceaccount.NewAccount(ctx,
+		vars.ConfigConnector.GsaName,
+		&serviceaccount.AccountArgs{
+			Project:     createdCluster.Project,
+			Description: pulumi.String("config connector service account for managing gcp resources from kubernetes"),
+			AccountId:   pulumi.String(locals.GsaAccountId(vars.ConfigConnector.GsaName)),
+			DisplayName: pulumi.String(locals.GsaAccountId(vars.ConfigConnector.GsaName)),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create config-connector google service account")
+	}
+	createdAddon.GoogleServiceAccount = createdGoogleServiceAccount
+	createdAddon.GsaEmail = createdGoogleServiceAccount.Email
+
+	//export config-connector gsa email
+	ctx.Export(outputs.ConfigConnectorGsaEmail, createdGoogleServiceAccount.Email)
+
+	//iam-member is used instead of iam-binding as the roles can be granted to others as well.
+	for _, r := range configConnectorProjectRoles(settings) {
+		project := createdCluster.Project
+		iamMemberName := fmt.Sprintf("%s-%s", vars.ConfigConnector.GsaName, strings.TrimPrefix(r.Role, "roles/"))
+		if r.ProjectId != "" {
+			project = pulumi.String(r.ProjectId).ToStringOutput()
+			iamMemberName = fmt.Sprintf("%s-%s", iamMemberName, r.ProjectId)
+		}
+		_, err := projects.NewIAMMember(ctx,
+			iamMemberName,
+			&projects.IAMMemberArgs{
+				Member:  pulumi.Sprintf("serviceAccount:%s", createdGoogleServiceAccount.Email),
+				Project: project,
+				Role:    pulumi.String(r.Role),
+			}, pulumi.Parent(createdGoogleServiceAccount))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to grant %s role to config-connector", r.Role)
+		}
+	}
+
+	workloadIdentityMembers := pulumi.StringArray{}
+	for _, ksaName := range configConnectorKsaNames(settings) {
+		workloadIdentityMembers = append(workloadIdentityMembers,
+			pulumi.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]",
+				createdCluster.Project, vars.ConfigConnector.Namespace, ksaName))
+	}
+	//the gsa is owned by the addon, so the binding can be authoritative.
+	//there are no controllers to bind in namespaced mode without contexts.
+	configConnectorDependencies := make([]pulumi.Resource, 0)
+	if len(workloadIdentityMembers) > 0 {
+		createdWorkloadIdentityBinding, err := serviceaccount.NewIAMBinding(ctx,
+			fmt.Sprintf("%s-workload-identity", vars.ConfigConnector.GsaName),
+			&serviceaccount.IAMBindingArgs{
+				ServiceAccountId: createdGoogleServiceAccount.Name,
+				Role:             pulumi.String(workloadidentity.WorkloadIdentityUserRole),
+				Members:          workloadIdentityMembers,
+			}, pulumi.Parent(createdGoogleServiceAccount))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create config-connector workload-identity binding")
+		}
+		configConnectorDependencies = append(configConnectorDependencies, createdWorkloadIdentityBinding)
+	}
+
+	//the operator is installed by gke once the addon is enabled on the cluster
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName: configConnectorAddonName,
+			namespace: vars.ConfigConnector.OperatorNamespace,
+			crds: []string{
+				"configconnectors.core.cnrm.cloud.google.com",
+				"configconnectorcontexts.core.cnrm.cloud.google.com",
+			},
+		}, createdAddon)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for config-connector readiness")
+	}
+	configConnectorDependencies = append(configConnectorDependencies, createdReadinessJob)
+
+	spec := pulumi.Map{
+		"mode": pulumi.String(configConnectorMode(settings)),
+	}
+	if !settings.IsNamespaced() {
+		spec["googleServiceAccount"] = createdGoogleServiceAccount.Email
+	}
+	//https://cloud.google.com/config-connector/docs/how-to/install-upgrade-uninstall#addon-configuring
+	createdConfigConnector, err := apiextensions.NewCustomResource(ctx,
+		vars.ConfigConnector.ResourceName,
+		&apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String(vars.ConfigConnector.ApiVersion),
+			Kind:       pulumi.String("ConfigConnector"),
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.ConfigConnector.ResourceName),
+					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			OtherFields: pulumikubernetes.UntypedArgs{
+				"spec": spec,
+			},
+		}, pulumi.Parent(createdAddon),
+		pulumi.DependsOn(configConnectorDependencies))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create config-connector resource")
+	}
+	createdAddon.ConfigConnector = createdConfigConnector
+
+	for _, c := range settings.Contexts {
+		projectId := createdCluster.Project
+		if c.ProjectId != "" {
+			projectId = pulumi.String(c.ProjectId).ToStringOutput()
+		}
+
+		//the resources of the namespace are created in the project of its annotation
+		createdNamespace, err := corev1.NewNamespace(ctx,
+			fmt.Sprintf("config-connector-%s", c.Namespace),
+			&corev1.NamespaceArgs{
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:   pulumi.String(c.Namespace),
+						Labels: pulumi.ToStringMap(locals.NamespaceLabels(c.Namespace)),
+						Annotations: pulumi.StringMap{
+							vars.ConfigConnector.ProjectIdAnnotationKey: projectId,
+						},
+					}),
+			}, pulumi.Parent(createdAddon))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create %s namespace", c.Namespace)
+		}
+		createdAddon.ContextNamespaces[c.Namespace] = createdNamespace
+
+		createdContext, err := apiextensions.NewCustomResource(ctx,
+			fmt.Sprintf("config-connector-context-%s", c.Namespace),
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String(vars.ConfigConnector.ApiVersion),
+				Kind:       pulumi.String("ConfigConnectorContext"),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String("configconnectorcontext.core.cnrm.cloud.google.com"),
+						Namespace: createdNamespace.Metadata.Name(),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": pulumi.Map{
+						"googleServiceAccount": createdGoogleServiceAccount.Email,
+					},
+				},
+			}, pulumi.Parent(createdNamespace),
+			pulumi.DependsOn([]pulumi.Resource{createdConfigConnector}))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create config-connector-context in %s namespace", c.Namespace)
+		}
+		createdAddon.Contexts[c.Namespace] = createdContext
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"gsaEmail": createdGoogleServiceAccount.Email,
+		"mode":     pulumi.String(configConnectorMode(settings)),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register config-connector addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// configConnectorMode returns the mode of the config-connector resource.
+func configConnectorMode(settings *options.ConfigConnector) string {
+	if settings.IsNamespaced() {
+		return options.ConfigConnectorModeNamespaced
+	}
+	return options.ConfigConnectorModeCluster
+}
+
+// configConnectorProjectRoles returns the project roles of the controller gsa, the default role in the cluster
+// project when none are configured.
+func configConnectorProjectRoles(settings *options.ConfigConnector) []*options.ProjectRole {
+	if len(settings.ProjectRoles) == 0 {
+		return []*options.ProjectRole{{Role: vars.ConfigConnector.DefaultProjectRole}}
+	}
+	return settings.ProjectRoles
+}
+
+// configConnectorKsaNames returns the names of the ksa of the controllers, ex: "cnrm-controller-manager-orders" for
+// the controller of the orders namespace in namespaced mode.
+func configConnectorKsaNames(settings *options.ConfigConnector) []string {
+	if !settings.IsNamespaced() {
+		return []string{vars.ConfigConnector.KsaName}
+	}
+	ksaNames := make([]string, 0)
+	for _, c := range settings.Contexts {
+		ksaNames = append(ksaNames, fmt.Sprintf("%s-%s", vars.ConfigConnector.KsaName, c.Namespace))
+	}
+	return ksaNames
+}
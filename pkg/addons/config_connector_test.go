@@ -0,0 +1,70 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"reflect"
+	"testing"
+)
+
+func TestConfigConnectorWiring(t *testing.T) {
+	contexts := []*options.ConfigConnectorContext{
+		{Namespace: "orders"},
+		{Namespace: "billing", ProjectId: "billing-project"},
+	}
+	billingRoles := []*options.ProjectRole{{Role: "roles/editor", ProjectId: "billing-project"}}
+	tests := []struct {
+		name         string
+		settings     *options.ConfigConnector
+		wantMode     string
+		wantKsaNames []string
+		wantRoles    []*options.ProjectRole
+	}{
+		{
+			name:         "cluster mode",
+			settings:     &options.ConfigConnector{},
+			wantMode:     options.ConfigConnectorModeCluster,
+			wantKsaNames: []string{vars.ConfigConnector.KsaName},
+			wantRoles:    []*options.ProjectRole{{Role: vars.ConfigConnector.DefaultProjectRole}},
+		},
+		{
+			//each namespace has a controller of its own, all of them impersonating the gsa of the addon
+			name:     "namespaced mode",
+			settings: &options.ConfigConnector{Mode: options.ConfigConnectorModeNamespaced, Contexts: contexts},
+			wantMode: options.ConfigConnectorModeNamespaced,
+			wantKsaNames: []string{
+				vars.ConfigConnector.KsaName + "-orders",
+				vars.ConfigConnector.KsaName + "-billing",
+			},
+			wantRoles: []*options.ProjectRole{{Role: vars.ConfigConnector.DefaultProjectRole}},
+		},
+		{
+			//no controller is bound to the gsa until a context is configured
+			name:         "namespaced mode without contexts",
+			settings:     &options.ConfigConnector{Mode: options.ConfigConnectorModeNamespaced},
+			wantMode:     options.ConfigConnectorModeNamespaced,
+			wantKsaNames: []string{},
+			wantRoles:    []*options.ProjectRole{{Role: vars.ConfigConnector.DefaultProjectRole}},
+		},
+		{
+			name:         "project roles replace the default role",
+			settings:     &options.ConfigConnector{ProjectRoles: billingRoles},
+			wantMode:     options.ConfigConnectorModeCluster,
+			wantKsaNames: []string{vars.ConfigConnector.KsaName},
+			wantRoles:    billingRoles,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := configConnectorMode(tt.settings); got != tt.wantMode {
+				t.Errorf("configConnectorMode() = %q, want %q", got, tt.wantMode)
+			}
+			if got := configConnectorKsaNames(tt.settings); !reflect.DeepEqual(got, tt.wantKsaNames) {
+				t.Errorf("configConnectorKsaNames() = %v, want %v", got, tt.wantKsaNames)
+			}
+			if got := configConnectorProjectRoles(tt.settings); !reflect.DeepEqual(got, tt.wantRoles) {
+				t.Errorf("configConnectorProjectRoles() = %v, want %v", got, tt.wantRoles)
+			}
+		})
+	}
+}
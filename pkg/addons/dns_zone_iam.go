@@ -0,0 +1,57 @@
+package addons
+
+import (
+	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// dnsZoneProjectIds returns the distinct gcp project ids hosting the cloud-dns zones of the ingress-dns-domains
+// in the order they are first seen. when isTlsEnabledOnly is set, domains without tls are skipped.
+func dnsZoneProjectIds(ingressDnsDomains []*gkeclusterv1.IngressDnsDomain, isTlsEnabledOnly bool) []string {
+	projectIds := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, i := range ingressDnsDomains {
+		if isTlsEnabledOnly && !i.IsTlsEnabled {
+			continue
+		}
+		if i.DnsZoneGcpProjectId == "" || seen[i.DnsZoneGcpProjectId] {
+			continue
+		}
+		seen[i.DnsZoneGcpProjectId] = true
+		projectIds = append(projectIds, i.DnsZoneGcpProjectId)
+	}
+	return projectIds
+}
+
+// dnsZoneIamMembers grants the google service account the cloud-dns admin role in each of the dns-zone projects.
+//
+// iam-member is used instead of iam-binding as the dns-zone projects are not always owned by this module and an
+// authoritative binding would remove members granted by others.
+func dnsZoneIamMembers(ctx *pulumi.Context, name string,
+	createdGoogleServiceAccount *serviceaccount.Account,
+	projectIds []string,
+	gcpProvider *gcp.Provider) ([]pulumi.Resource, error) {
+	createdIamMembers := make([]pulumi.Resource, 0)
+
+	for _, projectId := range projectIds {
+		createdIamMember, err := projects.NewIAMMember(ctx,
+			fmt.Sprintf("%s-dns-admin-%s", name, projectId),
+			&projects.IAMMemberArgs{
+				Member:  pulumi.Sprintf("serviceAccount:%s", createdGoogleServiceAccount.Email),
+				Project: pulumi.String(projectId),
+				Role:    pulumi.String(vars.CloudDnsAdminRole),
+			}, pulumi.Parent(createdGoogleServiceAccount), pulumi.Provider(gcpProvider))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to grant dns admin role in %s project", projectId)
+		}
+		createdIamMembers = append(createdIamMembers, createdIamMember)
+	}
+
+	return createdIamMembers, nil
+}
@@ -12,34 +12,98 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	elasticOperatorAddonName = "elastic-operator"
+	// ElasticOperatorResourceType is the pulumi type token of the elastic-operator addon component.
+	ElasticOperatorResourceType = "planton:gke:ElasticOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: elasticOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallElasticOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.ElasticOperator.HelmChartName},
+		Removal: &Removal{
+			Crds: []string{
+				"kibanas.kibana.k8s.elastic.co",
+				"apmservers.apm.k8s.elastic.co",
+				"enterprisesearches.enterprisesearch.k8s.elastic.co",
+				"beats.beat.k8s.elastic.co",
+				"agents.agent.k8s.elastic.co",
+				"elasticmapsservers.maps.k8s.elastic.co",
+				"logstashes.logstash.k8s.elastic.co",
+				"stackconfigpolicies.stackconfigpolicy.k8s.elastic.co",
+				"elasticsearchautoscalers.autoscaling.k8s.elastic.co",
+				"elasticsearches.elasticsearch.k8s.elastic.co",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ElasticOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// ElasticOperatorAddon is the component of the elastic-operator addon.
+type ElasticOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *kubernetescorev1.Namespace
+	HelmRelease *helm.Release
+}
+
+// ElasticOperator installs the Elastic Operator in the Kubernetes cluster using Helm.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *ElasticOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
 func ElasticOperator(ctx *pulumi.Context, locals *localz.Locals,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*ElasticOperatorAddon, error) {
+	createdAddon := &ElasticOperatorAddon{}
+	err := ctx.RegisterComponentResource(ElasticOperatorResourceType, elasticOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register elastic-operator addon component")
+	}
 
 	createdNamespace, err := kubernetescorev1.NewNamespace(ctx, vars.ElasticOperator.Namespace,
 		&kubernetescorev1.NamespaceArgs{
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.ElasticOperator.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.ElasticOperator.Namespace)),
 				}),
-		}, pulumi.Provider(kubernetesProvider))
+		}, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create namespace")
+		return nil, errors.Wrapf(err, "failed to create namespace")
 	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, elasticOperatorAddonName)
+	chartSource := helmChartSource(locals, elasticOperatorAddonName, vars.ElasticOperator.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, vars.ElasticOperator.HelmChartName,
+	createdHelmRelease, err := helm.NewRelease(ctx, vars.ElasticOperator.HelmChartName,
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.ElasticOperator.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.ElasticOperator.HelmChartName),
-			Version:         pulumi.String(vars.ElasticOperator.HelmChartVersion),
+			Chart:           chartSource.chart(vars.ElasticOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, elasticOperatorAddonName, vars.ElasticOperator.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, elasticOperatorAddonName, vars.ElasticOperator.HelmChartName, pulumi.Map{
 				"configKubernetes": pulumi.Map{
 					"inherited_labels": pulumi.ToStringArray(
 						[]string{
@@ -51,15 +115,20 @@ func ElasticOperator(ctx *pulumi.Context, locals *localz.Locals,
 						},
 					),
 				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.ElasticOperator.HelmChartRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to create helm release")
 	}
+	createdAddon.HelmRelease = createdHelmRelease
 
-	return nil
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register elastic-operator addon outputs")
+	}
+	return createdAddon, nil
 }
@@ -6,9 +6,9 @@ import (
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
-	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
 	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
@@ -17,43 +17,64 @@ import (
 	"strings"
 )
 
-func ExternalDns(ctx *pulumi.Context, locals *localz.Locals,
-	createdCluster *container.Cluster, gcpProvider *gcp.Provider,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+const (
+	externalDnsAddonName = "external-dns"
+	// ExternalDnsResourceType is the pulumi type token of the external-dns addon component.
+	ExternalDnsResourceType = "planton:gke:ExternalDnsAddon"
+)
 
-	//create google service account required to create workload identity binding
-	createdGoogleServiceAccount, err := serviceaccount.NewAccount(ctx,
-		vars.ExternalDns.KsaName,
-		&serviceaccount.AccountArgs{
-			Project:     createdCluster.Project,
-			Description: pulumi.String("external-dns service account for managing dns-records in cloud dns zones"),
-			AccountId:   pulumi.String(vars.ExternalDns.KsaName),
-			DisplayName: pulumi.String(vars.ExternalDns.KsaName),
-		}, pulumi.Parent(createdCluster), pulumi.Provider(gcpProvider))
-	if err != nil {
-		return errors.Wrap(err, "failed to create external-dns google service account")
-	}
+func init() {
+	register(&Addon{
+		Name: externalDnsAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallExternalDns()
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		DependsOn:       []string{gatewayApisAddonName},
+		HelmCharts:      []string{vars.ExternalDns.HelmChartName},
+		ProtectedValues: []string{"serviceAccount.create", "serviceAccount.name", "txtOwnerId", "domainFilters", "provider"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ExternalDns(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
 
-	//export cert-manager gsa email
-	ctx.Export(outputs.ExternalDnsGsaEmail, createdGoogleServiceAccount.Email)
+// ExternalDnsAddon is the component of the external-dns addon.
+type ExternalDnsAddon struct {
+	pulumi.ResourceState
 
-	//create workload-identity binding
-	_, err = serviceaccount.NewIAMBinding(ctx,
-		fmt.Sprintf("%s-workload-identity", vars.ExternalDns.KsaName),
-		&serviceaccount.IAMBindingArgs{
-			ServiceAccountId: createdGoogleServiceAccount.Name,
-			Role:             pulumi.String("roles/iam.workloadIdentityUser"),
-			Members: pulumi.StringArray{
-				pulumi.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]",
-					createdCluster.Project,
-					vars.ExternalDns.Namespace,
-					vars.ExternalDns.KsaName),
-			},
-		},
-		pulumi.Parent(createdGoogleServiceAccount),
-		pulumi.DependsOn([]pulumi.Resource{createdCluster}))
+	GsaEmail pulumi.StringOutput
+
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	// HelmReleases are keyed by the name of the ingress domain whose dns-records they manage.
+	HelmReleases map[string]*helm.Release
+}
+
+// ExternalDns installs an External DNS Helm release for each ingress domain of the cluster, along with the Google
+// Service Account (GSA) and Kubernetes Service Account (KSA) shared by the releases.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where External DNS will be installed.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *ExternalDnsAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+func ExternalDns(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster, gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*ExternalDnsAddon, error) {
+
+	createdAddon := &ExternalDnsAddon{HelmReleases: make(map[string]*helm.Release)}
+	err := ctx.RegisterComponentResource(ExternalDnsResourceType, externalDnsAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
 	if err != nil {
-		return errors.Wrap(err, "failed to create workload-identity binding for external-dns")
+		return nil, errors.Wrap(err, "failed to register external-dns addon component")
 	}
 
 	//create namespace resource
@@ -63,50 +84,69 @@ func ExternalDns(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.ExternalDns.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.ExternalDns.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create external-dns namespace")
+		return nil, errors.Wrapf(err, "failed to create external-dns namespace")
 	}
+	createdAddon.Namespace = createdNamespace
 
-	//create kubernetes service account to be used by the external-dns.
+	//create the google service account and the kubernetes service account to be used by external-dns.
 	//it is not straight forward to add the gsa email as one of the helm values.
-	// so, instead, disable service account creation in helm release and create it separately add
-	// the Google workload identity annotation to the service account which requires the email id
-	// of the Google service account added as part of IAM module.
-	createdKubernetesServiceAccount, err := corev1.NewServiceAccount(ctx,
+	// so, instead, disable service account creation in helm release and create it separately with
+	// the Google workload identity annotation which requires the email id of the Google service account.
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
 		vars.ExternalDns.KsaName,
-		&corev1.ServiceAccountArgs{
-			Metadata: metav1.ObjectMetaPtrInput(
-				&metav1.ObjectMetaArgs{
-					Name:      pulumi.String(vars.ExternalDns.KsaName),
-					Namespace: createdNamespace.Metadata.Name(),
-					Annotations: pulumi.StringMap{
-						vars.WorkloadIdentityKubeAnnotationKey: createdGoogleServiceAccount.Email,
-					},
-				}),
-		}, pulumi.Parent(createdNamespace))
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.ExternalDns.KsaName),
+			GsaAccountId:   locals.LegacyGsaAccountId(vars.ExternalDns.KsaName),
+			GsaDescription: "external-dns service account for managing dns-records in cloud dns zones",
+			MovedFrom: &workloadidentity.MovedFrom{
+				GsaParent:    createdCluster,
+				KsaParentUrn: rootNamespaceUrn(ctx, vars.ExternalDns.Namespace),
+			},
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for external-dns")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export cert-manager gsa email
+	ctx.Export(outputs.ExternalDnsGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	//grant dns admin role in the projects of the dns-zones in which the dns-records need to be managed
+	createdDnsZoneIamMembers, err := dnsZoneIamMembers(ctx,
+		vars.ExternalDns.KsaName,
+		createdWorkloadIdentity.GoogleServiceAccount,
+		dnsZoneProjectIds(locals.GkeCluster.Spec.IngressDnsDomains, false),
+		gcpProvider)
 	if err != nil {
-		return errors.Wrap(err, "failed to create kubernetes service account")
+		return nil, errors.Wrap(err, "failed to grant dns permissions for external-dns")
 	}
 
+	releaseSettings := helmReleaseSettings(locals, externalDnsAddonName)
+	chartSource := helmChartSource(locals, externalDnsAddonName, vars.ExternalDns.HelmChartRepo)
 	for _, i := range locals.GkeCluster.Spec.IngressDnsDomains {
 		//created helm-release
-		_, err := helm.NewRelease(ctx,
+		createdHelmRelease, err := helm.NewRelease(ctx,
 			fmt.Sprintf("external-dns-%s", i.Name),
 			&helm.ReleaseArgs{
 				Name:            pulumi.Sprintf("external-dns-%s", strings.ReplaceAll(i.Name, ".", "-")),
 				Namespace:       createdNamespace.Metadata.Name(),
-				Chart:           pulumi.String(vars.ExternalDns.HelmChartName),
-				Version:         pulumi.String(vars.ExternalDns.HelmChartVersion),
+				Chart:           chartSource.chart(vars.ExternalDns.HelmChartName),
+				Version:         pulumi.String(chartVersion(locals, externalDnsAddonName, vars.ExternalDns.HelmChartVersion)),
 				CreateNamespace: pulumi.Bool(false),
-				Atomic:          pulumi.Bool(false),
-				CleanupOnFail:   pulumi.Bool(true),
-				WaitForJobs:     pulumi.Bool(true),
-				Timeout:         pulumi.Int(180),
-				Values: pulumi.Map{
+				Atomic:          pulumi.Bool(releaseSettings.atomic),
+				CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+				WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+				SkipAwait:       releaseSettings.skipAwait(),
+				Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+				Values: helmValues(locals, externalDnsAddonName, vars.ExternalDns.HelmChartName, pulumi.Map{
 					"txtOwnerId": pulumi.String(locals.GkeCluster.Metadata.Name),
 					"serviceAccount": pulumi.Map{
 						"create": pulumi.Bool(false),
@@ -125,16 +165,23 @@ func ExternalDns(ctx *pulumi.Context, locals *localz.Locals,
 						pulumi.String("--google-zone-visibility=public"),
 						pulumi.Sprintf("--google-project=%s", i.DnsZoneGcpProjectId),
 					},
-				},
-				RepositoryOpts: helm.RepositoryOptsArgs{
-					Repo: pulumi.String(vars.ExternalDns.HelmChartRepo),
-				},
+				}),
+				RepositoryOpts: chartSource.repositoryOpts(),
 			}, pulumi.Parent(createdNamespace),
-			pulumi.DependsOn([]pulumi.Resource{createdKubernetesServiceAccount}),
-			pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+			pulumi.DependsOn(append([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}, createdDnsZoneIamMembers...)),
+			pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+			releaseSettings.customTimeouts())
 		if err != nil {
-			return errors.Wrap(err, "failed to create external-dns helm release")
+			return nil, errors.Wrap(err, "failed to create external-dns helm release")
 		}
+		createdAddon.HelmReleases[i.Name] = createdHelmRelease
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"gsaEmail":  createdAddon.GsaEmail,
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register external-dns addon outputs")
 	}
-	return nil
+	return createdAddon, nil
 }
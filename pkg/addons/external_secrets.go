@@ -6,11 +6,11 @@ import (
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
 	externalsecretsv1 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/externalsecrets/externalsecrets/v1beta1"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
-	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
 	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
@@ -18,6 +18,40 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	externalSecretsAddonName = "external-secrets"
+	// ExternalSecretsResourceType is the pulumi type token of the external-secrets addon component.
+	ExternalSecretsResourceType = "planton:gke:ExternalSecretsAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: externalSecretsAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallExternalSecrets()
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		HelmCharts:      []string{vars.ExternalSecrets.HelmChartName},
+		ProtectedValues: []string{"crds.create", "serviceAccount.create", "serviceAccount.name"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ExternalSecrets(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// ExternalSecretsAddon is the component of the external-secrets addon.
+type ExternalSecretsAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+
+	Namespace          *corev1.Namespace
+	WorkloadIdentity   *workloadidentity.WorkloadIdentity
+	HelmRelease        *helm.Release
+	ClusterSecretStore *externalsecretsv1.ClusterSecretStore
+}
+
 // ExternalSecrets installs the External Secrets operator in the Kubernetes cluster using Helm, sets up the necessary
 // Google Service Account (GSA), Kubernetes Service Account (KSA), and creates a ClusterSecretStore for GCP Secrets Manager.
 //
@@ -27,70 +61,32 @@ import (
 // - createdCluster: The GKE cluster where External Secrets will be installed.
 // - gcpProvider: The GCP provider for Pulumi.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *ExternalSecretsAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a Google Service Account (GSA) for External Secrets with a description and display name.
-// 2. Exports the email of the created GSA.
-// 3. Creates a Workload Identity binding for the GSA to allow it to act as the Kubernetes Service Account (KSA).
-// 4. Creates a namespace for External Secrets and labels it with metadata from locals.
-// 5. Creates a Kubernetes Service Account (KSA) and adds the Google Workload Identity annotation with the GSA email.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for External Secrets and labels it with metadata from locals.
+// 3. Creates the workload identity for External Secrets, i.e. a Google Service Account (GSA), the Workload Identity
+// binding and a Kubernetes Service Account (KSA) annotated with the GSA email.
+// 4. Exports the email of the created GSA.
+// 5. Grants the GSA the secret accessor role in the cluster project.
 // 6. Deploys the External Secrets Helm chart into the created namespace with specific values for CRDs, environment variables, and RBAC.
-// 7. Creates a ClusterSecretStore to configure the GCP project from which secrets need to be looked up.
-// 8. Handles errors and returns any errors encountered during the creation of resources or Helm release deployment.
+// 7. Waits for the CRDs to be established and the controller, webhook and cert-controller to be available.
+// 8. Creates a ClusterSecretStore to configure the GCP project from which secrets need to be looked up.
+// 9. Handles errors and returns any errors encountered during the creation of resources or Helm release deployment.
 func ExternalSecrets(ctx *pulumi.Context, locals *localz.Locals,
 	createdCluster *container.Cluster, gcpProvider *gcp.Provider,
-	kubernetesProvider *pulumikubernetes.Provider) error {
-
-	//create google service account required to create workload identity binding
-	createdGoogleServiceAccount, err := serviceaccount.NewAccount(ctx,
-		vars.ExternalSecrets.KsaName,
-		&serviceaccount.AccountArgs{
-			Project:     createdCluster.Project,
-			Description: pulumi.String("external-secrets service account for solving dns challenges to issue certificates"),
-			AccountId:   pulumi.String(vars.ExternalSecrets.KsaName),
-			DisplayName: pulumi.String(vars.ExternalSecrets.KsaName),
-		}, pulumi.Parent(createdCluster), pulumi.Provider(gcpProvider))
-	if err != nil {
-		return errors.Wrap(err, "failed to create external-secrets google service account")
-	}
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*ExternalSecretsAddon, error) {
 
-	//export external-secrets gsa email
-	ctx.Export(outputs.ExternalSecretsGsaEmail, createdGoogleServiceAccount.Email)
-
-	//add iam binding for secrets accessor role
-	_, err = projects.NewIAMBinding(ctx,
-		"external-secrets-secrets-accessor-binding",
-		&projects.IAMBindingArgs{
-			Members: pulumi.StringArray{
-				pulumi.Sprintf("serviceAccount:%s", createdGoogleServiceAccount.Email),
-			},
-			Project: createdCluster.Project,
-			Role:    pulumi.String("roles/secretmanager.secretAccessor"),
-		}, pulumi.Parent(createdGoogleServiceAccount))
+	createdAddon := &ExternalSecretsAddon{}
+	err := ctx.RegisterComponentResource(ExternalSecretsResourceType, externalSecretsAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
 	if err != nil {
-		return errors.Wrap(err, "failed to add secrets accessor IAM binding")
-	}
-
-	//create workload-identity binding
-	_, err = serviceaccount.NewIAMBinding(ctx,
-		fmt.Sprintf("%s-workload-identity", vars.ExternalSecrets.KsaName),
-		&serviceaccount.IAMBindingArgs{
-			ServiceAccountId: createdGoogleServiceAccount.Name,
-			Role:             pulumi.String("roles/iam.workloadIdentityUser"),
-			Members: pulumi.StringArray{
-				pulumi.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]",
-					createdCluster.Project,
-					vars.ExternalSecrets.Namespace,
-					vars.ExternalSecrets.KsaName),
-			},
-		},
-		pulumi.Parent(createdGoogleServiceAccount),
-		pulumi.DependsOn([]pulumi.Resource{createdCluster}))
-	if err != nil {
-		return errors.Wrap(err, "failed to create workload-identity binding for external-secrets")
+		return nil, errors.Wrap(err, "failed to register external-secrets addon component")
 	}
 
 	//create namespace resource
@@ -100,79 +96,115 @@ func ExternalSecrets(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.ExternalSecrets.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.ExternalSecrets.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed  namespace")
+		return nil, errors.Wrapf(err, "failed  namespace")
 	}
+	createdAddon.Namespace = createdNamespace
 
-	//create kubernetes service account to be used by the external-secrets.
+	//create the google service account and the kubernetes service account to be used by external-secrets.
 	//it is not straight forward to add the gsa email as one of the helm values.
-	// so, instead, disable service account creation in helm release and create it separately add
-	// the Google workload identity annotation to the service account which requires the email id
-	// of the Google service account added as part of IAM module.
-	createdKubernetesServiceAccount, err := corev1.NewServiceAccount(ctx,
+	// so, instead, disable service account creation in helm release and create it separately with
+	// the Google workload identity annotation which requires the email id of the Google service account.
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
 		vars.ExternalSecrets.KsaName,
-		&corev1.ServiceAccountArgs{
-			Metadata: metav1.ObjectMetaPtrInput(
-				&metav1.ObjectMetaArgs{
-					Name:      pulumi.String(vars.ExternalSecrets.KsaName),
-					Namespace: createdNamespace.Metadata.Name(),
-					Annotations: pulumi.StringMap{
-						vars.WorkloadIdentityKubeAnnotationKey: createdGoogleServiceAccount.Email,
-					},
-				}),
-		}, pulumi.Parent(createdNamespace))
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.ExternalSecrets.KsaName),
+			GsaAccountId:   locals.LegacyGsaAccountId(vars.ExternalSecrets.KsaName),
+			GsaDescription: "external-secrets service account for solving dns challenges to issue certificates",
+			MovedFrom: &workloadidentity.MovedFrom{
+				GsaParent:    createdCluster,
+				KsaParentUrn: rootNamespaceUrn(ctx, vars.ExternalSecrets.Namespace),
+			},
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrap(err, "failed to create kubernetes service account")
+		return nil, errors.Wrap(err, "failed to create workload identity for external-secrets")
 	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export external-secrets gsa email
+	ctx.Export(outputs.ExternalSecretsGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	//add iam binding for secrets accessor role. an iam member is used instead when cluster scoped naming is enabled,
+	//as the project can be shared with other clusters. the binding is retained on delete, so that enabling cluster
+	//scoped naming does not remove all the members of the role from the project.
+	if locals.Options.Naming.IsClusterScoped() {
+		_, err = projects.NewIAMMember(ctx,
+			"external-secrets-secrets-accessor-binding",
+			&projects.IAMMemberArgs{
+				Member:  pulumi.Sprintf("serviceAccount:%s", createdWorkloadIdentity.GsaEmail),
+				Project: createdCluster.Project,
+				Role:    pulumi.String("roles/secretmanager.secretAccessor"),
+			}, pulumi.Parent(createdWorkloadIdentity.GoogleServiceAccount))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to add secrets accessor IAM member")
+		}
+	} else {
+		_, err = projects.NewIAMBinding(ctx,
+			"external-secrets-secrets-accessor-binding",
+			&projects.IAMBindingArgs{
+				Members: pulumi.StringArray{
+					pulumi.Sprintf("serviceAccount:%s", createdWorkloadIdentity.GsaEmail),
+				},
+				Project: createdCluster.Project,
+				Role:    pulumi.String("roles/secretmanager.secretAccessor"),
+			}, pulumi.Parent(createdWorkloadIdentity.GoogleServiceAccount), pulumi.RetainOnDelete(true))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to add secrets accessor IAM binding")
+		}
+	}
+
+	releaseSettings := helmReleaseSettings(locals, externalSecretsAddonName)
+	chartSource := helmChartSource(locals, externalSecretsAddonName, vars.ExternalSecrets.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, "external-secrets",
+	createdHelmRelease, err := helm.NewRelease(ctx, "external-secrets",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.ExternalSecrets.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.ExternalSecrets.HelmChartName),
-			Version:         pulumi.String(vars.ExternalSecrets.HelmChartVersion),
+			Chart:           chartSource.chart(vars.ExternalSecrets.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, externalSecretsAddonName, vars.ExternalSecrets.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
-				"customResourceManagerDisabled": pulumi.Bool(false),
-				"crds": pulumi.Map{
-					"create": pulumi.Bool(true),
-				},
-				"env": pulumi.Map{
-					"POLLER_INTERVAL_MILLISECONDS": pulumi.Int(vars.ExternalSecrets.SecretsPollingIntervalSeconds * 1000),
-					"LOG_LEVEL":                    pulumi.String("info"),
-					"LOG_MESSAGE_KEY":              pulumi.String("msg"),
-					"METRICS_PORT":                 pulumi.Int(3001),
-				},
-				"rbac": pulumi.Map{
-					"create": pulumi.Bool(true),
-				},
-				"serviceAccount": pulumi.Map{
-					"create": pulumi.Bool(false),
-					"name":   pulumi.String(vars.ExternalSecrets.KsaName),
-				},
-				"replicaCount": pulumi.Int(1),
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.ExternalSecrets.HelmChartRepo),
-			},
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values:          externalSecretsValues(locals),
+			RepositoryOpts:  chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.DependsOn([]pulumi.Resource{createdKubernetesServiceAccount}),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.DependsOn([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	//wait for the webhook to be serving as cluster-secret-stores are validated by it
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName: externalSecretsAddonName,
+			namespace: vars.ExternalSecrets.Namespace,
+			crds:      []string{"clustersecretstores.external-secrets.io", "externalsecrets.external-secrets.io"},
+			deployments: []string{
+				vars.ExternalSecrets.HelmChartName,
+				fmt.Sprintf("%s-webhook", vars.ExternalSecrets.HelmChartName),
+				fmt.Sprintf("%s-cert-controller", vars.ExternalSecrets.HelmChartName),
+			},
+		}, createdHelmRelease)
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to wait for external-secrets readiness")
 	}
 
 	//create cluster-secret-store to configure the gcp project from which the secrets need to be looked up
-	_, err = externalsecretsv1.NewClusterSecretStore(ctx, "cluster-secret-store",
+	createdClusterSecretStore, err := externalsecretsv1.NewClusterSecretStore(ctx, "cluster-secret-store",
 		&externalsecretsv1.ClusterSecretStoreArgs{
 			Metadata: metav1.ObjectMetaArgs{
 				Name:   pulumi.String(vars.ExternalSecrets.GcpSecretsManagerClusterSecretStoreName),
@@ -187,10 +219,53 @@ func ExternalSecrets(ctx *pulumi.Context, locals *localz.Locals,
 				RefreshInterval: pulumi.Int(vars.ExternalSecrets.SecretsPollingIntervalSeconds),
 			},
 		}, pulumi.Parent(createdNamespace),
-		pulumi.DependsOn([]pulumi.Resource{createdKubernetesServiceAccount}))
+		pulumi.DependsOn([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount, createdReadinessJob}))
 	if err != nil {
-		return errors.Wrap(err, "failed to create cluster-secret-store")
+		return nil, errors.Wrap(err, "failed to create cluster-secret-store")
 	}
+	createdAddon.ClusterSecretStore = createdClusterSecretStore
 
-	return nil
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"gsaEmail":  createdAddon.GsaEmail,
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register external-secrets addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// externalSecretsValues returns the helm values of the external-secrets release, merged with the values of the addon
+// settings.
+func externalSecretsValues(locals *localz.Locals) pulumi.Map {
+	return helmValues(locals, externalSecretsAddonName, vars.ExternalSecrets.HelmChartName, pulumi.Map{
+		"customResourceManagerDisabled": pulumi.Bool(false),
+		"crds": pulumi.Map{
+			"create": pulumi.Bool(true),
+		},
+		"env": pulumi.Map{
+			"POLLER_INTERVAL_MILLISECONDS": pulumi.Int(vars.ExternalSecrets.SecretsPollingIntervalSeconds * 1000),
+			"LOG_LEVEL":                    pulumi.String("info"),
+			"LOG_MESSAGE_KEY":              pulumi.String("msg"),
+			"METRICS_PORT":                 pulumi.Int(vars.ExternalSecrets.MetricsPort),
+		},
+		//the port of the metrics scraped by the monitoring addon. the chart only names the container port of the
+		//metrics when the metrics service is enabled.
+		"metrics": pulumi.Map{
+			"listen": pulumi.Map{
+				"port": pulumi.Int(vars.ExternalSecrets.MetricsPort),
+			},
+			"service": pulumi.Map{
+				"enabled": pulumi.Bool(true),
+				"port":    pulumi.Int(vars.ExternalSecrets.MetricsPort),
+			},
+		},
+		"rbac": pulumi.Map{
+			"create": pulumi.Bool(true),
+		},
+		"serviceAccount": pulumi.Map{
+			"create": pulumi.Bool(false),
+			"name":   pulumi.String(vars.ExternalSecrets.KsaName),
+		},
+		"replicaCount": pulumi.Int(1),
+	})
 }
@@ -0,0 +1,53 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"testing"
+)
+
+func TestExternalSecretsValues(t *testing.T) {
+	metricsPort := pulumi.Int(vars.ExternalSecrets.MetricsPort)
+	tests := []struct {
+		name   string
+		addons map[string]*options.AddonSettings
+		want   map[string]pulumi.Input
+	}{
+		{
+			//the chart only declares the "metrics" container port scraped by the pod monitoring with the service
+			name: "metrics port named for the pod monitoring",
+			want: map[string]pulumi.Input{
+				"metrics.listen.port":     metricsPort,
+				"metrics.service.enabled": pulumi.Bool(true),
+				"metrics.service.port":    metricsPort,
+				"env.METRICS_PORT":        metricsPort,
+			},
+		},
+		{
+			name: "values of the addon settings merged",
+			addons: map[string]*options.AddonSettings{
+				externalSecretsAddonName: {Values: options.HelmValues{
+					"replicaCount": 2,
+					"metrics":      map[string]interface{}{"service": map[string]interface{}{"annotations": nil}},
+				}},
+			},
+			want: map[string]pulumi.Input{
+				"replicaCount":            pulumi.Int(2),
+				"metrics.service.enabled": pulumi.Bool(true),
+				"serviceAccount.create":   pulumi.Bool(false),
+				"serviceAccount.name":     pulumi.String(vars.ExternalSecrets.KsaName),
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := externalSecretsValues(testLocals(tt.addons))
+			for path, want := range tt.want {
+				if got := valueAt(values, path); got != want {
+					t.Errorf("externalSecretsValues() %s = %v, want %v", path, got, want)
+				}
+			}
+		})
+	}
+}
@@ -3,26 +3,104 @@ package addons
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
 	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
 	pulumiyaml "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"path"
 )
 
-// GatewayApis installs kubernetes gateway-api crds
+const (
+	gatewayApisAddonName = "gateway-apis"
+	// GatewayApisResourceType is the pulumi type token of the gateway-apis addon component.
+	GatewayApisResourceType = "planton:gke:GatewayApisAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: gatewayApisAddonName,
+		//gateway-api crds are installed for the addons that make use of them
+		IsEnabled: func(locals *localz.Locals) bool {
+			addons := locals.GkeCluster.Spec.GetKubernetesAddons()
+			return addons.GetIsInstallIstio() || addons.GetIsInstallExternalDns()
+		},
+		Requires: []Requirement{RequiresKubernetesProvider},
+		//istio creates its gateway-classes, which block the removal along with the gateways and routes of the
+		//applications until they are deleted or the removal is forced
+		Removal: &Removal{
+			Crds: []string{
+				"httproutes.gateway.networking.k8s.io",
+				"grpcroutes.gateway.networking.k8s.io",
+				"referencegrants.gateway.networking.k8s.io",
+				"gateways.gateway.networking.k8s.io",
+				"gatewayclasses.gateway.networking.k8s.io",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return GatewayApis(ctx, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// GatewayApisAddon is the component of the gateway-apis addon.
+type GatewayApisAddon struct {
+	pulumi.ResourceState
+
+	// Crds are keyed by the file name of the manifest they are created from, ex:
+	//"gateway.networking.k8s.io_gateways.yaml".
+	Crds map[string]*pulumiyaml.ConfigGroup
+}
+
+// GatewayApis installs kubernetes gateway-api crds from the manifests vendored into the module
 func GatewayApis(ctx *pulumi.Context,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*GatewayApisAddon, error) {
+	createdAddon := &GatewayApisAddon{Crds: make(map[string]*pulumiyaml.ConfigGroup)}
+	err := ctx.RegisterComponentResource(GatewayApisResourceType, gatewayApisAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register gateway-apis addon component")
+	}
+
 	//create gateway-api crd resources
-	for _, crdFile := range vars.GatewayApis.CrdFiles {
-		_, err := pulumiyaml.NewConfigFile(ctx,
+	for _, crdManifest := range vars.GatewayApisCrdManifests() {
+		crdFile := path.Base(crdManifest.Path)
+		crdsArgs, err := crdManifestConfigGroupArgs(crdManifest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s gateway-api crd manifest", crdFile)
+		}
+		createdCrds, err := pulumiyaml.NewConfigGroup(ctx,
 			fmt.Sprintf("gateway-api-crd-%s", crdFile),
-			&pulumiyaml.ConfigFileArgs{
-				File: fmt.Sprintf("%s/%s", vars.GatewayApis.CrdDownloadBaseUrl, crdFile),
-			}, pulumi.Provider(kubernetesProvider),
+			crdsArgs, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon),
+			pulumi.Aliases(configFileAliases),
 		)
 		if err != nil {
-			return errors.Wrapf(err, "failed to add %s gateway-api crd manifest", crdFile)
+			return nil, errors.Wrapf(err, "failed to add %s gateway-api crd manifest", crdFile)
 		}
+		createdAddon.Crds[crdFile] = createdCrds
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{}); err != nil {
+		return nil, errors.Wrap(err, "failed to register gateway-apis addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// configFileAliases are added to the config-groups of vendored crd manifests, which used to be config-files
+// downloading the manifests from their urls, so that the existing crds are not replaced. both used to be created
+// without a parent, before they were parented to the component of their addon.
+var configFileAliases = []pulumi.Alias{
+	{Type: pulumi.String("kubernetes:yaml:ConfigFile"), NoParent: pulumi.Bool(true)},
+	{NoParent: pulumi.Bool(true)},
+}
+
+// crdManifestConfigGroupArgs returns the args of a config-group created from the manifest vendored into the module,
+// after verifying its checksum. an error is returned when the manifest is not vendored, nothing is downloaded at
+// deploy time.
+func crdManifestConfigGroupArgs(m *vars.CrdManifest) (*pulumiyaml.ConfigGroupArgs, error) {
+	content, err := vars.ReadCrdManifest(m)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return &pulumiyaml.ConfigGroupArgs{YAML: []string{content}}, nil
 }
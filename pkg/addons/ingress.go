@@ -0,0 +1,317 @@
+package addons
+
+import (
+	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	certmanagerv1 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/certmanager/certmanager/v1"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	networkingv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/networking/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// IngressExposure holds the resources that expose the web interface of an addon on an ingress dns domain.
+type IngressExposure struct {
+	Hostname string
+	// Certificate is issued for domains with tls enabled when the addon is exposed through istio. for ingress-nginx,
+	//cert-manager issues the certificate from the annotation of the ingress instead.
+	Certificate *certmanagerv1.Certificate
+	// Gateway and HttpRoutes expose the addon through the istio ingress load-balancer.
+	Gateway    *apiextensions.CustomResource
+	HttpRoutes []*apiextensions.CustomResource
+	// Ingress exposes the addon through the ingress-nginx controller.
+	Ingress *networkingv1.Ingress
+}
+
+// resolvedIngress is the ingress of an addon validated against the ingress dns domains of the cluster.
+type resolvedIngress struct {
+	hostname   string
+	controller string
+	domain     *gkeclusterv1.IngressDnsDomain
+}
+
+// exposedService is the kubernetes service serving the web interface of an addon.
+type exposedService struct {
+	addonName string
+	namespace string
+	name      string
+	port      int
+}
+
+// resolveIngress returns the ingress of the addon, which defaults the hostname to the prefix in the domain, ex:
+// "argocd.example.com". an error is returned when the domain is not one of the ingress dns domains of the cluster
+// or when the addons needed for the ingress are not enabled.
+func resolveIngress(locals *localz.Locals, hostnamePrefix string, ingress *options.Ingress) (*resolvedIngress, error) {
+	var domain *gkeclusterv1.IngressDnsDomain
+	for _, i := range locals.GkeCluster.Spec.IngressDnsDomains {
+		if i.Name == ingress.Domain {
+			domain = i
+		}
+	}
+	if domain == nil {
+		return nil, errors.Errorf("%s is not one of the ingress dns domains of the cluster", ingress.Domain)
+	}
+
+	//nil-safe getters as the kubernetes-addons of the cluster are optional
+	addons := locals.GkeCluster.Spec.GetKubernetesAddons()
+	controller := ingress.ControllerName()
+	switch {
+	case controller == options.IngressControllerIstio && !addons.GetIsInstallIstio():
+		return nil, errors.Errorf("ingress through %s requires the %s addon", controller, istioAddonName)
+	case controller == options.IngressControllerIngressNginx && !addons.GetIsInstallIngressNginx():
+		return nil, errors.Errorf("ingress through %s requires the %s addon", controller, ingressNginxAddonName)
+	case domain.IsTlsEnabled && !addons.GetIsInstallCertManager():
+		return nil, errors.Errorf("ingress on %s, which has tls enabled, requires the %s addon",
+			domain.Name, certManagerAddonName)
+	}
+
+	hostname := ingress.Hostname
+	if hostname == "" {
+		hostname = fmt.Sprintf("%s.%s", hostnamePrefix, domain.Name)
+	}
+	return &resolvedIngress{hostname: hostname, controller: controller, domain: domain}, nil
+}
+
+// url returns the url of the web interface exposed on the ingress.
+func (i *resolvedIngress) url() string {
+	if i.domain.IsTlsEnabled {
+		return fmt.Sprintf("https://%s", i.hostname)
+	}
+	return fmt.Sprintf("http://%s", i.hostname)
+}
+
+// exposeService exposes the service on the hostname of the ingress, either through a gateway of the istio ingress
+// load-balancer or through an ingress of the ingress-nginx controller. for domains with tls enabled, a certificate is
+// issued by the cluster-issuer of the domain and plain http is redirected to https.
+//
+// the resources are created once the istio, ingress-nginx and cert-manager addons they need are installed.
+func exposeService(ctx *pulumi.Context, locals *localz.Locals, ingress *resolvedIngress, service *exposedService,
+	installedAddons map[string]pulumi.Resource, parent pulumi.Resource) (*IngressExposure, error) {
+	exposure := &IngressExposure{Hostname: ingress.hostname}
+	dependencies := make([]pulumi.Resource, 0)
+	for _, name := range []string{istioAddonName, ingressNginxAddonName, certManagerAddonName} {
+		if installedAddon, ok := installedAddons[name]; ok {
+			dependencies = append(dependencies, installedAddon)
+		}
+	}
+	tlsSecretName := fmt.Sprintf("%s-tls", service.addonName)
+
+	if ingress.controller == options.IngressControllerIngressNginx {
+		annotations := pulumi.StringMap{}
+		var tls networkingv1.IngressTLSArray
+		if ingress.domain.IsTlsEnabled {
+			//cert-manager issues the certificate for the hosts of the tls section of the ingress
+			annotations["cert-manager.io/cluster-issuer"] = pulumi.String(ingress.domain.Name)
+			tls = networkingv1.IngressTLSArray{
+				networkingv1.IngressTLSArgs{
+					Hosts:      pulumi.ToStringArray([]string{ingress.hostname}),
+					SecretName: pulumi.String(tlsSecretName),
+				},
+			}
+		}
+		createdIngress, err := networkingv1.NewIngress(ctx,
+			service.addonName,
+			&networkingv1.IngressArgs{
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:        pulumi.String(service.addonName),
+						Namespace:   pulumi.String(service.namespace),
+						Labels:      pulumi.ToStringMap(locals.KubernetesLabels),
+						Annotations: annotations,
+					}),
+				Spec: networkingv1.IngressSpecArgs{
+					IngressClassName: pulumi.String("nginx"),
+					Tls:              tls,
+					Rules: networkingv1.IngressRuleArray{
+						networkingv1.IngressRuleArgs{
+							Host: pulumi.String(ingress.hostname),
+							Http: networkingv1.HTTPIngressRuleValueArgs{
+								Paths: networkingv1.HTTPIngressPathArray{
+									networkingv1.HTTPIngressPathArgs{
+										Path:     pulumi.String("/"),
+										PathType: pulumi.String("Prefix"),
+										Backend: networkingv1.IngressBackendArgs{
+											Service: networkingv1.IngressServiceBackendArgs{
+												Name: pulumi.String(service.name),
+												Port: networkingv1.ServiceBackendPortArgs{
+													Number: pulumi.Int(service.port),
+												},
+											},
+										},
+									},
+								},
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(parent), pulumi.DependsOn(dependencies))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create ingress for %s", service.addonName)
+		}
+		exposure.Ingress = createdIngress
+		return exposure, nil
+	}
+
+	//the gateway and its certificate are created in the namespace of the istio ingress load-balancer
+	listeners := pulumi.Array{
+		pulumi.Map{
+			"name":     pulumi.String("http"),
+			"hostname": pulumi.String(ingress.hostname),
+			"port":     pulumi.Int(vars.Istio.HttpPort),
+			"protocol": pulumi.String("HTTP"),
+			"allowedRoutes": pulumi.Map{
+				"namespaces": pulumi.Map{"from": pulumi.String("All")},
+			},
+		},
+	}
+	if ingress.domain.IsTlsEnabled {
+		createdCertificate, err := certmanagerv1.NewCertificate(ctx,
+			tlsSecretName,
+			&certmanagerv1.CertificateArgs{
+				Metadata: metav1.ObjectMetaArgs{
+					Name:      pulumi.String(tlsSecretName),
+					Namespace: pulumi.String(vars.Istio.GatewayNamespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				},
+				Spec: certmanagerv1.CertificateSpecArgs{
+					DnsNames:   pulumi.ToStringArray([]string{ingress.hostname}),
+					SecretName: pulumi.String(tlsSecretName),
+					IssuerRef: certmanagerv1.CertificateSpecIssuerRefArgs{
+						Kind: pulumi.String("ClusterIssuer"),
+						Name: pulumi.String(ingress.domain.Name),
+					},
+				},
+			}, pulumi.Parent(parent), pulumi.DependsOn(dependencies))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create certificate for %s", service.addonName)
+		}
+		exposure.Certificate = createdCertificate
+		dependencies = append(dependencies, createdCertificate)
+
+		listeners = append(listeners, pulumi.Map{
+			"name":     pulumi.String("https"),
+			"hostname": pulumi.String(ingress.hostname),
+			"port":     pulumi.Int(vars.Istio.HttpsPort),
+			"protocol": pulumi.String("HTTPS"),
+			"tls": pulumi.Map{
+				"mode": pulumi.String("Terminate"),
+				"certificateRefs": pulumi.Array{
+					pulumi.Map{
+						"kind": pulumi.String("Secret"),
+						"name": pulumi.String(tlsSecretName),
+					},
+				},
+			},
+			"allowedRoutes": pulumi.Map{
+				"namespaces": pulumi.Map{"from": pulumi.String("All")},
+			},
+		})
+	}
+
+	//the gateway is served by the existing ingress-external load-balancer instead of a gateway deployed by istio
+	createdGateway, err := apiextensions.NewCustomResource(ctx,
+		service.addonName,
+		&apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("gateway.networking.k8s.io/v1"),
+			Kind:       pulumi.String("Gateway"),
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(service.addonName),
+					Namespace: pulumi.String(vars.Istio.GatewayNamespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			OtherFields: pulumikubernetes.UntypedArgs{
+				"spec": pulumi.Map{
+					"gatewayClassName": pulumi.String(vars.Istio.GatewayClassName),
+					"addresses": pulumi.Array{
+						pulumi.Map{
+							"type": pulumi.String("Hostname"),
+							"value": pulumi.Sprintf("%s.%s.svc.cluster.local",
+								vars.Istio.IngressExternalLoadBalancerServiceName, vars.Istio.GatewayNamespace),
+						},
+					},
+					"listeners": listeners,
+				},
+			},
+		}, pulumi.Parent(parent), pulumi.DependsOn(dependencies))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create gateway for %s", service.addonName)
+	}
+	exposure.Gateway = createdGateway
+
+	//the service is routed on the https listener for domains with tls, which have plain http redirected to https
+	type httpRoute struct {
+		name     string
+		listener string
+		rule     pulumi.Map
+	}
+	routes := []httpRoute{
+		{
+			name:     service.addonName,
+			listener: "http",
+			rule: pulumi.Map{
+				"backendRefs": pulumi.Array{
+					pulumi.Map{
+						"name": pulumi.String(service.name),
+						"port": pulumi.Int(service.port),
+					},
+				},
+			},
+		},
+	}
+	if ingress.domain.IsTlsEnabled {
+		routes[0].listener = "https"
+		routes = append(routes, httpRoute{
+			name:     fmt.Sprintf("%s-https-redirect", service.addonName),
+			listener: "http",
+			rule: pulumi.Map{
+				"filters": pulumi.Array{
+					pulumi.Map{
+						"type": pulumi.String("RequestRedirect"),
+						"requestRedirect": pulumi.Map{
+							"scheme":     pulumi.String("https"),
+							"statusCode": pulumi.Int(301),
+						},
+					},
+				},
+			},
+		})
+	}
+	for _, route := range routes {
+		createdHttpRoute, err := apiextensions.NewCustomResource(ctx,
+			route.name,
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String("gateway.networking.k8s.io/v1"),
+				Kind:       pulumi.String("HTTPRoute"),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String(route.name),
+						Namespace: pulumi.String(service.namespace),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": pulumi.Map{
+						"parentRefs": pulumi.Array{
+							pulumi.Map{
+								"name":        pulumi.String(service.addonName),
+								"namespace":   pulumi.String(vars.Istio.GatewayNamespace),
+								"sectionName": pulumi.String(route.listener),
+							},
+						},
+						"hostnames": pulumi.ToStringArray([]string{ingress.hostname}),
+						"rules":     pulumi.Array{route.rule},
+					},
+				},
+			}, pulumi.Parent(createdGateway))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create http-route %s", route.name)
+		}
+		exposure.HttpRoutes = append(exposure.HttpRoutes, createdHttpRoute)
+	}
+	return exposure, nil
+}
@@ -11,6 +11,34 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	ingressNginxAddonName = "ingress-nginx"
+	// IngressNginxResourceType is the pulumi type token of the ingress-nginx addon component.
+	IngressNginxResourceType = "planton:gke:IngressNginxAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: ingressNginxAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallIngressNginx()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.IngressNginx.HelmChartName},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return IngressNginx(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// IngressNginxAddon is the component of the ingress-nginx addon.
+type IngressNginxAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
 // IngressNginx installs the Ingress Nginx controller in the Kubernetes cluster using Helm.
 // It creates a namespace for the Ingress Nginx resources and then deploys the Helm chart.
 //
@@ -18,17 +46,26 @@ import (
 // - ctx: The Pulumi context used for defining cloud resources.
 // - locals: A struct containing local configuration and metadata.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *IngressNginxAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a namespace for the Ingress Nginx resources, applying any necessary labels from the locals.
-// 2. Deploys the Ingress Nginx Helm chart into the created namespace with specific configurations for the controller service and ingress class resource.
-// 3. Uses Helm chart repository and version specified in the vars package.
-// 4. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Ingress Nginx resources, applying any necessary labels from the locals.
+// 3. Deploys the Ingress Nginx Helm chart into the created namespace with specific configurations for the controller service and ingress class resource.
+// 4. Uses Helm chart repository and version specified in the vars package.
+// 5. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
 func IngressNginx(ctx *pulumi.Context, locals *localz.Locals,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*IngressNginxAddon, error) {
+	createdAddon := &IngressNginxAddon{}
+	err := ctx.RegisterComponentResource(IngressNginxResourceType, ingressNginxAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register ingress-nginx addon component")
+	}
 	//create namespace resource
 	createdNamespace, err := corev1.NewNamespace(ctx,
 		vars.IngressNginx.Namespace,
@@ -36,27 +73,32 @@ func IngressNginx(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.IngressNginx.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.IngressNginx.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create namespace")
+		return nil, errors.Wrapf(err, "failed to create namespace")
 	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, ingressNginxAddonName)
+	chartSource := helmChartSource(locals, ingressNginxAddonName, vars.IngressNginx.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, "ingress-nginx",
+	createdHelmRelease, err := helm.NewRelease(ctx, "ingress-nginx",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.IngressNginx.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.IngressNginx.HelmChartName),
-			Version:         pulumi.String(vars.IngressNginx.HelmChartVersion),
+			Chart:           chartSource.chart(vars.IngressNginx.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, ingressNginxAddonName, vars.IngressNginx.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, ingressNginxAddonName, vars.IngressNginx.HelmChartName, pulumi.Map{
 				"controller": pulumi.Map{
 					"service": pulumi.StringMap{
 						"type": pulumi.String("ClusterIP"),
@@ -64,15 +106,25 @@ func IngressNginx(ctx *pulumi.Context, locals *localz.Locals,
 					"ingressClassResource": pulumi.Map{
 						"default": pulumi.Bool(true),
 					},
+					//exposes the metrics port of the controller to be scraped by the monitoring addon
+					"metrics": pulumi.Map{
+						"enabled": pulumi.Bool(locals.Options.Monitoring != nil),
+					},
 				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.IngressNginx.HelmChartRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register ingress-nginx addon outputs")
 	}
-	return nil
+	return createdAddon, nil
 }
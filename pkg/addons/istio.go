@@ -6,15 +6,64 @@ import (
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
 	istiov1alpha3 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/istio/networking/v1alpha3"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/compute"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
 	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
 	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
 	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
 	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	istioAddonName = "istio"
+	// IstioResourceType is the pulumi type token of the istio addon component.
+	IstioResourceType = "planton:gke:IstioAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: istioAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallIstio()
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		After:           []string{gatewayApisAddonName},
+		HelmCharts:      []string{vars.Istio.BaseHelmChartName, vars.Istio.IstiodHelmChartName, vars.Istio.GatewayHelmChartName},
+		ProtectedValues: []string{"meshConfig.ingressClass", "meshConfig.ingressService", "meshConfig.ingressSelector"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Istio(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// IstioAddon is the component of the istio addon.
+type IstioAddon struct {
+	pulumi.ResourceState
+
+	IngressInternalIp pulumi.StringOutput
+	IngressExternalIp pulumi.StringOutput
+
+	SystemNamespace    *corev1.Namespace
+	GatewayNamespace   *corev1.Namespace
+	BaseHelmRelease    *helm.Release
+	IstiodHelmRelease  *helm.Release
+	GatewayHelmRelease *helm.Release
+	GrpcWebEnvoyFilter *istiov1alpha3.EnvoyFilter
+	// TracingTelemetry sets the sampling of the spans sent to the opentelemetry collector, only created when the
+	//open-telemetry addon is configured.
+	TracingTelemetry *apiextensions.CustomResource
+	// IngressInternalAddress is the ip address of the IngressInternalService.
+	IngressInternalAddress *compute.Address
+	IngressInternalService *corev1.Service
+	// IngressExternalAddress is the ip address of the IngressExternalService.
+	IngressExternalAddress *compute.Address
+	IngressExternalService *corev1.Service
+}
+
 // Istio installs the Istio service mesh in the Kubernetes cluster using Helm. It creates the necessary namespaces,
 // installs the Helm charts for Istio base, Istiod, and gateway components, and sets up load balancers for ingress.
 //
@@ -24,24 +73,38 @@ import (
 // - createdCluster: The GKE cluster where Istio will be installed.
 // - gcpProvider: The GCP provider for Pulumi.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *IstioAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates the `istio-system` namespace and labels it with metadata from locals.
-// 2. Deploys the Istio base Helm chart into the `istio-system` namespace.
-// 3. Deploys the Istiod Helm chart into the `istio-system` namespace with specific mesh configuration.
-// 4. Creates the Istio gateway namespace and labels it with metadata from locals.
-// 5. Deploys the Istio gateway Helm chart into the gateway namespace, configuring service ports for HTTP, HTTPS, and other protocols.
-// 6. Creates a compute IP address for the internal load balancer and exports its address.
-// 7. Creates a Kubernetes service for the internal load balancer using the created IP address and service port configurations.
-// 8. Creates a compute IP address for the external load balancer and exports its address.
-// 9. Creates a Kubernetes service for the external load balancer using the created IP address and service port configurations.
-// 10. Handles errors and returns any errors encountered during the namespace creation, Helm release deployment, or service setup.
+// 1. Registers the component of the addon.
+// 2. Creates the `istio-system` namespace and labels it with metadata from locals.
+// 3. Deploys the Istio base Helm chart into the `istio-system` namespace.
+// 4. Deploys the Istiod Helm chart into the `istio-system` namespace with specific mesh configuration and waits for
+// istiod to be available before istio resources are created. when the open-telemetry addon is configured, the
+// collector is the default tracing provider of the mesh and a telemetry resource sets the sampling of the spans.
+// 5. Creates the Istio gateway namespace and labels it with metadata from locals.
+// 6. Deploys the Istio gateway Helm chart into the gateway namespace, configuring service ports for HTTP, HTTPS, and other protocols.
+// 7. Creates a compute IP address for the internal load balancer and exports its address.
+// 8. Creates a Kubernetes service for the internal load balancer using the created IP address and service port configurations.
+// 9. Creates a compute IP address for the external load balancer and exports its address.
+// 10. Creates a Kubernetes service for the external load balancer using the created IP address and service port configurations.
+// 11. Handles errors and returns any errors encountered during the namespace creation, Helm release deployment, or service setup.
 func Istio(ctx *pulumi.Context, locals *localz.Locals,
 	createdCluster *container.Cluster,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*IstioAddon, error) {
+
+	createdAddon := &IstioAddon{}
+	err := ctx.RegisterComponentResource(IstioResourceType, istioAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register istio addon component")
+	}
+
 	//create istio-system namespace resource
 	createdIstioSystemNamespace, err := corev1.NewNamespace(ctx,
 		vars.Istio.SystemNamespace,
@@ -49,63 +112,111 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.Istio.SystemNamespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Istio.SystemNamespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create istio-system namespace")
+		return nil, errors.Wrapf(err, "failed to create istio-system namespace")
 	}
+	createdAddon.SystemNamespace = createdIstioSystemNamespace
+
+	releaseSettings := helmReleaseSettings(locals, istioAddonName)
+	chartSource := helmChartSource(locals, istioAddonName, vars.Istio.HelmChartsRepo)
+	//the charts are installed once the master version of the cluster is checked to be supported by istio
+	chartsVersion := istioChartVersion(locals, createdCluster)
 
 	//create istio-base helm-release
-	_, err = helm.NewRelease(ctx, "istio-base",
+	createdIstioBaseHelmRelease, err := helm.NewRelease(ctx, "istio-base",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.Istio.BaseHelmChartName),
 			Namespace:       createdIstioSystemNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.Istio.BaseHelmChartName),
-			Version:         pulumi.String(vars.Istio.HelmChartsVersion),
+			Chart:           chartSource.chart(vars.Istio.BaseHelmChartName),
+			Version:         chartsVersion,
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values:          pulumi.Map{},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.Istio.HelmChartsRepo),
-			},
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values:          helmValues(locals, istioAddonName, vars.Istio.BaseHelmChartName, pulumi.Map{}),
+			RepositoryOpts:  chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdIstioSystemNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create istio-base helm release")
+		return nil, errors.Wrap(err, "failed to create istio-base helm release")
 	}
+	createdAddon.BaseHelmRelease = createdIstioBaseHelmRelease
 
 	//create istiod helm-release
-	_, err = helm.NewRelease(ctx, "istiod",
+	createdIstiodHelmRelease, err := helm.NewRelease(ctx, "istiod",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.Istio.IstiodHelmChartName),
 			Namespace:       createdIstioSystemNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.Istio.IstiodHelmChartName),
-			Version:         pulumi.String(vars.Istio.HelmChartsVersion),
+			Chart:           chartSource.chart(vars.Istio.IstiodHelmChartName),
+			Version:         chartsVersion,
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
-				"meshConfig": pulumi.StringMap{
-					"ingressClass":          pulumi.String("istio"),
-					"ingressControllerMode": pulumi.String("STRICT"),
-					"ingressService":        pulumi.String("ingress-external"),
-					"ingressSelector":       pulumi.String("ingress"),
-				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.Istio.HelmChartsRepo),
-			},
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values:          helmValues(locals, istioAddonName, vars.Istio.IstiodHelmChartName, istiodValues(locals)),
+			RepositoryOpts:  chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdIstioSystemNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create istiod helm release")
+		return nil, errors.Wrap(err, "failed to create istiod helm release")
+	}
+	createdAddon.IstiodHelmRelease = createdIstiodHelmRelease
+
+	//wait for istiod to be serving as istio resources are validated by its webhook
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName:   istioAddonName,
+			namespace:   vars.Istio.SystemNamespace,
+			crds:        []string{"envoyfilters.networking.istio.io"},
+			deployments: []string{vars.Istio.IstiodHelmChartName},
+		}, createdIstiodHelmRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for istio readiness")
+	}
+
+	//send the spans of the mesh to the opentelemetry collector with the configured sampling
+	if locals.Options.OpenTelemetry != nil {
+		createdTracingTelemetry, err := apiextensions.NewCustomResource(ctx,
+			"mesh-default",
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String("telemetry.istio.io/v1"),
+				Kind:       pulumi.String("Telemetry"),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String("mesh-default"),
+						Namespace: createdIstioSystemNamespace.Metadata.Name(),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": pulumi.Map{
+						"tracing": pulumi.Array{
+							pulumi.Map{
+								"providers": pulumi.Array{
+									pulumi.Map{
+										"name": pulumi.String(vars.OpenTelemetry.IstioProviderName),
+									},
+								},
+								"randomSamplingPercentage": pulumi.Float64(tracingSamplingPercentage(locals)),
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(createdIstiodHelmRelease),
+			pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create istio tracing telemetry")
+		}
+		createdAddon.TracingTelemetry = createdTracingTelemetry
 	}
 
 	//create istio-gateway namespace resource
@@ -115,13 +226,14 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.Istio.GatewayNamespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Istio.GatewayNamespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create istio-system namespace")
+		return nil, errors.Wrapf(err, "failed to create istio-system namespace")
 	}
+	createdAddon.GatewayNamespace = createdIstioGatewayNamespace
 
 	//create istio-gateway helm-release
 	createdIstioGatewayHelmRelease, err := helm.NewRelease(ctx,
@@ -129,14 +241,15 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.Istio.GatewayHelmChartName),
 			Namespace:       createdIstioGatewayNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.Istio.GatewayHelmChartName),
-			Version:         pulumi.String(vars.Istio.HelmChartsVersion),
+			Chart:           chartSource.chart(vars.Istio.GatewayHelmChartName),
+			Version:         chartsVersion,
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, istioAddonName, vars.Istio.GatewayHelmChartName, pulumi.Map{
 				"service": pulumi.Map{
 					"type": pulumi.String("ClusterIP"),
 					"ports": pulumi.MapArray{
@@ -166,18 +279,18 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 						},
 					},
 				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.Istio.HelmChartsRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdIstioGatewayNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create istio-gateway helm release")
+		return nil, errors.Wrap(err, "failed to create istio-gateway helm release")
 	}
+	createdAddon.GatewayHelmRelease = createdIstioGatewayHelmRelease
 
 	//create grpc-web envoy filter to support grpc-web clients
-	_, err = istiov1alpha3.NewEnvoyFilter(ctx,
+	createdGrpcWebEnvoyFilter, err := istiov1alpha3.NewEnvoyFilter(ctx,
 		"grpc-web",
 		&istiov1alpha3.EnvoyFilterArgs{
 			Metadata: metav1.ObjectMetaArgs{
@@ -218,7 +331,11 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 				},
 			},
 		}, pulumi.Parent(createdIstioGatewayNamespace),
-		pulumi.DependsOn([]pulumi.Resource{createdIstioGatewayHelmRelease}))
+		pulumi.DependsOn([]pulumi.Resource{createdIstioGatewayHelmRelease, createdReadinessJob}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create grpc-web envoy filter")
+	}
+	createdAddon.GrpcWebEnvoyFilter = createdGrpcWebEnvoyFilter
 
 	//define array of ports to be configured for both internal and external ingress services
 	loadBalancerServicePortArray := corev1.ServicePortArray{
@@ -246,22 +363,24 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 	createdIngressInternalLoadBalancerIp, err := compute.NewAddress(ctx,
 		vars.Istio.IngressInternalLoadBalancerServiceName,
 		&compute.AddressArgs{
-			Name:        pulumi.Sprintf("gke-%s-ingress-internal", locals.GkeCluster.Metadata.Name),
+			Name:        pulumi.String(locals.ComputeAddressName("ingress-internal")),
 			Project:     createdCluster.Project,
 			Region:      pulumi.String(locals.GkeCluster.Spec.Region),
 			AddressType: pulumi.String("INTERNAL"),
 			Labels:      pulumi.ToStringMap(locals.GcpLabels),
 			Subnetwork:  createdCluster.Subnetwork,
-		}, pulumi.Parent(createdCluster))
+		}, pulumi.Parent(createdAddon), movedFrom(createdCluster))
 	if err != nil {
-		return errors.Wrap(err, "failed to create ip address for ingress-internal load-balancer")
+		return nil, errors.Wrap(err, "failed to create ip address for ingress-internal load-balancer")
 	}
+	createdAddon.IngressInternalAddress = createdIngressInternalLoadBalancerIp
+	createdAddon.IngressInternalIp = createdIngressInternalLoadBalancerIp.Address
 
 	//export ingress-internal ip
 	ctx.Export(outputs.IngressInternalIp, createdIngressInternalLoadBalancerIp.Address)
 
 	//create load-balancer service for internal load-balancer
-	_, err = corev1.NewService(ctx,
+	createdIngressInternalService, err := corev1.NewService(ctx,
 		vars.Istio.IngressInternalLoadBalancerServiceName,
 		&corev1.ServiceArgs{
 			Metadata: metav1.ObjectMetaArgs{
@@ -278,28 +397,31 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 			},
 		}, pulumi.Parent(createdIstioGatewayNamespace))
 	if err != nil {
-		return errors.Wrapf(err, "failed to create ingress-external kubernetes service")
+		return nil, errors.Wrapf(err, "failed to create ingress-internal kubernetes service")
 	}
+	createdAddon.IngressInternalService = createdIngressInternalService
 
 	//create compute ip address for external load-balancer
 	createdIngressExternalLoadBalancerIp, err := compute.NewAddress(ctx,
 		vars.Istio.IngressExternalLoadBalancerServiceName,
 		&compute.AddressArgs{
-			Name:        pulumi.Sprintf("gke-%s-ingress-external", locals.GkeCluster.Metadata.Name),
+			Name:        pulumi.String(locals.ComputeAddressName("ingress-external")),
 			Project:     createdCluster.Project,
 			Region:      pulumi.String(locals.GkeCluster.Spec.Region),
 			AddressType: pulumi.String("EXTERNAL"),
 			Labels:      pulumi.ToStringMap(locals.GcpLabels),
-		}, pulumi.Parent(createdCluster))
+		}, pulumi.Parent(createdAddon), movedFrom(createdCluster))
 	if err != nil {
-		return errors.Wrap(err, "failed to create ip address for ingress-internal load-balancer")
+		return nil, errors.Wrap(err, "failed to create ip address for ingress-external load-balancer")
 	}
+	createdAddon.IngressExternalAddress = createdIngressExternalLoadBalancerIp
+	createdAddon.IngressExternalIp = createdIngressExternalLoadBalancerIp.Address
 
 	//export ingress-external ip
 	ctx.Export(outputs.IngressExternalIp, createdIngressExternalLoadBalancerIp.Address)
 
 	//create load-balancer service for external load-balancer
-	_, err = corev1.NewService(ctx,
+	createdIngressExternalService, err := corev1.NewService(ctx,
 		vars.Istio.IngressExternalLoadBalancerServiceName,
 		&corev1.ServiceArgs{
 			Metadata: metav1.ObjectMetaArgs{
@@ -316,8 +438,52 @@ func Istio(ctx *pulumi.Context, locals *localz.Locals,
 			},
 		}, pulumi.Parent(createdIstioGatewayNamespace))
 	if err != nil {
-		return errors.Wrapf(err, "failed to create ingress-external kubernetes service")
+		return nil, errors.Wrapf(err, "failed to create ingress-external kubernetes service")
 	}
+	createdAddon.IngressExternalService = createdIngressExternalService
 
-	return nil
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"ingressInternalIp": createdAddon.IngressInternalIp,
+		"ingressExternalIp": createdAddon.IngressExternalIp,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register istio addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// istiodValues returns the helm values of istiod. when the open-telemetry addon is configured, the collector is added
+// as the default tracing provider of the mesh.
+func istiodValues(locals *localz.Locals) pulumi.Map {
+	meshConfig := pulumi.Map{
+		"ingressClass":          pulumi.String("istio"),
+		"ingressControllerMode": pulumi.String("STRICT"),
+		"ingressService":        pulumi.String("ingress-external"),
+		"ingressSelector":       pulumi.String("ingress"),
+	}
+	if locals.Options.OpenTelemetry != nil {
+		//https://istio.io/latest/docs/tasks/observability/distributed-tracing/opentelemetry/
+		meshConfig["extensionProviders"] = pulumi.Array{
+			pulumi.Map{
+				"name": pulumi.String(vars.OpenTelemetry.IstioProviderName),
+				"opentelemetry": pulumi.Map{
+					"service": pulumi.String(openTelemetryCollectorHost()),
+					"port":    pulumi.Int(vars.OpenTelemetry.OtlpGrpcPort),
+				},
+			},
+		}
+		meshConfig["defaultProviders"] = pulumi.Map{
+			"tracing": pulumi.ToStringArray([]string{vars.OpenTelemetry.IstioProviderName}),
+		}
+	}
+	return pulumi.Map{
+		"meshConfig": meshConfig,
+	}
+}
+
+// tracingSamplingPercentage returns the percentage of the requests traced by istio.
+func tracingSamplingPercentage(locals *localz.Locals) float64 {
+	if p := locals.Options.OpenTelemetry.TracingSamplingPercentage; p != nil {
+		return *p
+	}
+	return vars.OpenTelemetry.DefaultTracingSamplingPercentage
 }
@@ -0,0 +1,225 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	kedaAddonName = "keda"
+	// KedaResourceType is the pulumi type token of the keda addon component.
+	KedaResourceType = "planton:gke:KedaAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: kedaAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Keda != nil
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		HelmCharts:      []string{vars.Keda.HelmChartName},
+		ProtectedValues: []string{"serviceAccount.create", "serviceAccount.name"},
+		Removal: &Removal{
+			Crds: []string{
+				"scaledjobs.keda.sh",
+				"scaledobjects.keda.sh",
+				"triggerauthentications.keda.sh",
+				"clustertriggerauthentications.keda.sh",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Keda(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// KedaAddon is the component of the keda addon.
+type KedaAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	HelmRelease      *helm.Release
+	// GcpClusterTriggerAuthentication authenticates the gcp scalers of all namespaces with the GSA of the operator.
+	GcpClusterTriggerAuthentication *apiextensions.CustomResource
+}
+
+// Keda installs KEDA in the Kubernetes cluster using Helm, sets up the Google Service Account (GSA) used by the gcp
+// scalers and creates a ClusterTriggerAuthentication for them.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where KEDA will be installed.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *KedaAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates a namespace for KEDA and labels it with metadata from locals.
+//  3. Creates the workload identity for the KEDA operator, i.e. a Google Service Account (GSA) with the monitoring
+//     viewer role in the project of the cluster and the configured projects, the Workload Identity binding and a
+//     Kubernetes Service Account (KSA) annotated with the GSA email.
+//  4. Exports the email of the created GSA.
+//  5. Deploys the KEDA Helm chart into the created namespace with the created KSA.
+//  6. Waits for the CRDs to be established and the operator, metrics api server and webhooks to be available.
+//  7. Creates a ClusterTriggerAuthentication for the gcp pod identity provider.
+func Keda(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*KedaAddon, error) {
+	settings := locals.Options.Keda
+
+	createdAddon := &KedaAddon{}
+	err := ctx.RegisterComponentResource(KedaResourceType, kedaAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register keda addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.Keda.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.Keda.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Keda.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create keda namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	//the gcp scalers, ex: pub/sub, read the metrics of the scaled resources from cloud monitoring
+	projectRoles := []*workloadidentity.ProjectRole{{Role: "roles/monitoring.viewer"}}
+	for _, projectId := range settings.MonitoringProjectIds {
+		projectRoles = append(projectRoles, &workloadidentity.ProjectRole{
+			Role:      "roles/monitoring.viewer",
+			ProjectId: projectId,
+		})
+	}
+
+	//create the google service account and the kubernetes service account to be used by keda
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
+		vars.Keda.KsaName,
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.Keda.KsaName),
+			GsaAccountId:   locals.GsaAccountId(vars.Keda.KsaName),
+			GsaDescription: "keda service account for reading the cloud monitoring metrics of the gcp scalers",
+			ProjectRoles:   projectRoles,
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for keda")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export keda gsa email
+	ctx.Export(outputs.KedaGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	releaseSettings := helmReleaseSettings(locals, kedaAddonName)
+	chartSource := helmChartSource(locals, kedaAddonName, vars.Keda.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "keda",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.Keda.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.Keda.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, kedaAddonName, vars.Keda.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, kedaAddonName, vars.Keda.HelmChartName, pulumi.Map{
+				"serviceAccount": pulumi.Map{
+					"create": pulumi.Bool(false),
+					"name":   pulumi.String(vars.Keda.KsaName),
+				},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.DependsOn([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create keda helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	//wait for the admission webhooks as the keda resources are validated by them
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName: kedaAddonName,
+			namespace: vars.Keda.Namespace,
+			crds:      []string{"clustertriggerauthentications.keda.sh", "scaledobjects.keda.sh"},
+			deployments: []string{
+				"keda-operator",
+				"keda-operator-metrics-apiserver",
+				"keda-admission-webhooks",
+			},
+		}, createdHelmRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for keda readiness")
+	}
+
+	//https://keda.sh/docs/latest/authentication-providers/gcp-workload-identity/
+	createdClusterTriggerAuthentication, err := apiextensions.NewCustomResource(ctx,
+		vars.Keda.GcpClusterTriggerAuthenticationName,
+		&apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("keda.sh/v1alpha1"),
+			Kind:       pulumi.String("ClusterTriggerAuthentication"),
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.Keda.GcpClusterTriggerAuthenticationName),
+					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			OtherFields: pulumikubernetes.UntypedArgs{
+				"spec": pulumi.Map{
+					"podIdentity": pulumi.Map{
+						"provider": pulumi.String("gcp"),
+					},
+				},
+			},
+		}, pulumi.Parent(createdHelmRelease),
+		pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create %s cluster-trigger-authentication",
+			vars.Keda.GcpClusterTriggerAuthenticationName)
+	}
+	createdAddon.GcpClusterTriggerAuthentication = createdClusterTriggerAuthentication
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+		"gsaEmail":  createdWorkloadIdentity.GsaEmail,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register keda addon outputs")
+	}
+	return createdAddon, nil
+}
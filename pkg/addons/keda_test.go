@@ -0,0 +1,135 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testStackOutputs returns the outputs of a previous update of the stack with the installed addons and the guarded
+// addon removals.
+func testStackOutputs(installedAddons, guardedAddonRemovals []string) resource.PropertyMap {
+	toArray := func(names []string) resource.PropertyValue {
+		values := make([]resource.PropertyValue, 0)
+		for _, name := range names {
+			values = append(values, resource.NewStringProperty(name))
+		}
+		return resource.NewArrayProperty(values)
+	}
+	return resource.PropertyMap{
+		outputs.InstalledAddons:      toArray(installedAddons),
+		outputs.GuardedAddonRemovals: toArray(guardedAddonRemovals),
+	}
+}
+
+func TestKedaRemovalGuarded(t *testing.T) {
+	tests := []struct {
+		name         string
+		stackOutputs resource.PropertyMap
+		installed    []string
+		want         []string
+	}{
+		{
+			name:         "first update of the stack",
+			stackOutputs: resource.PropertyMap{},
+			want:         []string{},
+		},
+		{
+			name:         "keda never installed",
+			stackOutputs: testStackOutputs([]string{istioAddonName}, nil),
+			want:         []string{},
+		},
+		{
+			name:         "keda disabled after being installed",
+			stackOutputs: testStackOutputs([]string{istioAddonName, kedaAddonName}, nil),
+			want:         []string{kedaAddonName},
+		},
+		{
+			//the removal stays guarded while the update that removes keda did not succeed
+			name:         "removal of keda guarded in the previous update",
+			stackOutputs: testStackOutputs([]string{istioAddonName}, []string{kedaAddonName}),
+			want:         []string{kedaAddonName},
+		},
+		{
+			name:         "keda still installed",
+			stackOutputs: testStackOutputs([]string{kedaAddonName}, nil),
+			installed:    []string{kedaAddonName},
+			want:         []string{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := &testMocks{stackOutputs: tt.stackOutputs}
+			var got []string
+			runWithMocks(t, mocks, func(ctx *pulumi.Context) error {
+				installed, err := testInstalledAddons(ctx, tt.installed...)
+				if err != nil {
+					return err
+				}
+				got, err = defaultRegistry.guardedAddons(ctx, installed)
+				return err
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("guardedAddons() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKedaRemovalJob(t *testing.T) {
+	keda, _ := defaultRegistry.Get(kedaAddonName)
+	wantCrds := []string{
+		"scaledjobs.keda.sh",
+		"scaledobjects.keda.sh",
+		"triggerauthentications.keda.sh",
+		"clustertriggerauthentications.keda.sh",
+	}
+	if !reflect.DeepEqual(keda.Removal.Crds, wantCrds) {
+		t.Fatalf("keda removal crds = %v, want %v", keda.Removal.Crds, wantCrds)
+	}
+
+	mocks := &testMocks{}
+	runWithMocks(t, mocks, func(ctx *pulumi.Context) error {
+		kubernetesProvider, err := testKubernetesProvider(ctx)
+		if err != nil {
+			return err
+		}
+		return guardRemoval(ctx, &Input{Locals: testLocals(nil), KubernetesProvider: kubernetesProvider}, keda)
+	})
+
+	job := mocks.resource(t, "kubernetes:batch/v1:Job", "keda-removal")
+	if got := propertyAt(job.Inputs, "metadata", "namespace").StringValue(); got != vars.AddonRemoval.Namespace {
+		t.Errorf("removal job namespace = %q, want %q", got, vars.AddonRemoval.Namespace)
+	}
+	containers := propertyAt(job.Inputs, "spec", "template", "spec", "containers").ArrayValue()
+	env := make(map[string]string)
+	for _, variable := range containers[0].ObjectValue()["env"].ArrayValue() {
+		env[variable.ObjectValue()["name"].StringValue()] = variable.ObjectValue()["value"].StringValue()
+	}
+	wantEnv := map[string]string{
+		"ADDON": kedaAddonName,
+		"CRDS":  strings.Join(wantCrds, " "),
+		//the removal is refused while scaled objects exist, unless it is forced
+		"FORCE": "false",
+	}
+	for name, want := range wantEnv {
+		if env[name] != want {
+			t.Errorf("removal job %s = %q, want %q", name, env[name], want)
+		}
+	}
+
+	//the job can only delete the crds of keda
+	role := mocks.resource(t, "kubernetes:rbac.authorization.k8s.io/v1:ClusterRole", "keda-removal")
+	rules := propertyAt(role.Inputs, "rules").ArrayValue()
+	deletable := make([]string, 0)
+	for _, name := range rules[1].ObjectValue()["resourceNames"].ArrayValue() {
+		deletable = append(deletable, name.StringValue())
+	}
+	if !reflect.DeepEqual(deletable, wantCrds) {
+		t.Errorf("removal cluster role deletable crds = %v, want %v", deletable, wantCrds)
+	}
+}
@@ -0,0 +1,231 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	keycloakAddonName = "keycloak"
+	// KeycloakResourceType is the pulumi type token of the keycloak addon component.
+	KeycloakResourceType = "planton:gke:KeycloakAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: keycloakAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Keycloak != nil
+		},
+		Requires:  []Requirement{RequiresKubernetesProvider},
+		DependsOn: []string{keycloakOperatorAddonName, zalandoPostgresOperatorAddonName},
+		//the addons used for the ingress of keycloak, when enabled
+		After: []string{istioAddonName, ingressNginxAddonName, certManagerAddonName},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Keycloak(ctx, input.Locals, input.KubernetesProvider, input.Installed, input.opts...)
+		},
+	})
+}
+
+// KeycloakAddon is the component of the keycloak addon.
+type KeycloakAddon struct {
+	pulumi.ResourceState
+
+	// Url of the keycloak server, empty when keycloak is not exposed on an ingress.
+	Url pulumi.StringOutput
+
+	// Database is the postgres cluster of the zalando operator, which is retained when the addon is removed.
+	Database *apiextensions.CustomResource
+	Keycloak *apiextensions.CustomResource
+	// Ingress is only created when an ingress is configured.
+	Ingress *IngressExposure
+}
+
+// Keycloak creates a Keycloak instance with the keycloak operator, backed by a postgres cluster created with the
+// zalando postgres operator, and optionally exposes it on one of the ingress dns domains.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - installedAddons: The components of the installed addons keyed by the addon name, used for the operators and
+// the ingress.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *KeycloakAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates the postgres cluster in the namespace of the keycloak operator once the zalando operator is installed.
+//     the operator creates the database, its owner and the secret with the credentials of the owner.
+//  3. Creates the keycloak instance once the keycloak operator is ready, connecting it to the database with the
+//     credentials from the secret of the zalando operator.
+//  4. When an ingress is configured, exposes the keycloak service on the hostname through istio or ingress-nginx.
+func Keycloak(ctx *pulumi.Context, locals *localz.Locals, kubernetesProvider *pulumikubernetes.Provider,
+	installedAddons map[string]pulumi.Resource, opts ...pulumi.ResourceOption) (*KeycloakAddon, error) {
+	settings := locals.Options.Keycloak
+
+	installedKeycloakOperator, ok := installedAddons[keycloakOperatorAddonName].(*KeycloakOperatorAddon)
+	if !ok {
+		return nil, errors.Errorf("keycloak requires the %s addon", keycloakOperatorAddonName)
+	}
+	installedPostgresOperator, ok := installedAddons[zalandoPostgresOperatorAddonName]
+	if !ok {
+		return nil, errors.Errorf("keycloak requires the %s addon", zalandoPostgresOperatorAddonName)
+	}
+
+	createdAddon := &KeycloakAddon{Url: pulumi.String("").ToStringOutput()}
+	err := ctx.RegisterComponentResource(KeycloakResourceType, keycloakAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register keycloak addon component")
+	}
+
+	databaseInstances := settings.DatabaseInstances
+	if databaseInstances == 0 {
+		databaseInstances = 1
+	}
+	databaseVolumeSize := settings.DatabaseVolumeSize
+	if databaseVolumeSize == "" {
+		databaseVolumeSize = vars.Keycloak.PostgresVolumeSize
+	}
+
+	//the database is retained when keycloak is removed, the zalando operator would otherwise delete its volumes
+	createdDatabase, err := apiextensions.NewCustomResource(ctx,
+		vars.Keycloak.PostgresName,
+		&apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("acid.zalan.do/v1"),
+			Kind:       pulumi.String("postgresql"),
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(vars.Keycloak.PostgresName),
+					Namespace: pulumi.String(vars.KeycloakOperator.Namespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			OtherFields: pulumikubernetes.UntypedArgs{
+				"spec": pulumi.Map{
+					"teamId":            pulumi.String(vars.Keycloak.PostgresTeamId),
+					"numberOfInstances": pulumi.Int(databaseInstances),
+					"volume": pulumi.Map{
+						"size": pulumi.String(databaseVolumeSize),
+					},
+					"postgresql": pulumi.Map{
+						"version": pulumi.String(vars.Keycloak.PostgresVersion),
+					},
+					"users": pulumi.Map{
+						vars.Keycloak.DatabaseUser: pulumi.StringArray{},
+					},
+					"databases": pulumi.Map{
+						vars.Keycloak.DatabaseName: pulumi.String(vars.Keycloak.DatabaseUser),
+					},
+				},
+			},
+		}, pulumi.Parent(createdAddon),
+		pulumi.DependsOn([]pulumi.Resource{installedPostgresOperator, installedKeycloakOperator.Namespace}),
+		pulumi.RetainOnDelete(true))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create keycloak postgres cluster")
+	}
+	createdAddon.Database = createdDatabase
+
+	instances := settings.Instances
+	if instances == 0 {
+		instances = 1
+	}
+	//secret created by the zalando operator for the owner of the database
+	credentialsSecret := pulumi.String(fmt.Sprintf("%s.%s.credentials.postgresql.acid.zalan.do",
+		vars.Keycloak.DatabaseUser, vars.Keycloak.PostgresName))
+	//tls is terminated by the ingress, the ingress of the operator is disabled in favour of the one of the module
+	spec := pulumi.Map{
+		"instances": pulumi.Int(instances),
+		"db": pulumi.Map{
+			"vendor":   pulumi.String("postgres"),
+			"host":     pulumi.String(vars.Keycloak.PostgresName),
+			"database": pulumi.String(vars.Keycloak.DatabaseName),
+			"usernameSecret": pulumi.Map{
+				"name": credentialsSecret,
+				"key":  pulumi.String("username"),
+			},
+			"passwordSecret": pulumi.Map{
+				"name": credentialsSecret,
+				"key":  pulumi.String("password"),
+			},
+		},
+		"http": pulumi.Map{
+			"httpEnabled": pulumi.Bool(true),
+		},
+		"proxy": pulumi.Map{
+			"headers": pulumi.String("xforwarded"),
+		},
+		"ingress": pulumi.Map{
+			"enabled": pulumi.Bool(false),
+		},
+	}
+
+	var ingress *resolvedIngress
+	if settings.Ingress != nil {
+		ingress, err = resolveIngress(locals, vars.Keycloak.HostnamePrefix, settings.Ingress)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ingress")
+		}
+		spec["hostname"] = pulumi.Map{
+			"hostname": pulumi.String(ingress.url()),
+		}
+		createdAddon.Url = pulumi.String(ingress.url()).ToStringOutput()
+	} else {
+		spec["hostname"] = pulumi.Map{
+			"strict": pulumi.Bool(false),
+		}
+	}
+
+	createdKeycloak, err := apiextensions.NewCustomResource(ctx,
+		vars.Keycloak.Name,
+		&apiextensions.CustomResourceArgs{
+			ApiVersion: pulumi.String("k8s.keycloak.org/v2alpha1"),
+			Kind:       pulumi.String("Keycloak"),
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(vars.Keycloak.Name),
+					Namespace: pulumi.String(vars.KeycloakOperator.Namespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			OtherFields: pulumikubernetes.UntypedArgs{
+				"spec": spec,
+			},
+		}, pulumi.Parent(createdAddon),
+		pulumi.DependsOn([]pulumi.Resource{installedKeycloakOperator.ReadinessJob, createdDatabase}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create keycloak instance")
+	}
+	createdAddon.Keycloak = createdKeycloak
+
+	if ingress != nil {
+		createdIngress, err := exposeService(ctx, locals, ingress,
+			&exposedService{
+				addonName: keycloakAddonName,
+				namespace: vars.KeycloakOperator.Namespace,
+				//service created by the operator for the instance
+				name: fmt.Sprintf("%s-service", vars.Keycloak.Name),
+				port: vars.Keycloak.HttpPort,
+			}, installedAddons, createdKeycloak)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to expose keycloak")
+		}
+		createdAddon.Ingress = createdIngress
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"url": createdAddon.Url,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register keycloak addon outputs")
+	}
+	return createdAddon, nil
+}
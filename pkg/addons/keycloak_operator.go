@@ -0,0 +1,203 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	pulumiyaml "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"path"
+	"strings"
+)
+
+const (
+	keycloakOperatorAddonName = "keycloak-operator"
+	// KeycloakOperatorResourceType is the pulumi type token of the keycloak-operator addon component.
+	KeycloakOperatorResourceType = "planton:gke:KeycloakOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: keycloakOperatorAddonName,
+		//the operator is installed for the keycloak instance of the keycloak addon
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Keycloak != nil
+		},
+		Requires: []Requirement{RequiresKubernetesProvider},
+		Removal: &Removal{
+			Crds: []string{
+				"keycloakrealmimports.k8s.keycloak.org",
+				"keycloaks.k8s.keycloak.org",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return KeycloakOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// KeycloakOperatorAddon is the component of the keycloak-operator addon.
+type KeycloakOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace *corev1.Namespace
+	// Crds are keyed by the file name of the vendored manifest they are created from,
+	//ex: "keycloaks.k8s.keycloak.org-v1.yml".
+	Crds     map[string]*pulumiyaml.ConfigGroup
+	Operator *pulumiyaml.ConfigGroup
+	// ReadinessJob completes once the crds are established and the operator is available.
+	ReadinessJob *batchv1.Job
+}
+
+// KeycloakOperator installs the Keycloak Operator in the Kubernetes cluster from the manifests vendored into the
+// module, as the operator is not published as a helm chart.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *KeycloakOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates a namespace for the operator and labels it with metadata from locals. the operator only watches the
+//     namespace it is installed in, so keycloak instances are created in the same namespace.
+//  3. Creates the keycloak crds from their manifests.
+//  4. Creates the operator deployment and its rbac from its manifest, placed in the created namespace and
+//     pulling its images from the mirror when one is configured.
+//  5. Creates a job that waits for the crds to be established and the operator to be available.
+func KeycloakOperator(ctx *pulumi.Context, locals *localz.Locals,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*KeycloakOperatorAddon, error) {
+	createdAddon := &KeycloakOperatorAddon{Crds: make(map[string]*pulumiyaml.ConfigGroup)}
+	err := ctx.RegisterComponentResource(KeycloakOperatorResourceType, keycloakOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register keycloak-operator addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.KeycloakOperator.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.KeycloakOperator.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.KeycloakOperator.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create keycloak-operator namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	//create keycloak crd resources
+	operatorDependencies := []pulumi.Resource{createdNamespace}
+	for _, crdManifest := range vars.KeycloakOperatorCrdManifests() {
+		crdFile := path.Base(crdManifest.Path)
+		crdsArgs, err := crdManifestConfigGroupArgs(crdManifest)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s keycloak crd manifest", crdFile)
+		}
+		createdCrds, err := pulumiyaml.NewConfigGroup(ctx,
+			"keycloak-crd-"+crdFile,
+			crdsArgs, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to add %s keycloak crd manifest", crdFile)
+		}
+		createdAddon.Crds[crdFile] = createdCrds
+		operatorDependencies = append(operatorDependencies, createdCrds)
+	}
+
+	//create operator resources
+	operatorArgs, err := crdManifestConfigGroupArgs(vars.KeycloakOperatorManifest())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read keycloak operator manifest")
+	}
+	operatorArgs.Transformations = []pulumiyaml.Transformation{
+		keycloakOperatorTransformation(locals, vars.KeycloakOperator.Namespace),
+	}
+	createdOperator, err := pulumiyaml.NewConfigGroup(ctx,
+		"keycloak-operator",
+		operatorArgs, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon),
+		pulumi.DependsOn(operatorDependencies))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to add keycloak operator manifest")
+	}
+	createdAddon.Operator = createdOperator
+
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName:   keycloakOperatorAddonName,
+			namespace:   vars.KeycloakOperator.Namespace,
+			crds:        []string{"keycloaks.k8s.keycloak.org", "keycloakrealmimports.k8s.keycloak.org"},
+			deployments: []string{vars.KeycloakOperator.DeploymentName},
+		}, createdOperator)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for keycloak-operator readiness")
+	}
+	createdAddon.ReadinessJob = createdReadinessJob
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register keycloak-operator addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// keycloakOperatorTransformation returns the transformation of the vendored operator manifest, which has no
+// namespace set, into the namespace of the addon. the images of the operator, and the keycloak image it deploys,
+// are pulled from the mirror when one is configured.
+func keycloakOperatorTransformation(locals *localz.Locals, namespace string) pulumiyaml.Transformation {
+	return func(state map[string]interface{}, opts ...pulumi.ResourceOption) {
+		kind, _ := state["kind"].(string)
+		if metadata, ok := state["metadata"].(map[string]interface{}); ok &&
+			!strings.HasPrefix(kind, "Cluster") && kind != "CustomResourceDefinition" {
+			metadata["namespace"] = namespace
+		}
+		//the cluster role bindings of the manifest bind the service account of the operator without a namespace
+		if subjects, ok := state["subjects"].([]interface{}); ok {
+			for _, subject := range subjects {
+				if s, ok := subject.(map[string]interface{}); ok && s["kind"] == "ServiceAccount" {
+					s["namespace"] = namespace
+				}
+			}
+		}
+		if kind != "Deployment" {
+			return
+		}
+		spec, _ := state["spec"].(map[string]interface{})
+		template, _ := spec["template"].(map[string]interface{})
+		podSpec, _ := template["spec"].(map[string]interface{})
+		containers, _ := podSpec["containers"].([]interface{})
+		for _, container := range containers {
+			c, ok := container.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			if image, ok := c["image"].(string); ok {
+				c["image"] = mirroredImage(locals, image)
+			}
+			env, _ := c["env"].([]interface{})
+			for _, variable := range env {
+				v, ok := variable.(map[string]interface{})
+				if !ok {
+					continue
+				}
+				if name, _ := v["name"].(string); strings.HasPrefix(name, "RELATED_IMAGE_") {
+					if image, ok := v["value"].(string); ok {
+						v["value"] = mirroredImage(locals, image)
+					}
+				}
+			}
+		}
+	}
+}
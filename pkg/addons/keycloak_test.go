@@ -0,0 +1,205 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"testing"
+)
+
+const (
+	// postgresqlType is the type token of the postgres clusters of the zalando operator.
+	postgresqlType = "kubernetes:acid.zalan.do/v1:postgresql"
+	// keycloakType is the type token of the keycloak instances of the keycloak operator.
+	keycloakType = "kubernetes:k8s.keycloak.org/v2alpha1:Keycloak"
+)
+
+// testKeycloakOperator registers a keycloak-operator component with the namespace and the readiness job that the
+// keycloak instance depends on.
+func testKeycloakOperator(ctx *pulumi.Context) (*KeycloakOperatorAddon, error) {
+	installed := &KeycloakOperatorAddon{}
+	if err := ctx.RegisterComponentResource(KeycloakOperatorResourceType, keycloakOperatorAddonName,
+		installed); err != nil {
+		return nil, err
+	}
+	namespace, err := corev1.NewNamespace(ctx, vars.KeycloakOperator.Namespace, &corev1.NamespaceArgs{},
+		pulumi.Parent(installed))
+	if err != nil {
+		return nil, err
+	}
+	readinessJob, err := batchv1.NewJob(ctx, "keycloak-operator-readiness", &batchv1.JobArgs{},
+		pulumi.Parent(installed))
+	if err != nil {
+		return nil, err
+	}
+	installed.Namespace = namespace
+	installed.ReadinessJob = readinessJob
+	return installed, nil
+}
+
+func TestKeycloak(t *testing.T) {
+	tests := []struct {
+		name               string
+		keycloak           *options.Keycloak
+		wantInstances      float64
+		wantDbInstances    float64
+		wantDbVolumeSize   string
+		wantHostnameStrict bool
+	}{
+		{
+			name:             "defaults",
+			keycloak:         &options.Keycloak{},
+			wantInstances:    1,
+			wantDbInstances:  1,
+			wantDbVolumeSize: vars.Keycloak.PostgresVolumeSize,
+		},
+		{
+			name:             "instances and database volume",
+			keycloak:         &options.Keycloak{Instances: 3, DatabaseInstances: 2, DatabaseVolumeSize: "50Gi"},
+			wantInstances:    3,
+			wantDbInstances:  2,
+			wantDbVolumeSize: "50Gi",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := &testMocks{}
+			runWithMocks(t, mocks, func(ctx *pulumi.Context) error {
+				kubernetesProvider, err := testKubernetesProvider(ctx)
+				if err != nil {
+					return err
+				}
+				installed, err := testInstalledAddons(ctx, zalandoPostgresOperatorAddonName)
+				if err != nil {
+					return err
+				}
+				if installed[keycloakOperatorAddonName], err = testKeycloakOperator(ctx); err != nil {
+					return err
+				}
+				locals := &localz.Locals{Options: &options.Options{Keycloak: tt.keycloak}}
+				_, err = Keycloak(ctx, locals, kubernetesProvider, installed)
+				return err
+			})
+
+			database := mocks.resource(t, postgresqlType, vars.Keycloak.PostgresName)
+			if got := propertyAt(database.Inputs, "metadata", "namespace").StringValue(); got !=
+				vars.KeycloakOperator.Namespace {
+				t.Errorf("postgres cluster namespace = %q, want %q", got, vars.KeycloakOperator.Namespace)
+			}
+			if got := propertyAt(database.Inputs, "spec", "numberOfInstances").NumberValue(); got != tt.wantDbInstances {
+				t.Errorf("postgres cluster numberOfInstances = %v, want %v", got, tt.wantDbInstances)
+			}
+			if got := propertyAt(database.Inputs, "spec", "volume", "size").StringValue(); got != tt.wantDbVolumeSize {
+				t.Errorf("postgres cluster volume size = %q, want %q", got, tt.wantDbVolumeSize)
+			}
+			//the zalando operator creates the database owned by the user whose credentials keycloak reads
+			if got := propertyAt(database.Inputs, "spec", "databases", vars.Keycloak.DatabaseName).StringValue(); got !=
+				vars.Keycloak.DatabaseUser {
+				t.Errorf("postgres cluster database owner = %q, want %q", got, vars.Keycloak.DatabaseUser)
+			}
+
+			keycloak := mocks.resource(t, keycloakType, vars.Keycloak.Name)
+			if got := propertyAt(keycloak.Inputs, "spec", "instances").NumberValue(); got != tt.wantInstances {
+				t.Errorf("keycloak instances = %v, want %v", got, tt.wantInstances)
+			}
+			if got := propertyAt(keycloak.Inputs, "spec", "db", "host").StringValue(); got != vars.Keycloak.PostgresName {
+				t.Errorf("keycloak db host = %q, want %q", got, vars.Keycloak.PostgresName)
+			}
+			wantSecret := fmt.Sprintf("%s.%s.credentials.postgresql.acid.zalan.do", vars.Keycloak.DatabaseUser,
+				vars.Keycloak.PostgresName)
+			for _, key := range []string{"usernameSecret", "passwordSecret"} {
+				if got := propertyAt(keycloak.Inputs, "spec", "db", key, "name").StringValue(); got != wantSecret {
+					t.Errorf("keycloak db %s = %q, want %q", key, got, wantSecret)
+				}
+			}
+			//without an ingress, keycloak accepts requests on any hostname
+			if got := propertyAt(keycloak.Inputs, "spec", "hostname", "strict"); !got.IsBool() || got.BoolValue() {
+				t.Errorf("keycloak hostname strict = %v, want false", got)
+			}
+			if got := propertyAt(keycloak.Inputs, "spec", "ingress", "enabled"); !got.IsBool() || got.BoolValue() {
+				t.Errorf("keycloak ingress enabled = %v, want false", got)
+			}
+		})
+	}
+}
+
+func TestKeycloakOperatorTransformation(t *testing.T) {
+	locals := testMirrorLocals(&options.Mirror{ImageRegistry: "europe-docker.pkg.dev/my-project/images"}, nil)
+	tests := []struct {
+		name  string
+		state map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name: "namespaced resource",
+			state: map[string]interface{}{
+				"kind":     "ServiceAccount",
+				"metadata": map[string]interface{}{"name": "keycloak-operator"},
+			},
+			want: map[string]interface{}{
+				"kind":     "ServiceAccount",
+				"metadata": map[string]interface{}{"name": "keycloak-operator", "namespace": "keycloak"},
+			},
+		},
+		{
+			name: "cluster role binding",
+			state: map[string]interface{}{
+				"kind":     "ClusterRoleBinding",
+				"metadata": map[string]interface{}{"name": "keycloak-operator"},
+				"subjects": []interface{}{
+					map[string]interface{}{"kind": "ServiceAccount", "name": "keycloak-operator"},
+				},
+			},
+			want: map[string]interface{}{
+				"kind":     "ClusterRoleBinding",
+				"metadata": map[string]interface{}{"name": "keycloak-operator"},
+				"subjects": []interface{}{
+					map[string]interface{}{"kind": "ServiceAccount", "name": "keycloak-operator", "namespace": "keycloak"},
+				},
+			},
+		},
+		{
+			name: "deployment images mirrored",
+			state: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "keycloak-operator"},
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"image": "quay.io/keycloak/keycloak-operator:25.0.4",
+						"env": []interface{}{
+							map[string]interface{}{"name": "RELATED_IMAGE_KEYCLOAK", "value": "quay.io/keycloak/keycloak:25.0.4"},
+							map[string]interface{}{"name": "KUBERNETES_NAMESPACE", "value": "keycloak"},
+						},
+					}},
+				}}},
+			},
+			want: map[string]interface{}{
+				"kind":     "Deployment",
+				"metadata": map[string]interface{}{"name": "keycloak-operator", "namespace": "keycloak"},
+				"spec": map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{
+					"containers": []interface{}{map[string]interface{}{
+						"image": "europe-docker.pkg.dev/my-project/images/keycloak/keycloak-operator:25.0.4",
+						"env": []interface{}{
+							map[string]interface{}{"name": "RELATED_IMAGE_KEYCLOAK",
+								"value": "europe-docker.pkg.dev/my-project/images/keycloak/keycloak:25.0.4"},
+							map[string]interface{}{"name": "KUBERNETES_NAMESPACE", "value": "keycloak"},
+						},
+					}},
+				}}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			keycloakOperatorTransformation(locals, "keycloak")(tt.state)
+			if !reflect.DeepEqual(tt.state, tt.want) {
+				t.Errorf("keycloakOperatorTransformation() = %v, want %v", tt.state, tt.want)
+			}
+		})
+	}
+}
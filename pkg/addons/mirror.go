@@ -0,0 +1,229 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"strings"
+)
+
+// imageRegistryValues returns, keyed by chart name, the helm values that make the chart pull its images from the
+// image registry of the mirror. the images are expected under their path without the original registry.
+//
+// charts without images, ex: the istio "base" chart, are not listed.
+var imageRegistryValues = map[string]func(registry string) map[string]interface{}{
+	//quay.io/jetstack/cert-manager-*
+	vars.CertManager.HelmChartName: func(registry string) map[string]interface{} {
+		repository := func(component string) map[string]interface{} {
+			return map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/jetstack/cert-manager-%s", registry, component),
+				},
+			}
+		}
+		values := repository("controller")
+		for _, component := range []string{"webhook", "cainjector", "acmesolver", "startupapicheck"} {
+			values[component] = repository(component)
+		}
+		return values
+	},
+	//registry.k8s.io/external-dns/external-dns
+	vars.ExternalDns.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/external-dns/external-dns", registry),
+			},
+		}
+	},
+	//ghcr.io/external-secrets/external-secrets, shared by the controller, webhook and cert-controller
+	vars.ExternalSecrets.HelmChartName: func(registry string) map[string]interface{} {
+		image := func() map[string]interface{} {
+			return map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/external-secrets/external-secrets", registry),
+				},
+			}
+		}
+		values := image()
+		values["webhook"] = image()
+		values["certController"] = image()
+		return values
+	},
+	//registry.k8s.io/ingress-nginx/*
+	vars.IngressNginx.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"global": map[string]interface{}{
+				"image": map[string]interface{}{
+					"registry": registry,
+				},
+			},
+		}
+	},
+	//docker.io/istio/*
+	vars.Istio.IstiodHelmChartName:  istioImageRegistryValues,
+	vars.Istio.GatewayHelmChartName: istioImageRegistryValues,
+	//ghcr.io/zalando/postgres-operator
+	vars.ZalandoPostgresOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"registry": registry,
+			},
+		}
+	},
+	//docker.io/apache/solr-operator
+	vars.SolrOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/apache/solr-operator", registry),
+			},
+		}
+	},
+	//quay.io/strimzi/*, also used for the images of the kafka clusters
+	vars.StrimziKafkaOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"defaultImageRegistry": registry,
+		}
+	},
+	//docker.elastic.co/eck/eck-operator, the container registry is also used for the images of the elastic stack
+	vars.ElasticOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/eck/eck-operator", registry),
+			},
+			"config": map[string]interface{}{
+				"containerRegistry": registry,
+			},
+		}
+	},
+	//quay.io/prometheus/*, registry.k8s.io/kube-state-metrics/* and docker.io/grafana/*, including the subcharts
+	vars.Monitoring.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"global": map[string]interface{}{
+				"imageRegistry": registry,
+			},
+		}
+	},
+	//quay.io/argoproj/argocd and the redis image of the chart
+	vars.ArgoCd.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"global": map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/argoproj/argocd", registry),
+				},
+			},
+			"redis": map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/docker/library/redis", registry),
+				},
+			},
+		}
+	},
+	//docker.io/velero/velero and the kubectl image of the crd upgrade job, the gcp plugin image is mirrored by the addon
+	vars.Velero.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/velero/velero", registry),
+			},
+			"kubectl": map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/bitnami/kubectl", registry),
+				},
+			},
+		}
+	},
+	//ghcr.io/kyverno/* and the kubectl image of the hooks and cleanup jobs
+	vars.PolicyEngine.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"global": map[string]interface{}{
+				"image": map[string]interface{}{
+					"registry": registry,
+				},
+			},
+		}
+	},
+	//docker.io/otel/opentelemetry-collector-contrib
+	vars.OpenTelemetry.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/%s", registry, vars.OpenTelemetry.Image),
+			},
+		}
+	},
+	//ghcr.io/kedacore/*
+	vars.Keda.HelmChartName: func(registry string) map[string]interface{} {
+		values := make(map[string]interface{})
+		for _, component := range []string{"keda", "metricsApiServer", "webhooks"} {
+			values[component] = map[string]interface{}{
+				"registry": registry,
+			}
+		}
+		return map[string]interface{}{
+			"image": values,
+		}
+	},
+	//docker.io/bitnami/*
+	vars.RabbitMqOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"global": map[string]interface{}{
+				"imageRegistry": registry,
+			},
+		}
+	},
+	//docker.io/percona/percona-server-mongodb-operator
+	vars.MongoDbOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"image": map[string]interface{}{
+				"repository": fmt.Sprintf("%s/percona/percona-server-mongodb-operator", registry),
+			},
+		}
+	},
+	//quay.io/opstree/redis-operator
+	vars.RedisOperator.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"redisOperator": map[string]interface{}{
+				"imageName": fmt.Sprintf("%s/opstree/redis-operator", registry),
+			},
+		}
+	},
+	//docker.io/hashicorp/vault and docker.io/hashicorp/vault-k8s
+	vars.Vault.HelmChartName: func(registry string) map[string]interface{} {
+		return map[string]interface{}{
+			"server": map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/hashicorp/vault", registry),
+				},
+			},
+			"injector": map[string]interface{}{
+				"image": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/hashicorp/vault-k8s", registry),
+				},
+				"agentImage": map[string]interface{}{
+					"repository": fmt.Sprintf("%s/hashicorp/vault", registry),
+				},
+			},
+		}
+	},
+}
+
+func istioImageRegistryValues(registry string) map[string]interface{} {
+	return map[string]interface{}{
+		"global": map[string]interface{}{
+			"hub": fmt.Sprintf("%s/istio", registry),
+		},
+	}
+}
+
+// mirroredImage returns the image to be pulled from the image registry of the mirror, ex: "<image-registry>/kubectl:v1.30.5"
+// for "registry.k8s.io/kubectl:v1.30.5", or the image itself when no image registry is configured.
+func mirroredImage(locals *localz.Locals, image string) string {
+	mirror := mirrorSettings(locals)
+	if mirror == nil || mirror.ImageRegistry == "" {
+		return image
+	}
+	//the first part of the image is the registry when it is a host name, docker hub images have no registry
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 2 && (strings.ContainsAny(parts[0], ".:") || parts[0] == "localhost") {
+		image = parts[1]
+	}
+	return fmt.Sprintf("%s/%s", strings.TrimSuffix(mirror.ImageRegistry, "/"), image)
+}
@@ -0,0 +1,114 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"reflect"
+	"testing"
+)
+
+// testMirrorLocals returns locals with the mirror and the addon settings, keyed by the addon name.
+func testMirrorLocals(mirror *options.Mirror, addons map[string]*options.AddonSettings) *localz.Locals {
+	return &localz.Locals{Options: &options.Options{Mirror: mirror, Addons: addons}}
+}
+
+func TestMirroredImage(t *testing.T) {
+	mirror := &options.Mirror{ImageRegistry: "europe-docker.pkg.dev/my-project/images/"}
+	tests := []struct {
+		name   string
+		locals *localz.Locals
+		image  string
+		want   string
+	}{
+		{
+			name:   "no options",
+			locals: &localz.Locals{},
+			image:  "registry.k8s.io/kubectl:v1.30.5",
+			want:   "registry.k8s.io/kubectl:v1.30.5",
+		},
+		{
+			name:   "no image registry",
+			locals: testMirrorLocals(&options.Mirror{ChartRepo: "https://charts.example.com"}, nil),
+			image:  "registry.k8s.io/kubectl:v1.30.5",
+			want:   "registry.k8s.io/kubectl:v1.30.5",
+		},
+		{
+			name:   "registry replaced",
+			locals: testMirrorLocals(mirror, nil),
+			image:  "registry.k8s.io/kubectl:v1.30.5",
+			want:   "europe-docker.pkg.dev/my-project/images/kubectl:v1.30.5",
+		},
+		{
+			name:   "registry with port replaced",
+			locals: testMirrorLocals(mirror, nil),
+			image:  "localhost:5000/jetstack/cert-manager-controller:v1.15.3",
+			want:   "europe-docker.pkg.dev/my-project/images/jetstack/cert-manager-controller:v1.15.3",
+		},
+		{
+			name:   "localhost registry replaced",
+			locals: testMirrorLocals(mirror, nil),
+			image:  "localhost/kubectl:v1.30.5",
+			want:   "europe-docker.pkg.dev/my-project/images/kubectl:v1.30.5",
+		},
+		{
+			name:   "docker hub image without registry",
+			locals: testMirrorLocals(mirror, nil),
+			image:  "alpine/k8s:1.30.5",
+			want:   "europe-docker.pkg.dev/my-project/images/alpine/k8s:1.30.5",
+		},
+		{
+			name:   "docker hub official image",
+			locals: testMirrorLocals(mirror, nil),
+			image:  "busybox:1.36",
+			want:   "europe-docker.pkg.dev/my-project/images/busybox:1.36",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mirroredImage(tt.locals, tt.image); got != tt.want {
+				t.Errorf("mirroredImage() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHelmChartSource(t *testing.T) {
+	const defaultRepo = "https://istio-release.storage.googleapis.com/charts"
+	tests := []struct {
+		name   string
+		locals *localz.Locals
+		want   resolvedChartSource
+	}{
+		{
+			name:   "default repository",
+			locals: &localz.Locals{},
+			want:   resolvedChartSource{repo: defaultRepo},
+		},
+		{
+			name: "helm repository mirror",
+			locals: testMirrorLocals(&options.Mirror{ChartRepo: "https://charts.example.com/", Username: "user",
+				Password: "password"}, nil),
+			want: resolvedChartSource{repo: "https://charts.example.com", username: "user", password: "password"},
+		},
+		{
+			name:   "oci mirror",
+			locals: testMirrorLocals(&options.Mirror{ChartRepo: "oci://europe-docker.pkg.dev/my-project/charts"}, nil),
+			want:   resolvedChartSource{repo: "oci://europe-docker.pkg.dev/my-project/charts", oci: true},
+		},
+		{
+			name: "addon repository over mirror",
+			locals: testMirrorLocals(&options.Mirror{ChartRepo: "https://charts.example.com", Username: "user",
+				Password: "password"}, map[string]*options.AddonSettings{
+				istioAddonName: {ChartRepo: "oci://registry.example.com/istio/"},
+			}),
+			want: resolvedChartSource{repo: "oci://registry.example.com/istio", oci: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := helmChartSource(tt.locals, istioAddonName, defaultRepo); !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("helmChartSource() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,126 @@
+package addons
+
+import (
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"sort"
+	"sync"
+	"testing"
+)
+
+const (
+	// helmReleaseType is the type token of helm releases.
+	helmReleaseType = "kubernetes:helm.sh/v3:Release"
+	// stackReferenceType is the type token of stack references.
+	stackReferenceType = "pulumi:pulumi:StackReference"
+)
+
+// testMocks records the resources that a program registers when it is run with pulumi mocks, see runWithMocks.
+type testMocks struct {
+	mu        sync.Mutex
+	resources []pulumi.MockResourceArgs
+	// stackOutputs are the outputs of the stacks referenced by the program, ex: the addons of the previous update.
+	stackOutputs resource.PropertyMap
+}
+
+func (m *testMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.resources = append(m.resources, args)
+
+	state := args.Inputs
+	if args.TypeToken == stackReferenceType {
+		state = resource.PropertyMap{
+			"name":    resource.NewStringProperty(args.ID),
+			"outputs": resource.NewObjectProperty(m.stackOutputs),
+		}
+	}
+	id := args.ID
+	if id == "" {
+		id = args.Name + "-id"
+	}
+	return id, state, nil
+}
+
+func (m *testMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return resource.PropertyMap{}, nil
+}
+
+// resource returns the resource of the type registered with the name and fails the test when there is none.
+func (m *testMocks) resource(t *testing.T, typeToken, name string) pulumi.MockResourceArgs {
+	t.Helper()
+	for _, r := range m.resources {
+		if r.TypeToken == typeToken && r.Name == name {
+			return r
+		}
+	}
+	t.Fatalf("no %s resource named %s was registered", typeToken, name)
+	return pulumi.MockResourceArgs{}
+}
+
+// names returns the sorted names of the registered resources of the type.
+func (m *testMocks) names(typeToken string) []string {
+	names := make([]string, 0)
+	for _, r := range m.resources {
+		if r.TypeToken == typeToken {
+			names = append(names, r.Name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// runWithMocks runs the program with the mocks and fails the test when the program returns an error.
+func runWithMocks(t *testing.T, mocks *testMocks, program func(ctx *pulumi.Context) error) {
+	t.Helper()
+	if err := pulumi.RunErr(program, pulumi.WithMocks("project", "stack", mocks)); err != nil {
+		t.Fatalf("program error = %v", err)
+	}
+}
+
+// testKubernetesProvider returns a kubernetes provider for the programs run with mocks.
+func testKubernetesProvider(ctx *pulumi.Context) (*pulumikubernetes.Provider, error) {
+	return pulumikubernetes.NewProvider(ctx, "kubernetes", &pulumikubernetes.ProviderArgs{})
+}
+
+// testComponent stands in for the component of an addon that the resources of another addon depend on.
+type testComponent struct {
+	pulumi.ResourceState
+}
+
+// testInstalledAddons registers a component for each of the addons, keyed by the addon name.
+func testInstalledAddons(ctx *pulumi.Context, names ...string) (map[string]pulumi.Resource, error) {
+	installed := make(map[string]pulumi.Resource)
+	for _, name := range names {
+		component := &testComponent{}
+		if err := ctx.RegisterComponentResource("test:addons:Addon", name, component); err != nil {
+			return nil, err
+		}
+		installed[name] = component
+	}
+	return installed, nil
+}
+
+// propertyAt returns the value at the path of keys in the resource inputs, or a null value when the path is not set.
+// secrets are unwrapped along the way.
+func propertyAt(inputs resource.PropertyMap, keys ...string) resource.PropertyValue {
+	value := resource.NewObjectProperty(inputs)
+	for _, key := range keys {
+		if value.IsSecret() {
+			value = value.SecretValue().Element
+		}
+		if !value.IsObject() {
+			return resource.NewNullProperty()
+		}
+		next, ok := value.ObjectValue()[resource.PropertyKey(key)]
+		if !ok {
+			return resource.NewNullProperty()
+		}
+		value = next
+	}
+	if value.IsSecret() {
+		return value.SecretValue().Element
+	}
+	return value
+}
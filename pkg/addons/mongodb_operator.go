@@ -0,0 +1,128 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	mongoDbOperatorAddonName = "mongodb-operator"
+	// MongoDbOperatorResourceType is the pulumi type token of the mongodb-operator addon component.
+	MongoDbOperatorResourceType = "planton:gke:MongoDbOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: mongoDbOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.DataOperators.IsInstallMongoDbOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.MongoDbOperator.HelmChartName},
+		Removal: &Removal{
+			//the backups and restores belong to a mongodb cluster
+			Crds: []string{
+				"perconaservermongodbbackups.psmdb.percona.com",
+				"perconaservermongodbrestores.psmdb.percona.com",
+				"perconaservermongodbs.psmdb.percona.com",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return MongoDbOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// MongoDbOperatorAddon is the component of the mongodb-operator addon.
+type MongoDbOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
+// MongoDbOperator installs the Percona Operator for MongoDB in the Kubernetes cluster using Helm.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *MongoDbOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Percona Operator for MongoDB and labels it with metadata from locals.
+// 3. Deploys the Percona Operator for MongoDB Helm chart into the created namespace, watching all namespaces.
+// 4. Uses Helm chart repository and version specified in the vars package.
+//
+// the operator has no setting to inherit the labels of a PerconaServerMongoDB, the labels are set on the child
+// resources in the resource itself, ex: with "spec.replsets[].labels" for the pods of a replica set.
+func MongoDbOperator(ctx *pulumi.Context, locals *localz.Locals,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*MongoDbOperatorAddon, error) {
+	createdAddon := &MongoDbOperatorAddon{}
+	err := ctx.RegisterComponentResource(MongoDbOperatorResourceType, mongoDbOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register mongodb-operator addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.MongoDbOperator.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.MongoDbOperator.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.MongoDbOperator.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create mongodb-operator namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, mongoDbOperatorAddonName)
+	chartSource := helmChartSource(locals, mongoDbOperatorAddonName, vars.MongoDbOperator.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "psmdb-operator",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.MongoDbOperator.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.MongoDbOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, mongoDbOperatorAddonName, vars.MongoDbOperator.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, mongoDbOperatorAddonName, vars.MongoDbOperator.HelmChartName, pulumi.Map{
+				"watchAllNamespaces": pulumi.Bool(true),
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create mongodb-operator helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register mongodb-operator addon outputs")
+	}
+	return createdAddon, nil
+}
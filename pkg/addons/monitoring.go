@@ -0,0 +1,312 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	monitoringAddonName = "monitoring"
+	// MonitoringResourceType is the pulumi type token of the monitoring addon component.
+	MonitoringResourceType = "planton:gke:MonitoringAddon"
+)
+
+// scrapeTarget describes the metrics endpoint of the pods of an addon.
+type scrapeTarget struct {
+	// name of the pod monitor, ex: "istiod".
+	name      string
+	addonName string
+	namespace string
+	// matchLabels select the pods of the addon that serve the metrics.
+	matchLabels map[string]string
+	// port is the name of the container port serving the metrics.
+	port string
+	path string
+}
+
+// scrapeTargets are the metrics endpoints of the addons installed by the module. a pod monitor is created for each
+// target of an installed addon.
+var scrapeTargets = []*scrapeTarget{
+	{
+		name:        "istiod",
+		addonName:   istioAddonName,
+		namespace:   vars.Istio.SystemNamespace,
+		matchLabels: map[string]string{"app": "istiod"},
+		port:        "http-monitoring",
+		path:        "/metrics",
+	},
+	{
+		name:      "cert-manager",
+		addonName: certManagerAddonName,
+		namespace: vars.CertManager.Namespace,
+		matchLabels: map[string]string{
+			"app.kubernetes.io/name":      "cert-manager",
+			"app.kubernetes.io/component": "controller",
+		},
+		port: "http-metrics",
+		path: "/metrics",
+	},
+	{
+		//serves the metrics on the METRICS_PORT of the addon
+		name:        "external-secrets",
+		addonName:   externalSecretsAddonName,
+		namespace:   vars.ExternalSecrets.Namespace,
+		matchLabels: map[string]string{"app.kubernetes.io/name": "external-secrets"},
+		port:        "metrics",
+		path:        "/metrics",
+	},
+	{
+		//selects the pods of the releases of all ingress domains
+		name:        "external-dns",
+		addonName:   externalDnsAddonName,
+		namespace:   vars.ExternalDns.Namespace,
+		matchLabels: map[string]string{"app.kubernetes.io/name": "external-dns"},
+		port:        "http",
+		path:        "/metrics",
+	},
+	{
+		name:      "ingress-nginx",
+		addonName: ingressNginxAddonName,
+		namespace: vars.IngressNginx.Namespace,
+		matchLabels: map[string]string{
+			"app.kubernetes.io/name":      "ingress-nginx",
+			"app.kubernetes.io/component": "controller",
+		},
+		port: "metrics",
+		path: "/metrics",
+	},
+	{
+		name:        "strimzi-cluster-operator",
+		addonName:   strimziKafkaOperatorAddonName,
+		namespace:   vars.StrimziKafkaOperator.Namespace,
+		matchLabels: map[string]string{"name": "strimzi-cluster-operator"},
+		port:        "http",
+		path:        "/metrics",
+	},
+}
+
+func init() {
+	//the pod monitors are created in the namespaces of the addons that are scraped
+	after := make([]string, 0)
+	for _, target := range scrapeTargets {
+		if !contains(after, target.addonName) {
+			after = append(after, target.addonName)
+		}
+	}
+	register(&Addon{
+		Name: monitoringAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Monitoring != nil
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		After:      after,
+		HelmCharts: []string{vars.Monitoring.HelmChartName},
+		ProtectedValues: []string{
+			"prometheus.prometheusSpec.podMonitorSelectorNilUsesHelmValues",
+			"prometheus.prometheusSpec.scrapeInterval",
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Monitoring(ctx, input.Locals, input.KubernetesProvider, input.Installed, input.opts...)
+		},
+	})
+}
+
+// MonitoringAddon is the component of the monitoring addon.
+type MonitoringAddon struct {
+	pulumi.ResourceState
+
+	// Namespace and HelmRelease are only created when kube-prometheus-stack is installed in the cluster.
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+	// PodMonitors are keyed by the name of the scrape target, ex: "istiod". they are PodMonitoring resources of
+	//managed prometheus or PodMonitor resources of the prometheus operator, depending on the mode.
+	PodMonitors map[string]*apiextensions.CustomResource
+}
+
+// Monitoring collects the metrics of the addons installed by the module, either with Google Cloud Managed Service for
+// Prometheus, which is enabled on the cluster, or with kube-prometheus-stack installed in the cluster.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - installedAddons: The components of the installed addons keyed by the addon name, whose metrics are scraped.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *MonitoringAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. For managed prometheus, waits for the PodMonitoring crd which is installed by GKE.
+//  3. For kube-prometheus-stack, creates the monitoring namespace, deploys the helm chart and waits for the
+//     prometheus operator to be ready.
+//  4. Creates a pod monitor in the namespace of each installed addon with metrics, once the addon is installed.
+func Monitoring(ctx *pulumi.Context, locals *localz.Locals, kubernetesProvider *pulumikubernetes.Provider,
+	installedAddons map[string]pulumi.Resource, opts ...pulumi.ResourceOption) (*MonitoringAddon, error) {
+	createdAddon := &MonitoringAddon{PodMonitors: make(map[string]*apiextensions.CustomResource)}
+	err := ctx.RegisterComponentResource(MonitoringResourceType, monitoringAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register monitoring addon component")
+	}
+
+	interval := scrapeInterval(locals)
+
+	//the crd and the endpoints of the pod monitors differ between managed prometheus and the prometheus operator
+	podMonitorApiVersion := "monitoring.coreos.com/v1"
+	podMonitorKind := "PodMonitor"
+	podMonitorEndpointsKey := "podMetricsEndpoints"
+	var createdReadinessJob pulumi.Resource
+
+	if locals.Options.Monitoring.IsManagedPrometheus() {
+		podMonitorApiVersion = "monitoring.googleapis.com/v1"
+		podMonitorKind = "PodMonitoring"
+		podMonitorEndpointsKey = "endpoints"
+
+		//the crds of managed prometheus are installed by gke once it is enabled on the cluster
+		createdReadinessJob, err = waitForReadiness(ctx, locals,
+			&readinessGate{
+				addonName: monitoringAddonName,
+				namespace: vars.Monitoring.ManagedPrometheusReadinessNamespace,
+				crds:      []string{"podmonitorings.monitoring.googleapis.com"},
+			}, createdAddon)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to wait for managed prometheus")
+		}
+	} else {
+		//create namespace resource
+		createdNamespace, err := corev1.NewNamespace(ctx,
+			vars.Monitoring.Namespace,
+			&corev1.NamespaceArgs{
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:   pulumi.String(vars.Monitoring.Namespace),
+						Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Monitoring.Namespace)),
+					}),
+			}, pulumi.Parent(createdAddon))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create monitoring namespace")
+		}
+		createdAddon.Namespace = createdNamespace
+
+		releaseSettings := helmReleaseSettings(locals, monitoringAddonName)
+		chartSource := helmChartSource(locals, monitoringAddonName, vars.Monitoring.HelmChartRepo)
+
+		//create helm-release
+		createdHelmRelease, err := helm.NewRelease(ctx, "kube-prometheus-stack",
+			&helm.ReleaseArgs{
+				Name:            pulumi.String(vars.Monitoring.HelmChartName),
+				Namespace:       createdNamespace.Metadata.Name(),
+				Chart:           chartSource.chart(vars.Monitoring.HelmChartName),
+				Version:         pulumi.String(chartVersion(locals, monitoringAddonName, vars.Monitoring.HelmChartVersion)),
+				CreateNamespace: pulumi.Bool(false),
+				Atomic:          pulumi.Bool(releaseSettings.atomic),
+				CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+				WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+				SkipAwait:       releaseSettings.skipAwait(),
+				Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+				Values: helmValues(locals, monitoringAddonName, vars.Monitoring.HelmChartName, pulumi.Map{
+					"prometheus": pulumi.Map{
+						"prometheusSpec": pulumi.Map{
+							//pod monitors in all namespaces are selected, not only the ones labeled for the release
+							"podMonitorSelectorNilUsesHelmValues":     pulumi.Bool(false),
+							"serviceMonitorSelectorNilUsesHelmValues": pulumi.Bool(false),
+							"scrapeInterval":                          pulumi.String(interval),
+						},
+					},
+					//the control plane of gke is not reachable from the cluster
+					"kubeControllerManager": pulumi.Map{
+						"enabled": pulumi.Bool(false),
+					},
+					"kubeScheduler": pulumi.Map{
+						"enabled": pulumi.Bool(false),
+					},
+					"kubeEtcd": pulumi.Map{
+						"enabled": pulumi.Bool(false),
+					},
+				}),
+				RepositoryOpts: chartSource.repositoryOpts(),
+			}, pulumi.Parent(createdNamespace),
+			pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+			releaseSettings.customTimeouts())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create helm release")
+		}
+		createdAddon.HelmRelease = createdHelmRelease
+
+		//wait for the prometheus operator as the pod monitors are validated against its crds
+		createdReadinessJob, err = waitForReadiness(ctx, locals,
+			&readinessGate{
+				addonName:   monitoringAddonName,
+				namespace:   vars.Monitoring.Namespace,
+				crds:        []string{"podmonitors.monitoring.coreos.com"},
+				deployments: []string{fmt.Sprintf("%s-operator", vars.Monitoring.HelmChartName)},
+			}, createdHelmRelease)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to wait for kube-prometheus-stack")
+		}
+	}
+
+	for _, target := range scrapeTargets {
+		installedAddon, ok := installedAddons[target.addonName]
+		if !ok {
+			continue
+		}
+		createdPodMonitor, err := apiextensions.NewCustomResource(ctx,
+			fmt.Sprintf("%s-metrics", target.name),
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String(podMonitorApiVersion),
+				Kind:       pulumi.String(podMonitorKind),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String(target.name),
+						Namespace: pulumi.String(target.namespace),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": pulumi.Map{
+						"selector": pulumi.Map{
+							"matchLabels": pulumi.ToStringMap(target.matchLabels),
+						},
+						podMonitorEndpointsKey: pulumi.Array{
+							pulumi.Map{
+								"port":     pulumi.String(target.port),
+								"path":     pulumi.String(target.path),
+								"interval": pulumi.String(interval),
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(createdAddon),
+			pulumi.DependsOn([]pulumi.Resource{createdReadinessJob, installedAddon}))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create pod monitor for %s", target.name)
+		}
+		createdAddon.PodMonitors[target.name] = createdPodMonitor
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"mode": pulumi.String(locals.Options.Monitoring.Mode),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register monitoring addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// scrapeInterval returns the scrape interval configured in the monitoring options or the default interval.
+func scrapeInterval(locals *localz.Locals) string {
+	if locals.Options.Monitoring != nil && locals.Options.Monitoring.ScrapeInterval != "" {
+		return locals.Options.Monitoring.ScrapeInterval
+	}
+	return vars.Monitoring.ScrapeInterval
+}
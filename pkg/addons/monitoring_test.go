@@ -0,0 +1,113 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"testing"
+)
+
+func TestMonitoring(t *testing.T) {
+	tests := []struct {
+		name               string
+		monitoring         *options.Monitoring
+		wantPodMonitorType string
+		wantEndpointsKey   string
+		wantHelmReleases   []string
+		wantInterval       string
+	}{
+		{
+			name:               "managed prometheus",
+			monitoring:         &options.Monitoring{Mode: options.MonitoringModeManagedPrometheus},
+			wantPodMonitorType: "kubernetes:monitoring.googleapis.com/v1:PodMonitoring",
+			wantEndpointsKey:   "endpoints",
+			wantHelmReleases:   []string{},
+			wantInterval:       vars.Monitoring.ScrapeInterval,
+		},
+		{
+			name:               "kube-prometheus-stack",
+			monitoring:         &options.Monitoring{Mode: options.MonitoringModeKubePrometheusStack, ScrapeInterval: "1m"},
+			wantPodMonitorType: "kubernetes:monitoring.coreos.com/v1:PodMonitor",
+			wantEndpointsKey:   "podMetricsEndpoints",
+			wantHelmReleases:   []string{"kube-prometheus-stack"},
+			wantInterval:       "1m",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := &testMocks{}
+			runWithMocks(t, mocks, func(ctx *pulumi.Context) error {
+				kubernetesProvider, err := testKubernetesProvider(ctx)
+				if err != nil {
+					return err
+				}
+				installed, err := testInstalledAddons(ctx, istioAddonName, externalSecretsAddonName)
+				if err != nil {
+					return err
+				}
+				locals := &localz.Locals{Options: &options.Options{Monitoring: tt.monitoring}}
+				_, err = Monitoring(ctx, locals, kubernetesProvider, installed)
+				return err
+			})
+
+			if got := mocks.names(helmReleaseType); !reflect.DeepEqual(got, tt.wantHelmReleases) {
+				t.Errorf("Monitoring() helm releases = %v, want %v", got, tt.wantHelmReleases)
+			}
+			if len(tt.wantHelmReleases) > 0 {
+				release := mocks.resource(t, helmReleaseType, "kube-prometheus-stack")
+				prometheusSpec := []string{"values", "prometheus", "prometheusSpec"}
+				if got := propertyAt(release.Inputs, append(prometheusSpec, "scrapeInterval")...); got.StringValue() !=
+					tt.wantInterval {
+					t.Errorf("kube-prometheus-stack scrapeInterval = %v, want %s", got, tt.wantInterval)
+				}
+				if got := propertyAt(release.Inputs, append(prometheusSpec, "podMonitorSelectorNilUsesHelmValues")...); !got.IsBool() || got.BoolValue() {
+					t.Errorf("kube-prometheus-stack podMonitorSelectorNilUsesHelmValues = %v, want false", got)
+				}
+			}
+
+			//pod monitors are only created for the installed addons
+			wantPodMonitors := []string{"external-secrets-metrics", "istiod-metrics"}
+			if got := mocks.names(tt.wantPodMonitorType); !reflect.DeepEqual(got, wantPodMonitors) {
+				t.Fatalf("Monitoring() pod monitors = %v, want %v", got, wantPodMonitors)
+			}
+			for _, target := range scrapeTargets {
+				if target.addonName != istioAddonName && target.addonName != externalSecretsAddonName {
+					continue
+				}
+				podMonitor := mocks.resource(t, tt.wantPodMonitorType, target.name+"-metrics")
+				if got := propertyAt(podMonitor.Inputs, "metadata", "namespace"); got.StringValue() != target.namespace {
+					t.Errorf("%s pod monitor namespace = %v, want %s", target.name, got, target.namespace)
+				}
+				matchLabels := propertyAt(podMonitor.Inputs, "spec", "selector", "matchLabels")
+				if got := matchLabels.Mappable(); !reflect.DeepEqual(got, toMappable(target.matchLabels)) {
+					t.Errorf("%s pod monitor matchLabels = %v, want %v", target.name, got, target.matchLabels)
+				}
+				endpoints := propertyAt(podMonitor.Inputs, "spec", tt.wantEndpointsKey)
+				if !endpoints.IsArray() || len(endpoints.ArrayValue()) != 1 {
+					t.Fatalf("%s pod monitor %s = %v, want one endpoint", target.name, tt.wantEndpointsKey, endpoints)
+				}
+				endpoint := endpoints.ArrayValue()[0].ObjectValue()
+				want := resource.PropertyMap{
+					"port":     resource.NewStringProperty(target.port),
+					"path":     resource.NewStringProperty(target.path),
+					"interval": resource.NewStringProperty(tt.wantInterval),
+				}
+				if !reflect.DeepEqual(endpoint, want) {
+					t.Errorf("%s pod monitor endpoint = %v, want %v", target.name, endpoint, want)
+				}
+			}
+		})
+	}
+}
+
+// toMappable returns the labels as the mappable value of a property map.
+func toMappable(labels map[string]string) map[string]interface{} {
+	mappable := make(map[string]interface{})
+	for k, v := range labels {
+		mappable[k] = v
+	}
+	return mappable
+}
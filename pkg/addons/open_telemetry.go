@@ -0,0 +1,232 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	openTelemetryAddonName = "open-telemetry"
+	// OpenTelemetryResourceType is the pulumi type token of the open-telemetry addon component.
+	OpenTelemetryResourceType = "planton:gke:OpenTelemetryAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: openTelemetryAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.OpenTelemetry != nil
+		},
+		Requires:        []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		HelmCharts:      []string{vars.OpenTelemetry.HelmChartName},
+		ProtectedValues: []string{"fullnameOverride", "serviceAccount.create", "serviceAccount.name"},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return OpenTelemetry(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// OpenTelemetryAddon is the component of the open-telemetry addon.
+type OpenTelemetryAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	HelmRelease      *helm.Release
+}
+
+// OpenTelemetry installs the OpenTelemetry Collector in the Kubernetes cluster using Helm, which exports the traces
+// sent to it to Cloud Trace and the metrics to Cloud Monitoring.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where the collector will be installed.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *OpenTelemetryAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates a namespace for the collector and labels it with metadata from locals.
+//  3. Creates the workload identity for the collector, i.e. a Google Service Account (GSA) with the cloud trace
+//     agent and monitoring metric writer roles, the Workload Identity binding and a Kubernetes Service Account (KSA)
+//     annotated with the GSA email.
+//  4. Exports the email of the created GSA.
+//  5. Deploys the OpenTelemetry Collector Helm chart into the created namespace, as a gateway deployment or as a
+//     daemonset, with the otlp receiver and the googlecloud exporter.
+//
+// istio is configured to send the spans of the mesh to the collector by the istio addon.
+func OpenTelemetry(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*OpenTelemetryAddon, error) {
+	createdAddon := &OpenTelemetryAddon{}
+	err := ctx.RegisterComponentResource(OpenTelemetryResourceType, openTelemetryAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register open-telemetry addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.OpenTelemetry.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.OpenTelemetry.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.OpenTelemetry.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create open-telemetry namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	//create the google service account and the kubernetes service account to be used by the collector
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
+		vars.OpenTelemetry.KsaName,
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.OpenTelemetry.KsaName),
+			GsaAccountId:   locals.GsaAccountId("otel-collector"),
+			GsaDescription: "opentelemetry collector service account for exporting traces and metrics",
+			ProjectRoles: []*workloadidentity.ProjectRole{
+				{Role: "roles/cloudtrace.agent"},
+				{Role: "roles/monitoring.metricWriter"},
+			},
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for open-telemetry collector")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export open-telemetry collector gsa email
+	ctx.Export(outputs.OpenTelemetryGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	releaseSettings := helmReleaseSettings(locals, openTelemetryAddonName)
+	chartSource := helmChartSource(locals, openTelemetryAddonName, vars.OpenTelemetry.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "opentelemetry-collector",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.OpenTelemetry.FullName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.OpenTelemetry.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, openTelemetryAddonName, vars.OpenTelemetry.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, openTelemetryAddonName, vars.OpenTelemetry.HelmChartName,
+				openTelemetryValues(locals)),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.DependsOn([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create open-telemetry collector helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+		"gsaEmail":  createdWorkloadIdentity.GsaEmail,
+		"endpoint":  pulumi.String(openTelemetryCollectorEndpoint()),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register open-telemetry addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// openTelemetryValues returns the helm values of the collector, which exports the traces and metrics received over
+// otlp to cloud trace and cloud monitoring, in the mode of the settings.
+func openTelemetryValues(locals *localz.Locals) pulumi.Map {
+	//the project of the exported telemetry is detected from the metadata server of the node
+	pipeline := func() pulumi.Map {
+		return pulumi.Map{
+			"receivers":  pulumi.ToStringArray([]string{"otlp"}),
+			"processors": pulumi.ToStringArray([]string{"memory_limiter", "resourcedetection", "batch"}),
+			"exporters":  pulumi.ToStringArray([]string{"googlecloud"}),
+		}
+	}
+	values := pulumi.Map{
+		"fullnameOverride": pulumi.String(vars.OpenTelemetry.FullName),
+		"mode":             pulumi.String(openTelemetryMode(locals)),
+		"image": pulumi.Map{
+			"repository": pulumi.String(vars.OpenTelemetry.Image),
+		},
+		"serviceAccount": pulumi.Map{
+			"create": pulumi.Bool(false),
+			"name":   pulumi.String(vars.OpenTelemetry.KsaName),
+		},
+		"config": pulumi.Map{
+			"processors": pulumi.Map{
+				"resourcedetection": pulumi.Map{
+					"detectors": pulumi.ToStringArray([]string{"env", "gcp"}),
+					"timeout":   pulumi.String("10s"),
+				},
+			},
+			"exporters": pulumi.Map{
+				"googlecloud": pulumi.Map{},
+			},
+			"service": pulumi.Map{
+				"pipelines": pulumi.Map{
+					"traces":  pipeline(),
+					"metrics": pipeline(),
+				},
+			},
+		},
+	}
+	if locals.Options.OpenTelemetry.IsDaemonSet() {
+		//the service of a daemonset routes to the collector on the node of the sender
+		values["service"] = pulumi.Map{
+			"enabled":               pulumi.Bool(true),
+			"internalTrafficPolicy": pulumi.String("Local"),
+		}
+	} else {
+		values["replicaCount"] = pulumi.Int(vars.OpenTelemetry.GatewayReplicas)
+	}
+	return values
+}
+
+// openTelemetryMode returns the mode of the collector as expected by the helm chart.
+func openTelemetryMode(locals *localz.Locals) string {
+	if locals.Options.OpenTelemetry.IsDaemonSet() {
+		return "daemonset"
+	}
+	return "deployment"
+}
+
+// openTelemetryCollectorHost returns the cluster local host name of the service of the collector.
+func openTelemetryCollectorHost() string {
+	return fmt.Sprintf("%s.%s.svc.cluster.local", vars.OpenTelemetry.FullName, vars.OpenTelemetry.Namespace)
+}
+
+// openTelemetryCollectorEndpoint returns the otlp grpc endpoint of the collector, ex: for OTEL_EXPORTER_OTLP_ENDPOINT.
+func openTelemetryCollectorEndpoint() string {
+	return fmt.Sprintf("http://%s:%d", openTelemetryCollectorHost(), vars.OpenTelemetry.OtlpGrpcPort)
+}
@@ -0,0 +1,128 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"testing"
+)
+
+// testOpenTelemetryLocals returns locals with the open-telemetry settings.
+func testOpenTelemetryLocals(openTelemetry *options.OpenTelemetry) *localz.Locals {
+	return &localz.Locals{Options: &options.Options{OpenTelemetry: openTelemetry}}
+}
+
+func TestOpenTelemetryValues(t *testing.T) {
+	tests := []struct {
+		name          string
+		openTelemetry *options.OpenTelemetry
+		want          map[string]pulumi.Input
+		wantUnset     []string
+	}{
+		{
+			name:          "gateway deployment",
+			openTelemetry: &options.OpenTelemetry{},
+			want: map[string]pulumi.Input{
+				"mode":                pulumi.String("deployment"),
+				"replicaCount":        pulumi.Int(vars.OpenTelemetry.GatewayReplicas),
+				"serviceAccount.name": pulumi.String(vars.OpenTelemetry.KsaName),
+			},
+			wantUnset: []string{"service"},
+		},
+		{
+			//the service routes to the collector on the node of the sender
+			name:          "daemonset",
+			openTelemetry: &options.OpenTelemetry{Mode: options.OpenTelemetryModeDaemonSet},
+			want: map[string]pulumi.Input{
+				"mode":                          pulumi.String("daemonset"),
+				"service.enabled":               pulumi.Bool(true),
+				"service.internalTrafficPolicy": pulumi.String("Local"),
+			},
+			wantUnset: []string{"replicaCount"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := openTelemetryValues(testOpenTelemetryLocals(tt.openTelemetry))
+			for path, want := range tt.want {
+				if got := valueAt(values, path); got != want {
+					t.Errorf("openTelemetryValues() %s = %v, want %v", path, got, want)
+				}
+			}
+			for _, path := range tt.wantUnset {
+				if got := valueAt(values, path); got != nil {
+					t.Errorf("openTelemetryValues() %s = %v, want unset", path, got)
+				}
+			}
+			//traces and metrics are both exported to google cloud
+			for _, pipeline := range []string{"traces", "metrics"} {
+				exporters := valueAt(values, "config.service.pipelines."+pipeline+".exporters")
+				if !reflect.DeepEqual(exporters, pulumi.ToStringArray([]string{"googlecloud"})) {
+					t.Errorf("openTelemetryValues() %s exporters = %v, want googlecloud", pipeline, exporters)
+				}
+			}
+		})
+	}
+}
+
+func TestIstiodValuesTracing(t *testing.T) {
+	tests := []struct {
+		name          string
+		openTelemetry *options.OpenTelemetry
+		wantProviders pulumi.Input
+	}{
+		{
+			name: "without open-telemetry",
+		},
+		{
+			name:          "collector as the default tracing provider",
+			openTelemetry: &options.OpenTelemetry{},
+			wantProviders: pulumi.Array{
+				pulumi.Map{
+					"name": pulumi.String(vars.OpenTelemetry.IstioProviderName),
+					"opentelemetry": pulumi.Map{
+						"service": pulumi.String("opentelemetry-collector.opentelemetry.svc.cluster.local"),
+						"port":    pulumi.Int(vars.OpenTelemetry.OtlpGrpcPort),
+					},
+				},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values := istiodValues(testOpenTelemetryLocals(tt.openTelemetry))
+			if got := valueAt(values, "meshConfig.extensionProviders"); !reflect.DeepEqual(got, tt.wantProviders) {
+				t.Errorf("istiodValues() extensionProviders = %v, want %v", got, tt.wantProviders)
+			}
+		})
+	}
+}
+
+func TestTracingSamplingPercentage(t *testing.T) {
+	percentage := 25.0
+	tests := []struct {
+		name          string
+		openTelemetry *options.OpenTelemetry
+		want          float64
+	}{
+		{
+			name:          "default",
+			openTelemetry: &options.OpenTelemetry{},
+			want:          vars.OpenTelemetry.DefaultTracingSamplingPercentage,
+		},
+		{
+			name:          "configured",
+			openTelemetry: &options.OpenTelemetry{TracingSamplingPercentage: &percentage},
+			want:          25,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tracingSamplingPercentage(testOpenTelemetryLocals(tt.openTelemetry)); got != tt.want {
+				t.Errorf("tracingSamplingPercentage() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
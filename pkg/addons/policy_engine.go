@@ -0,0 +1,269 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	pulumiyaml "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/yaml"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"sort"
+)
+
+const (
+	policyEngineAddonName = "policy-engine"
+	// PolicyEngineResourceType is the pulumi type token of the policy-engine addon component.
+	PolicyEngineResourceType = "planton:gke:PolicyEngineAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: policyEngineAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.PolicyEngine != nil
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.PolicyEngine.HelmChartName},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return PolicyEngine(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// PolicyEngineAddon is the component of the policy-engine addon.
+type PolicyEngineAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+	// BaselinePolicies are the kyverno cluster-policies of the baseline policy set.
+	BaselinePolicies *pulumiyaml.ConfigGroup
+}
+
+// PolicyEngine installs Kyverno in the Kubernetes cluster using Helm and creates the policies of the baseline policy
+// set shipped with the module, each in audit or enforce mode.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *PolicyEngineAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates a namespace for Kyverno and labels it with metadata from locals.
+//  3. Deploys the Kyverno Helm chart into the created namespace with a highly available admission controller.
+//  4. Waits for the CRDs to be established and the admission and background controllers to be available.
+//  5. Creates the policies of the configured version of the baseline policy set, excluding the addon and system
+//     namespaces from each of their rules.
+func PolicyEngine(ctx *pulumi.Context, locals *localz.Locals,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*PolicyEngineAddon, error) {
+	settings := locals.Options.PolicyEngine
+
+	policies, err := baselinePolicies(settings)
+	if err != nil {
+		return nil, errors.Wrap(err, "invalid baseline policies")
+	}
+
+	createdAddon := &PolicyEngineAddon{}
+	err = ctx.RegisterComponentResource(PolicyEngineResourceType, policyEngineAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register policy-engine addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.PolicyEngine.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.PolicyEngine.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.PolicyEngine.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create policy-engine namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, policyEngineAddonName)
+	chartSource := helmChartSource(locals, policyEngineAddonName, vars.PolicyEngine.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "kyverno",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.PolicyEngine.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.PolicyEngine.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, policyEngineAddonName, vars.PolicyEngine.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, policyEngineAddonName, vars.PolicyEngine.HelmChartName, pulumi.Map{
+				"admissionController": pulumi.Map{
+					"replicas": pulumi.Int(vars.PolicyEngine.AdmissionControllerReplicas),
+				},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kyverno helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	//wait for the admission controller as the policies are validated by its webhook
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName: policyEngineAddonName,
+			namespace: vars.PolicyEngine.Namespace,
+			crds:      []string{"clusterpolicies.kyverno.io", "policyreports.wgpolicyk8s.io"},
+			deployments: []string{
+				fmt.Sprintf("%s-admission-controller", vars.PolicyEngine.HelmChartName),
+				fmt.Sprintf("%s-background-controller", vars.PolicyEngine.HelmChartName),
+			},
+		}, createdHelmRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for policy-engine readiness")
+	}
+
+	policyManifests := make([]string, 0)
+	for _, p := range policies {
+		policyManifests = append(policyManifests, p.Content)
+	}
+	createdBaselinePolicies, err := pulumiyaml.NewConfigGroup(ctx,
+		"baseline-policies",
+		&pulumiyaml.ConfigGroupArgs{
+			YAML: policyManifests,
+			Transformations: []pulumiyaml.Transformation{
+				baselinePolicyTransformation(locals, policyExcludedNamespaces(settings)),
+			},
+		}, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdHelmRelease),
+		pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create baseline policies")
+	}
+	createdAddon.BaselinePolicies = createdBaselinePolicies
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register policy-engine addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// baselinePolicies returns the policies of the configured version of the baseline policy set. an error is returned
+// when the version is not shipped with the module or when a mode is configured for a policy that is not in the set.
+func baselinePolicies(settings *options.PolicyEngine) ([]*vars.Policy, error) {
+	version := settings.BaselineVersion
+	if version == "" {
+		version = vars.PolicyEngine.BaselinePolicyVersion
+	}
+	policies, err := vars.BaselinePolicies(version)
+	if err != nil {
+		versions, _ := vars.BaselinePolicyVersions()
+		return nil, errors.Wrapf(err, "available versions are %v", versions)
+	}
+	names := make([]string, 0)
+	for _, p := range policies {
+		names = append(names, p.Name)
+	}
+	for name := range settings.Policies {
+		if !contains(names, name) {
+			return nil, errors.Errorf("%s policy is not part of version %s of the baseline policy set, "+
+				"the policies are %v", name, version, names)
+		}
+	}
+	return policies, nil
+}
+
+// policyExcludedNamespaces returns the namespaces excluded from the policies, which are the namespaces of the addons,
+// the system namespaces and the namespaces excluded in the settings, sorted so that the policies do not change.
+func policyExcludedNamespaces(settings *options.PolicyEngine) []string {
+	namespaces := make([]string, 0)
+	add := func(namespace string) {
+		if !contains(namespaces, namespace) {
+			namespaces = append(namespaces, namespace)
+		}
+	}
+	//the addon namespaces are the ones with a pod-security level of their own
+	for namespace := range vars.PodSecurity.NamespaceLevels {
+		add(namespace)
+	}
+	for _, namespace := range vars.PolicyEngine.SystemNamespaces {
+		add(namespace)
+	}
+	for _, namespace := range settings.ExcludedNamespaces {
+		add(namespace)
+	}
+	sort.Strings(namespaces)
+	return namespaces
+}
+
+// baselinePolicyTransformation returns the transformation of the policies of the baseline policy set, which sets the
+// validation failure action from the mode of the policy and excludes the namespaces from each rule of the policy.
+//
+// the rules of the shipped policies have no exclusions of their own, so the exclusion is set rather than merged.
+func baselinePolicyTransformation(locals *localz.Locals, excludedNamespaces []string) pulumiyaml.Transformation {
+	settings := locals.Options.PolicyEngine
+	return func(state map[string]interface{}, opts ...pulumi.ResourceOption) {
+		metadata, _ := state["metadata"].(map[string]interface{})
+		name, _ := metadata["name"].(string)
+		if metadata != nil {
+			labels, _ := metadata["labels"].(map[string]interface{})
+			if labels == nil {
+				labels = make(map[string]interface{})
+			}
+			for k, v := range locals.KubernetesLabels {
+				labels[k] = v
+			}
+			metadata["labels"] = labels
+		}
+
+		spec, ok := state["spec"].(map[string]interface{})
+		if !ok {
+			return
+		}
+		//https://kyverno.io/docs/writing-policies/validate/#validation-failure-action
+		spec["validationFailureAction"] = "Audit"
+		if settings.PolicyMode(name) == options.PolicyModeEnforce {
+			spec["validationFailureAction"] = "Enforce"
+		}
+
+		namespaces := make([]interface{}, 0)
+		for _, namespace := range excludedNamespaces {
+			namespaces = append(namespaces, namespace)
+		}
+		rules, _ := spec["rules"].([]interface{})
+		for _, rule := range rules {
+			r, ok := rule.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			r["exclude"] = map[string]interface{}{
+				"any": []interface{}{
+					map[string]interface{}{
+						"resources": map[string]interface{}{
+							"namespaces": namespaces,
+						},
+					},
+				},
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestBaselinePolicies(t *testing.T) {
+	tests := []struct {
+		name     string
+		settings *options.PolicyEngine
+		want     []string
+		wantErr  string
+	}{
+		{
+			name:     "default version",
+			settings: &options.PolicyEngine{},
+			want: []string{"disallow-latest-tag", "disallow-privileged-containers", "require-requests",
+				"require-team-label"},
+		},
+		{
+			name:     "version not shipped",
+			settings: &options.PolicyEngine{BaselineVersion: "v9"},
+			wantErr:  "available versions are [v1]",
+		},
+		{
+			name: "mode of a policy not in the set",
+			settings: &options.PolicyEngine{Policies: map[string]string{
+				"require-probes": options.PolicyModeEnforce,
+			}},
+			wantErr: "require-probes policy is not part of version v1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policies, err := baselinePolicies(tt.settings)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Errorf("baselinePolicies() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("baselinePolicies() error = %v", err)
+			}
+			got := make([]string, 0)
+			for _, p := range policies {
+				got = append(got, p.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("baselinePolicies() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPolicyExcludedNamespaces(t *testing.T) {
+	got := policyExcludedNamespaces(&options.PolicyEngine{ExcludedNamespaces: []string{"legacy", "kube-system"}})
+	if !sort.StringsAreSorted(got) {
+		t.Errorf("policyExcludedNamespaces() = %v, want sorted", got)
+	}
+	want := append([]string{"legacy", vars.PolicyEngine.Namespace}, vars.PolicyEngine.SystemNamespaces...)
+	for _, namespace := range want {
+		if !contains(got, namespace) {
+			t.Errorf("policyExcludedNamespaces() = %v, want %s excluded", got, namespace)
+		}
+	}
+	seen := make(map[string]bool)
+	for _, namespace := range got {
+		if seen[namespace] {
+			t.Errorf("policyExcludedNamespaces() = %v, %s excluded twice", got, namespace)
+		}
+		seen[namespace] = true
+	}
+}
+
+func TestBaselinePolicyTransformation(t *testing.T) {
+	locals := &localz.Locals{
+		KubernetesLabels: map[string]string{"planton.cloud/resource-id": "gke-cluster"},
+		Options: &options.Options{PolicyEngine: &options.PolicyEngine{
+			Policies: map[string]string{"disallow-latest-tag": options.PolicyModeEnforce},
+		}},
+	}
+	policy := func(name string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind": "ClusterPolicy",
+			"metadata": map[string]interface{}{
+				"name":   name,
+				"labels": map[string]interface{}{"policies.kyverno.io/set": "baseline"},
+			},
+			"spec": map[string]interface{}{
+				"rules": []interface{}{
+					map[string]interface{}{"name": "validate"},
+				},
+			},
+		}
+	}
+	want := func(name, validationFailureAction string) map[string]interface{} {
+		return map[string]interface{}{
+			"kind": "ClusterPolicy",
+			"metadata": map[string]interface{}{
+				"name": name,
+				"labels": map[string]interface{}{
+					"policies.kyverno.io/set":   "baseline",
+					"planton.cloud/resource-id": "gke-cluster",
+				},
+			},
+			"spec": map[string]interface{}{
+				"validationFailureAction": validationFailureAction,
+				"rules": []interface{}{
+					map[string]interface{}{
+						"name": "validate",
+						"exclude": map[string]interface{}{
+							"any": []interface{}{
+								map[string]interface{}{
+									"resources": map[string]interface{}{
+										"namespaces": []interface{}{"kube-system", "kyverno"},
+									},
+								},
+							},
+						},
+					},
+				},
+			},
+		}
+	}
+	tests := []struct {
+		name  string
+		state map[string]interface{}
+		want  map[string]interface{}
+	}{
+		{
+			name:  "policy in the default mode",
+			state: policy("require-requests"),
+			want:  want("require-requests", "Audit"),
+		},
+		{
+			name:  "policy in the mode of its own",
+			state: policy("disallow-latest-tag"),
+			want:  want("disallow-latest-tag", "Enforce"),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			baselinePolicyTransformation(locals, []string{"kube-system", "kyverno"})(tt.state)
+			if !reflect.DeepEqual(tt.state, tt.want) {
+				t.Errorf("baselinePolicyTransformation() = %v, want %v", tt.state, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	rabbitMqOperatorAddonName = "rabbitmq-operator"
+	// RabbitMqOperatorResourceType is the pulumi type token of the rabbitmq-operator addon component.
+	RabbitMqOperatorResourceType = "planton:gke:RabbitMqOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: rabbitMqOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.DataOperators.IsInstallRabbitMqOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.RabbitMqOperator.HelmChartName},
+		Removal: &Removal{
+			//the resources of the messaging topology operator belong to a rabbitmq cluster
+			Crds: []string{
+				"bindings.rabbitmq.com",
+				"exchanges.rabbitmq.com",
+				"federations.rabbitmq.com",
+				"operatorpolicies.rabbitmq.com",
+				"permissions.rabbitmq.com",
+				"policies.rabbitmq.com",
+				"queues.rabbitmq.com",
+				"schemareplications.rabbitmq.com",
+				"shovels.rabbitmq.com",
+				"superstreams.rabbitmq.com",
+				"topicpermissions.rabbitmq.com",
+				"users.rabbitmq.com",
+				"vhosts.rabbitmq.com",
+				"rabbitmqclusters.rabbitmq.com",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return RabbitMqOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// RabbitMqOperatorAddon is the component of the rabbitmq-operator addon.
+type RabbitMqOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
+// RabbitMqOperator installs the RabbitMQ Cluster Operator and the Messaging Topology Operator in the Kubernetes
+// cluster using Helm.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *RabbitMqOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the RabbitMQ Cluster Operator and labels it with metadata from locals.
+// 3. Deploys the RabbitMQ Cluster Operator Helm chart into the created namespace, watching all namespaces.
+// 4. Uses Helm chart repository and version specified in the vars package.
+//
+// the operator propagates the labels of a RabbitmqCluster to all of its child resources, so the labels of the
+// resource are inherited without configuration of the operator.
+func RabbitMqOperator(ctx *pulumi.Context, locals *localz.Locals,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*RabbitMqOperatorAddon, error) {
+	createdAddon := &RabbitMqOperatorAddon{}
+	err := ctx.RegisterComponentResource(RabbitMqOperatorResourceType, rabbitMqOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register rabbitmq-operator addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.RabbitMqOperator.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.RabbitMqOperator.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.RabbitMqOperator.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create rabbitmq-operator namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, rabbitMqOperatorAddonName)
+	chartSource := helmChartSource(locals, rabbitMqOperatorAddonName, vars.RabbitMqOperator.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "rabbitmq-cluster-operator",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.RabbitMqOperator.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.RabbitMqOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, rabbitMqOperatorAddonName, vars.RabbitMqOperator.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, rabbitMqOperatorAddonName, vars.RabbitMqOperator.HelmChartName, pulumi.Map{
+				"clusterOperator": pulumi.Map{
+					"watchAllNamespaces": pulumi.Bool(true),
+				},
+				"msgTopologyOperator": pulumi.Map{
+					"watchAllNamespaces": pulumi.Bool(true),
+				},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create rabbitmq-operator helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register rabbitmq-operator addon outputs")
+	}
+	return createdAddon, nil
+}
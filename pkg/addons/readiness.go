@@ -0,0 +1,217 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strconv"
+)
+
+// readinessGate describes what has to be ready before the custom resources of an addon can be created.
+type readinessGate struct {
+	addonName string
+	// namespace of the deployments, also used to run the job that waits for the readiness.
+	namespace string
+	// crds are the names of the crds that need to be established, ex: "clusterissuers.cert-manager.io".
+	crds []string
+	// deployments are the names of the controller and webhook deployments that need to be available.
+	deployments []string
+	// statefulsets are the names of the controller statefulsets whose rollout needs to be complete.
+	statefulsets []string
+}
+
+// waitForReadiness creates a job that waits until the crds of the gate are established, its deployments are
+// available and the rollout of its statefulsets is complete. pulumi waits for the job to complete, so resources
+// that depend on the returned job are only created once the addon is ready. the job fails, naming the addon, when
+// the addon is not ready within the readiness timeout of the addon.
+//
+// the job is created as a child of the parent. when the parent is the helm release of the addon, the job is replaced,
+// and so the readiness is checked again, on every new revision of the helm release.
+func waitForReadiness(ctx *pulumi.Context, locals *localz.Locals, gate *readinessGate,
+	parent pulumi.Resource) (*batchv1.Job, error) {
+	resourceName := fmt.Sprintf("%s-readiness", gate.addonName)
+	timeoutSeconds := readinessTimeoutSeconds(locals, gate.addonName)
+
+	createdServiceAccount, err := corev1.NewServiceAccount(ctx,
+		resourceName,
+		&corev1.ServiceAccountArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(resourceName),
+					Namespace: pulumi.String(gate.namespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+		}, pulumi.Parent(parent))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create readiness service account")
+	}
+
+	//crds, deployments and statefulsets are read-only for the job
+	createdClusterRole, err := rbacv1.NewClusterRole(ctx,
+		resourceName,
+		&rbacv1.ClusterRoleArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(resourceName),
+					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			Rules: rbacv1.PolicyRuleArray{
+				rbacv1.PolicyRuleArgs{
+					ApiGroups: pulumi.ToStringArray([]string{"apiextensions.k8s.io"}),
+					Resources: pulumi.ToStringArray([]string{"customresourcedefinitions"}),
+					Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch"}),
+				},
+				rbacv1.PolicyRuleArgs{
+					ApiGroups: pulumi.ToStringArray([]string{"apps"}),
+					Resources: pulumi.ToStringArray([]string{"deployments", "statefulsets"}),
+					Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch"}),
+				},
+			},
+		}, pulumi.Parent(parent))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create readiness cluster role")
+	}
+
+	createdClusterRoleBinding, err := rbacv1.NewClusterRoleBinding(ctx,
+		resourceName,
+		&rbacv1.ClusterRoleBindingArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(resourceName),
+					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+				}),
+			RoleRef: rbacv1.RoleRefArgs{
+				ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+				Kind:     pulumi.String("ClusterRole"),
+				Name:     createdClusterRole.Metadata.Name().Elem(),
+			},
+			Subjects: rbacv1.SubjectArray{
+				rbacv1.SubjectArgs{
+					Kind:      pulumi.String("ServiceAccount"),
+					Name:      createdServiceAccount.Metadata.Name().Elem(),
+					Namespace: pulumi.String(gate.namespace),
+				},
+			},
+		}, pulumi.Parent(parent))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create readiness cluster role binding")
+	}
+
+	//crds are waited for in an init container so that the deployments are only checked once the crds are established
+	waitContainers := make([]corev1.ContainerInput, 0)
+	if len(gate.crds) > 0 {
+		args := []string{"wait", "--for=condition=Established", fmt.Sprintf("--timeout=%ds", timeoutSeconds)}
+		for _, crd := range gate.crds {
+			args = append(args, fmt.Sprintf("crd/%s", crd))
+		}
+		waitContainers = append(waitContainers, readinessContainer(locals, "crds", args))
+	}
+	if len(gate.deployments) > 0 {
+		args := []string{"wait", "--for=condition=Available", fmt.Sprintf("--timeout=%ds", timeoutSeconds),
+			"--namespace", gate.namespace}
+		for _, deployment := range gate.deployments {
+			args = append(args, fmt.Sprintf("deployment/%s", deployment))
+		}
+		waitContainers = append(waitContainers, readinessContainer(locals, "deployments", args))
+	}
+	//statefulsets have no available condition, and the rollout status is checked for one statefulset at a time
+	for i, statefulset := range gate.statefulsets {
+		args := []string{"rollout", "status", fmt.Sprintf("--timeout=%ds", timeoutSeconds),
+			"--namespace", gate.namespace, fmt.Sprintf("statefulset/%s", statefulset)}
+		waitContainers = append(waitContainers, readinessContainer(locals, fmt.Sprintf("statefulset-%d", i), args))
+	}
+	if len(waitContainers) == 0 {
+		return nil, errors.Errorf("nothing to wait for in readiness gate of %s addon", gate.addonName)
+	}
+
+	podAnnotations := pulumi.StringMap{}
+	if createdHelmRelease, ok := parent.(*helm.Release); ok {
+		//changes with every helm release revision which replaces the job
+		podAnnotations["helm-release-revision"] = createdHelmRelease.Status.Revision().ApplyT(
+			func(revision *int) string {
+				if revision == nil {
+					return ""
+				}
+				return strconv.Itoa(*revision)
+			}).(pulumi.StringOutput)
+	}
+
+	createdJob, err := batchv1.NewJob(ctx,
+		resourceName,
+		&batchv1.JobArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(resourceName),
+					Namespace: pulumi.String(gate.namespace),
+					Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					Annotations: pulumi.StringMap{
+						"description": pulumi.Sprintf("waits for the %s addon to be ready", gate.addonName),
+					},
+				}),
+			Spec: batchv1.JobSpecArgs{
+				BackoffLimit:          pulumi.Int(0),
+				ActiveDeadlineSeconds: pulumi.Int(timeoutSeconds),
+				Template: corev1.PodTemplateSpecArgs{
+					Metadata: metav1.ObjectMetaPtrInput(
+						&metav1.ObjectMetaArgs{
+							Labels:      pulumi.ToStringMap(locals.KubernetesLabels),
+							Annotations: podAnnotations,
+						}),
+					Spec: corev1.PodSpecArgs{
+						ServiceAccountName: createdServiceAccount.Metadata.Name().Elem(),
+						RestartPolicy:      pulumi.String("Never"),
+						//satisfies the restricted pod-security level of the addon namespaces
+						SecurityContext: corev1.PodSecurityContextArgs{
+							RunAsNonRoot: pulumi.Bool(true),
+							RunAsUser:    pulumi.Int(65532),
+							SeccompProfile: corev1.SeccompProfileArgs{
+								Type: pulumi.String("RuntimeDefault"),
+							},
+						},
+						InitContainers: corev1.ContainerArray(waitContainers[:len(waitContainers)-1]),
+						Containers:     corev1.ContainerArray(waitContainers[len(waitContainers)-1:]),
+					},
+				},
+			},
+		}, pulumi.Parent(parent),
+		pulumi.DependsOn([]pulumi.Resource{createdClusterRoleBinding}),
+		pulumi.DeleteBeforeReplace(true),
+		pulumi.Timeouts(&pulumi.CustomTimeouts{
+			Create: fmt.Sprintf("%ds", timeoutSeconds+vars.Readiness.JobStartupSeconds),
+			Update: fmt.Sprintf("%ds", timeoutSeconds+vars.Readiness.JobStartupSeconds),
+		}))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create readiness job for %s addon", gate.addonName)
+	}
+	return createdJob, nil
+}
+
+// readinessContainer returns a container running kubectl with the args.
+func readinessContainer(locals *localz.Locals, name string, args []string) corev1.ContainerInput {
+	return corev1.ContainerArgs{
+		Name:  pulumi.String(name),
+		Image: pulumi.String(mirroredImage(locals, vars.Readiness.KubectlImage)),
+		Args:  pulumi.ToStringArray(args),
+		SecurityContext: corev1.SecurityContextArgs{
+			AllowPrivilegeEscalation: pulumi.Bool(false),
+			Capabilities: corev1.CapabilitiesArgs{
+				Drop: pulumi.ToStringArray([]string{"ALL"}),
+			},
+		},
+	}
+}
+
+// readinessTimeoutSeconds returns the readiness timeout configured in the addon settings or the default timeout.
+func readinessTimeoutSeconds(locals *localz.Locals, addonName string) int {
+	if settings := locals.Options.Addon(addonName); settings != nil && settings.ReadinessTimeoutSeconds > 0 {
+		return settings.ReadinessTimeoutSeconds
+	}
+	return vars.Readiness.TimeoutSeconds
+}
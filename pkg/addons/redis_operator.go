@@ -0,0 +1,127 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	redisOperatorAddonName = "redis-operator"
+	// RedisOperatorResourceType is the pulumi type token of the redis-operator addon component.
+	RedisOperatorResourceType = "planton:gke:RedisOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: redisOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.DataOperators.IsInstallRedisOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.RedisOperator.HelmChartName},
+		Removal: &Removal{
+			//the sentinels monitor a redis replication
+			Crds: []string{
+				"redissentinels.redis.redis.opstreelabs.in",
+				"redisreplications.redis.redis.opstreelabs.in",
+				"redisclusters.redis.redis.opstreelabs.in",
+				"redis.redis.redis.opstreelabs.in",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return RedisOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// RedisOperatorAddon is the component of the redis-operator addon.
+type RedisOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
+// RedisOperator installs the Redis Operator of OpsTree in the Kubernetes cluster using Helm.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *RedisOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Redis Operator and labels it with metadata from locals.
+// 3. Deploys the Redis Operator Helm chart into the created namespace, which watches all namespaces.
+// 4. Uses Helm chart repository and version specified in the vars package.
+//
+// the operator adds the labels of a Redis, RedisCluster, RedisReplication or RedisSentinel to its statefulsets and
+// services, so the labels of the resource are inherited without configuration of the operator.
+func RedisOperator(ctx *pulumi.Context, locals *localz.Locals,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*RedisOperatorAddon, error) {
+	createdAddon := &RedisOperatorAddon{}
+	err := ctx.RegisterComponentResource(RedisOperatorResourceType, redisOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register redis-operator addon component")
+	}
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.RedisOperator.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.RedisOperator.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.RedisOperator.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create redis-operator namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, redisOperatorAddonName)
+	chartSource := helmChartSource(locals, redisOperatorAddonName, vars.RedisOperator.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "redis-operator",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.RedisOperator.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.RedisOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, redisOperatorAddonName, vars.RedisOperator.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values:          helmValues(locals, redisOperatorAddonName, vars.RedisOperator.HelmChartName, pulumi.Map{}),
+			RepositoryOpts:  chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create redis-operator helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register redis-operator addon outputs")
+	}
+	return createdAddon, nil
+}
@@ -0,0 +1,238 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"sort"
+	"strings"
+)
+
+// Registry holds the addons known to the module and resolves the order of their installation.
+type Registry struct {
+	addons map[string]*Addon
+}
+
+// defaultRegistry contains the addons implemented in this package. each addon adds itself from an init function.
+var defaultRegistry = NewRegistry()
+
+// register adds the addon to the default registry and panics on an invalid or duplicate addon, which can only be
+// the result of a programming error.
+func register(addon *Addon) {
+	if err := defaultRegistry.Add(addon); err != nil {
+		panic(err)
+	}
+}
+
+func NewRegistry() *Registry {
+	return &Registry{addons: make(map[string]*Addon)}
+}
+
+// Add adds the addon to the registry.
+func (r *Registry) Add(addon *Addon) error {
+	if addon.Name == "" || addon.IsEnabled == nil || addon.Install == nil {
+		return errors.Errorf("addon %q is missing name, is-enabled or install", addon.Name)
+	}
+	if addon.Removal != nil {
+		if len(addon.Removal.Crds) == 0 {
+			return errors.Errorf("removal of addon %s has no crds", addon.Name)
+		}
+		for _, crd := range addon.Removal.Crds {
+			if !strings.Contains(crd, ".") {
+				return errors.Errorf("%q in removal of addon %s is not the name of a crd", crd, addon.Name)
+			}
+		}
+	}
+	if _, ok := r.addons[addon.Name]; ok {
+		return errors.Errorf("addon %s is already registered", addon.Name)
+	}
+	r.addons[addon.Name] = addon
+	return nil
+}
+
+// Get returns the addon registered with the name.
+func (r *Registry) Get(name string) (*Addon, bool) {
+	addon, ok := r.addons[name]
+	return addon, ok
+}
+
+// IsAnyEnabled reports whether any addon of the registry is enabled.
+func (r *Registry) IsAnyEnabled(locals *localz.Locals) bool {
+	for _, addon := range r.addons {
+		if addon.IsEnabled(locals) {
+			return true
+		}
+	}
+	return false
+}
+
+// Resolve returns the enabled addons in the order in which they need to be installed.
+//
+// an error is returned when an addon depends on an addon which is unknown or not enabled, or when the
+// dependencies of the enabled addons form a cycle.
+func (r *Registry) Resolve(locals *localz.Locals) ([]*Addon, error) {
+	enabled := make(map[string]bool)
+	for name, addon := range r.addons {
+		enabled[name] = addon.IsEnabled(locals)
+	}
+
+	//collect the edges of each enabled addon, validating hard dependencies along the way
+	edges := make(map[string][]string)
+	for name, addon := range r.addons {
+		if !enabled[name] {
+			continue
+		}
+		for _, dependency := range addon.DependsOn {
+			if _, ok := r.addons[dependency]; !ok {
+				return nil, errors.Errorf("%s addon depends on unknown addon %s", name, dependency)
+			}
+			if !enabled[dependency] {
+				return nil, errors.Errorf("%s addon depends on %s addon which is not enabled", name, dependency)
+			}
+			edges[name] = append(edges[name], dependency)
+		}
+		for _, dependency := range addon.After {
+			if _, ok := r.addons[dependency]; !ok {
+				return nil, errors.Errorf("%s addon is ordered after unknown addon %s", name, dependency)
+			}
+			if enabled[dependency] {
+				edges[name] = append(edges[name], dependency)
+			}
+		}
+	}
+
+	//depth-first topological sort. names are visited in sorted order to keep the result stable across runs.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int)
+	resolved := make([]*Addon, 0)
+	path := make([]string, 0)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			cycle := append([]string{}, path...)
+			for len(cycle) > 0 && cycle[0] != name {
+				cycle = cycle[1:]
+			}
+			cycle = append(cycle, name)
+			return errors.Errorf("dependency cycle between addons: %s", strings.Join(cycle, " -> "))
+		}
+		state[name] = visiting
+		path = append(path, name)
+		dependencies := append([]string{}, edges[name]...)
+		sort.Strings(dependencies)
+		for _, dependency := range dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		resolved = append(resolved, r.addons[name])
+		return nil
+	}
+
+	names := make([]string, 0)
+	for name := range r.addons {
+		if enabled[name] {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+	return resolved, nil
+}
+
+// Install installs the enabled addons in dependency order and returns the components of the installed addons keyed
+// by the addon name. the removal of disabled addons with custom resources is guarded, see guardRemoval.
+func (r *Registry) Install(ctx *pulumi.Context, input *Input) (map[string]pulumi.Resource, error) {
+	resolved, err := r.Resolve(input.Locals)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to resolve addons")
+	}
+
+	for name := range input.Locals.Options.Addons {
+		if _, ok := r.addons[name]; !ok {
+			return nil, errors.Errorf("settings are configured for unknown addon %s", name)
+		}
+	}
+	for name := range input.ResourceOptions {
+		if _, ok := r.addons[name]; !ok {
+			return nil, errors.Errorf("resource options are passed for unknown addon %s", name)
+		}
+	}
+
+	for _, addon := range resolved {
+		for _, requirement := range addon.Requires {
+			if !input.isAvailable(requirement) {
+				return nil, errors.Errorf("%s addon requires %s which is not available", addon.Name, requirement)
+			}
+		}
+		if err := addon.validateValues(input.Locals.Options.Addon(addon.Name)); err != nil {
+			return nil, errors.Wrapf(err, "invalid settings for %s addon", addon.Name)
+		}
+		if err := checkCompatibility(ctx, addon, input); err != nil {
+			return nil, err
+		}
+	}
+
+	installed := make(map[string]pulumi.Resource)
+	addonInput := *input
+	addonInput.Installed = installed
+	for _, addon := range resolved {
+		addonInput.opts = input.ResourceOptions[addon.Name]
+		createdComponent, err := addon.Install(ctx, &addonInput)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to install %s resources", addon.Name)
+		}
+		installed[addon.Name] = createdComponent
+	}
+
+	//the custom resources of disabled addons are checked before pulumi removes the resources of the addons
+	guarded, err := r.guardedAddons(ctx, installed)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to look up the addons of the previous update")
+	}
+	for _, name := range guarded {
+		if !input.isAvailable(RequiresKubernetesProvider) {
+			return nil, errors.Errorf("removal of %s addon requires %s which is not available",
+				name, RequiresKubernetesProvider)
+		}
+		if err := guardRemoval(ctx, input, r.addons[name]); err != nil {
+			return nil, errors.Wrapf(err, "failed to guard removal of %s addon", name)
+		}
+	}
+
+	installedNames := make([]string, 0)
+	for name := range installed {
+		installedNames = append(installedNames, name)
+	}
+	sort.Strings(installedNames)
+	ctx.Export(outputs.InstalledAddons, pulumi.ToStringArray(installedNames))
+	ctx.Export(outputs.GuardedAddonRemovals, pulumi.ToStringArray(guarded))
+	return installed, nil
+}
+
+// Install installs the enabled addons of the default registry in dependency order and returns the components of the
+// installed addons keyed by the addon name.
+func Install(ctx *pulumi.Context, input *Input) (map[string]pulumi.Resource, error) {
+	return defaultRegistry.Install(ctx, input)
+}
+
+// IsAnyEnabled reports whether any addon of the default registry is enabled, by kubernetes-addons or by the module
+// options.
+func IsAnyEnabled(locals *localz.Locals) bool {
+	return defaultRegistry.IsAnyEnabled(locals)
+}
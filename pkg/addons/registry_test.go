@@ -0,0 +1,197 @@
+package addons
+
+import (
+	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testAddon returns an addon which is enabled as given and installs nothing.
+func testAddon(name string, enabled bool, dependsOn, after []string) *Addon {
+	return &Addon{
+		Name: name,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return enabled
+		},
+		DependsOn: dependsOn,
+		After:     after,
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return nil, nil
+		},
+	}
+}
+
+func TestRegistryResolve(t *testing.T) {
+	tests := []struct {
+		name    string
+		addons  []*Addon
+		want    []string
+		wantErr string
+	}{
+		{
+			name: "sorted by name without dependencies",
+			addons: []*Addon{
+				testAddon("c", true, nil, nil),
+				testAddon("a", true, nil, nil),
+				testAddon("b", true, nil, nil),
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name: "dependencies first",
+			addons: []*Addon{
+				testAddon("a", true, []string{"c"}, nil),
+				testAddon("b", true, nil, []string{"a"}),
+				testAddon("c", true, nil, nil),
+			},
+			want: []string{"c", "a", "b"},
+		},
+		{
+			name: "disabled addons are skipped",
+			addons: []*Addon{
+				testAddon("a", true, nil, []string{"b"}),
+				testAddon("b", false, nil, nil),
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "dependency on disabled addon",
+			addons: []*Addon{
+				testAddon("a", true, []string{"b"}, nil),
+				testAddon("b", false, nil, nil),
+			},
+			wantErr: "a addon depends on b addon which is not enabled",
+		},
+		{
+			name: "dependency of disabled addon on disabled addon",
+			addons: []*Addon{
+				testAddon("a", false, []string{"b"}, nil),
+				testAddon("b", false, nil, nil),
+			},
+			want: []string{},
+		},
+		{
+			name: "dependency on unknown addon",
+			addons: []*Addon{
+				testAddon("a", true, []string{"b"}, nil),
+			},
+			wantErr: "a addon depends on unknown addon b",
+		},
+		{
+			name: "ordered after unknown addon",
+			addons: []*Addon{
+				testAddon("a", true, nil, []string{"b"}),
+			},
+			wantErr: "a addon is ordered after unknown addon b",
+		},
+		{
+			name: "cycle",
+			addons: []*Addon{
+				testAddon("a", true, []string{"b"}, nil),
+				testAddon("b", true, nil, []string{"c"}),
+				testAddon("c", true, []string{"b"}, nil),
+			},
+			wantErr: "dependency cycle between addons: b -> c -> b",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			for _, addon := range tt.addons {
+				if err := r.Add(addon); err != nil {
+					t.Fatalf("Add() error = %v", err)
+				}
+			}
+			resolved, err := r.Resolve(&localz.Locals{})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Resolve() error = %v", err)
+			}
+			got := make([]string, 0)
+			for _, addon := range resolved {
+				got = append(got, addon.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Resolve() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRegistryAdd(t *testing.T) {
+	tests := []struct {
+		name    string
+		addon   *Addon
+		wantErr bool
+	}{
+		{
+			name:  "valid",
+			addon: testAddon("b", true, nil, nil),
+		},
+		{
+			name:    "missing name",
+			addon:   testAddon("", true, nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "duplicate",
+			addon:   testAddon("a", true, nil, nil),
+			wantErr: true,
+		},
+		{
+			name: "removal without crds",
+			addon: func() *Addon {
+				a := testAddon("b", true, nil, nil)
+				a.Removal = &Removal{}
+				return a
+			}(),
+			wantErr: true,
+		},
+		{
+			name: "removal with kind instead of crd name",
+			addon: func() *Addon {
+				a := testAddon("b", true, nil, nil)
+				a.Removal = &Removal{Crds: []string{"postgresql"}}
+				return a
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRegistry()
+			if err := r.Add(testAddon("a", true, nil, nil)); err != nil {
+				t.Fatalf("Add() error = %v", err)
+			}
+			if err := r.Add(tt.addon); (err != nil) != tt.wantErr {
+				t.Errorf("Add() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestDefaultRegistryResolve(t *testing.T) {
+	locals := &localz.Locals{
+		GkeCluster: &gkeclusterv1.GkeCluster{Spec: &gkeclusterv1.GkeClusterSpec{}},
+		Options:    &options.Options{},
+	}
+	resolved, err := defaultRegistry.Resolve(locals)
+	if err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if len(resolved) != 0 {
+		t.Errorf("Resolve() = %v, want no addons", resolved)
+	}
+	if IsAnyEnabled(locals) {
+		t.Errorf("IsAnyEnabled() = true, want false")
+	}
+}
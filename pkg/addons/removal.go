@@ -0,0 +1,325 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	batchv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/batch/v1"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	rbacv1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/rbac/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// AddonRemovalResourceType is the pulumi type token of the component that guards the removal of a disabled addon.
+const AddonRemovalResourceType = "planton:gke:AddonRemoval"
+
+// removalScript is run by the removal job of a disabled addon. it refuses to remove the addon while custom resources
+// of the addon exist, unless forced, and deletes the custom resources and crds of the addon otherwise.
+const removalScript = `#!/bin/sh
+set -eu
+
+existing=""
+for crd in $CRDS; do
+  if [ -n "$(kubectl get crd "$crd" --ignore-not-found -o name)" ]; then
+    existing="$existing $crd"
+  fi
+done
+
+blocked=""
+for crd in $existing; do
+  resources="$(kubectl get "$crd" --all-namespaces -o jsonpath='{range .items[*]}{.metadata.namespace}/{.metadata.name}{"\n"}{end}')"
+  for resource in $resources; do
+    blocked="$blocked
+  $crd $resource"
+  done
+done
+
+if [ -n "$blocked" ] && [ "$FORCE" != "true" ]; then
+  echo "refusing to remove the $ADDON addon as its custom resources still exist:$blocked"
+  echo "delete them, or set forceRemoval in the settings of the addon to delete them along with the addon"
+  exit 1
+fi
+
+#custom resources are deleted while the operator is still running, so that it handles their finalizers
+for crd in $existing; do
+  kubectl delete "$crd" --all --all-namespaces --wait --timeout="${TIMEOUT_SECONDS}s"
+done
+for crd in $existing; do
+  kubectl delete crd "$crd" --wait --timeout="${TIMEOUT_SECONDS}s"
+done
+echo "removed the custom resources and crds of the $ADDON addon:$existing"
+`
+
+// guardedAddons returns the sorted names of the disabled addons with a removal that were installed, or whose removal
+// was guarded, in the previous update of the stack. addons that were never enabled are not guarded.
+//
+// the addons of the previous update are read from the outputs of the stack itself. the outputs are missing on the
+// first update of a stack, in which case no addon is guarded.
+func (r *Registry) guardedAddons(ctx *pulumi.Context, installed map[string]pulumi.Resource) ([]string, error) {
+	candidates := make([]string, 0)
+	for name, addon := range r.addons {
+		if _, ok := installed[name]; !ok && addon.Removal != nil {
+			candidates = append(candidates, name)
+		}
+	}
+	guarded := make([]string, 0)
+	if len(candidates) == 0 {
+		return guarded, nil
+	}
+
+	previousStack, err := pulumi.NewStackReference(ctx,
+		vars.AddonRemoval.PreviousStackReferenceName,
+		&pulumi.StackReferenceArgs{
+			Name: pulumi.String(fmt.Sprintf("%s/%s/%s", ctx.Organization(), ctx.Project(), ctx.Stack())),
+		})
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to reference the previous update of the stack")
+	}
+	previous := make(map[string]bool)
+	for _, output := range []string{outputs.InstalledAddons, outputs.GuardedAddonRemovals} {
+		details, err := previousStack.GetOutputDetails(output)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s output of the previous update", output)
+		}
+		names, _ := details.Value.([]interface{})
+		for _, name := range names {
+			if n, ok := name.(string); ok {
+				previous[n] = true
+			}
+		}
+	}
+
+	for _, name := range candidates {
+		if previous[name] {
+			guarded = append(guarded, name)
+		}
+	}
+	sort.Strings(guarded)
+	return guarded, nil
+}
+
+// guardRemoval creates a job that has to complete before the resources of the disabled addon are removed.
+//
+// pulumi removes the resources of a disabled addon only after all other resources were updated successfully, so the
+// resources stay in place while the job fails. the job fails, listing the custom resources that block the removal,
+// when custom resources of the addon exist and the removal is not forced in the settings of the addon. otherwise
+// the job deletes the custom resources and then the crds of the addon, in the order of the removal.
+//
+// the job is created when the addon is disabled after having been enabled, see guardedAddons, and it is replaced on
+// the next update after it failed, so the check is repeated until the removal succeeds. the completed job stays in
+// the stack until the addon is enabled again, so that a removal whose update failed for another reason stays guarded.
+func guardRemoval(ctx *pulumi.Context, input *Input, addon *Addon) error {
+	resourceName := fmt.Sprintf("%s-removal", addon.Name)
+	force := false
+	if settings := input.Locals.Options.Addon(addon.Name); settings != nil {
+		force = settings.ForceRemoval
+	}
+
+	createdComponent := &pulumi.ResourceState{}
+	err := ctx.RegisterComponentResource(AddonRemovalResourceType, addon.Name, createdComponent,
+		pulumi.Provider(input.KubernetesProvider))
+	if err != nil {
+		return errors.Wrapf(err, "failed to register removal component of %s addon", addon.Name)
+	}
+
+	createdServiceAccount, err := corev1.NewServiceAccount(ctx,
+		resourceName,
+		&corev1.ServiceAccountArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(resourceName),
+					Namespace: pulumi.String(vars.AddonRemoval.Namespace),
+					Labels:    pulumi.ToStringMap(input.Locals.KubernetesLabels),
+				}),
+		}, pulumi.Parent(createdComponent))
+	if err != nil {
+		return errors.Wrap(err, "failed to create removal service account")
+	}
+
+	//the crds are listed by the job, but it can only delete the crds of the addon
+	rules := rbacv1.PolicyRuleArray{
+		rbacv1.PolicyRuleArgs{
+			ApiGroups: pulumi.ToStringArray([]string{"apiextensions.k8s.io"}),
+			Resources: pulumi.ToStringArray([]string{"customresourcedefinitions"}),
+			Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch"}),
+		},
+		rbacv1.PolicyRuleArgs{
+			ApiGroups:     pulumi.ToStringArray([]string{"apiextensions.k8s.io"}),
+			Resources:     pulumi.ToStringArray([]string{"customresourcedefinitions"}),
+			ResourceNames: pulumi.ToStringArray(addon.Removal.Crds),
+			Verbs:         pulumi.ToStringArray([]string{"delete"}),
+		},
+	}
+	for _, crd := range addon.Removal.Crds {
+		resource, group := crdResourceAndGroup(crd)
+		rules = append(rules, rbacv1.PolicyRuleArgs{
+			ApiGroups: pulumi.ToStringArray([]string{group}),
+			Resources: pulumi.ToStringArray([]string{resource}),
+			Verbs:     pulumi.ToStringArray([]string{"get", "list", "watch", "delete", "deletecollection"}),
+		})
+	}
+
+	createdClusterRole, err := rbacv1.NewClusterRole(ctx,
+		resourceName,
+		&rbacv1.ClusterRoleArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(resourceName),
+					Labels: pulumi.ToStringMap(input.Locals.KubernetesLabels),
+				}),
+			Rules: rules,
+		}, pulumi.Parent(createdComponent))
+	if err != nil {
+		return errors.Wrap(err, "failed to create removal cluster role")
+	}
+
+	createdClusterRoleBinding, err := rbacv1.NewClusterRoleBinding(ctx,
+		resourceName,
+		&rbacv1.ClusterRoleBindingArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(resourceName),
+					Labels: pulumi.ToStringMap(input.Locals.KubernetesLabels),
+				}),
+			RoleRef: rbacv1.RoleRefArgs{
+				ApiGroup: pulumi.String("rbac.authorization.k8s.io"),
+				Kind:     pulumi.String("ClusterRole"),
+				Name:     createdClusterRole.Metadata.Name().Elem(),
+			},
+			Subjects: rbacv1.SubjectArray{
+				rbacv1.SubjectArgs{
+					Kind:      pulumi.String("ServiceAccount"),
+					Name:      createdServiceAccount.Metadata.Name().Elem(),
+					Namespace: pulumi.String(vars.AddonRemoval.Namespace),
+				},
+			},
+		}, pulumi.Parent(createdComponent))
+	if err != nil {
+		return errors.Wrap(err, "failed to create removal cluster role binding")
+	}
+
+	createdConfigMap, err := corev1.NewConfigMap(ctx,
+		resourceName,
+		&corev1.ConfigMapArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(resourceName),
+					Namespace: pulumi.String(vars.AddonRemoval.Namespace),
+					Labels:    pulumi.ToStringMap(input.Locals.KubernetesLabels),
+				}),
+			Data: pulumi.StringMap{
+				"remove.sh": pulumi.String(removalScript),
+			},
+		}, pulumi.Parent(createdComponent))
+	if err != nil {
+		return errors.Wrap(err, "failed to create removal script config-map")
+	}
+
+	_, err = batchv1.NewJob(ctx,
+		resourceName,
+		&batchv1.JobArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      pulumi.String(resourceName),
+					Namespace: pulumi.String(vars.AddonRemoval.Namespace),
+					Labels:    pulumi.ToStringMap(input.Locals.KubernetesLabels),
+					Annotations: pulumi.StringMap{
+						"description": pulumi.Sprintf("checks the custom resources of the %s addon before it is removed",
+							addon.Name),
+						//a failed check is run again on the next update
+						"pulumi.com/replaceUnready": pulumi.String("true"),
+					},
+				}),
+			Spec: batchv1.JobSpecArgs{
+				BackoffLimit:          pulumi.Int(0),
+				ActiveDeadlineSeconds: pulumi.Int(vars.AddonRemoval.TimeoutSeconds),
+				Template: corev1.PodTemplateSpecArgs{
+					Metadata: metav1.ObjectMetaPtrInput(
+						&metav1.ObjectMetaArgs{
+							Labels: pulumi.ToStringMap(input.Locals.KubernetesLabels),
+							Annotations: pulumi.StringMap{
+								//replaces the job when the removal is forced after it was refused
+								"force-removal": pulumi.String(strconv.FormatBool(force)),
+							},
+						}),
+					Spec: corev1.PodSpecArgs{
+						ServiceAccountName: createdServiceAccount.Metadata.Name().Elem(),
+						RestartPolicy:      pulumi.String("Never"),
+						SecurityContext: corev1.PodSecurityContextArgs{
+							RunAsNonRoot: pulumi.Bool(true),
+							RunAsUser:    pulumi.Int(65532),
+							SeccompProfile: corev1.SeccompProfileArgs{
+								Type: pulumi.String("RuntimeDefault"),
+							},
+						},
+						Containers: corev1.ContainerArray{
+							corev1.ContainerArgs{
+								Name:    pulumi.String("removal"),
+								Image:   pulumi.String(mirroredImage(input.Locals, vars.AddonRemoval.Image)),
+								Command: pulumi.ToStringArray([]string{"/bin/sh", "/scripts/remove.sh"}),
+								Env: corev1.EnvVarArray{
+									corev1.EnvVarArgs{Name: pulumi.String("ADDON"), Value: pulumi.String(addon.Name)},
+									corev1.EnvVarArgs{Name: pulumi.String("CRDS"), Value: pulumi.String(strings.Join(addon.Removal.Crds, " "))},
+									corev1.EnvVarArgs{Name: pulumi.String("FORCE"), Value: pulumi.String(strconv.FormatBool(force))},
+									corev1.EnvVarArgs{Name: pulumi.String("TIMEOUT_SECONDS"), Value: pulumi.String(strconv.Itoa(vars.AddonRemoval.TimeoutSeconds))},
+									//kubectl writes its cache to the home directory
+									corev1.EnvVarArgs{Name: pulumi.String("HOME"), Value: pulumi.String("/tmp")},
+								},
+								//the blocking custom resources are reported in the termination message of the pod
+								TerminationMessagePolicy: pulumi.String("FallbackToLogsOnError"),
+								SecurityContext: corev1.SecurityContextArgs{
+									AllowPrivilegeEscalation: pulumi.Bool(false),
+									Capabilities: corev1.CapabilitiesArgs{
+										Drop: pulumi.ToStringArray([]string{"ALL"}),
+									},
+								},
+								VolumeMounts: corev1.VolumeMountArray{
+									corev1.VolumeMountArgs{
+										Name:      pulumi.String("scripts"),
+										MountPath: pulumi.String("/scripts"),
+									},
+								},
+							},
+						},
+						Volumes: corev1.VolumeArray{
+							corev1.VolumeArgs{
+								Name: pulumi.String("scripts"),
+								ConfigMap: corev1.ConfigMapVolumeSourceArgs{
+									Name: createdConfigMap.Metadata.Name(),
+								},
+							},
+						},
+					},
+				},
+			},
+		}, pulumi.Parent(createdComponent),
+		pulumi.DependsOn([]pulumi.Resource{createdClusterRoleBinding}),
+		pulumi.DeleteBeforeReplace(true),
+		pulumi.Timeouts(&pulumi.CustomTimeouts{
+			Create: fmt.Sprintf("%ds", vars.AddonRemoval.TimeoutSeconds+vars.Readiness.JobStartupSeconds),
+			Update: fmt.Sprintf("%ds", vars.AddonRemoval.TimeoutSeconds+vars.Readiness.JobStartupSeconds),
+		}))
+	if err != nil {
+		return errors.Wrapf(err, "failed to create removal job for %s addon", addon.Name)
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdComponent, pulumi.Map{}); err != nil {
+		return errors.Wrapf(err, "failed to register outputs of removal component of %s addon", addon.Name)
+	}
+	return nil
+}
+
+// crdResourceAndGroup returns the plural resource name and the api group of a crd from the name of the crd,
+// ex: "postgresqls" and "acid.zalan.do" for "postgresqls.acid.zalan.do".
+func crdResourceAndGroup(crd string) (string, string) {
+	parts := strings.SplitN(crd, ".", 2)
+	if len(parts) < 2 {
+		return crd, ""
+	}
+	return parts[0], parts[1]
+}
@@ -12,6 +12,42 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	solrOperatorAddonName = "solr-operator"
+	// SolrOperatorResourceType is the pulumi type token of the solr-operator addon component.
+	SolrOperatorResourceType = "planton:gke:SolrOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: solrOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallSolrOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.SolrOperator.HelmChartName},
+		Removal: &Removal{
+			Crds: []string{
+				"solrbackups.solr.apache.org",
+				"solrprometheusexporters.solr.apache.org",
+				"solrclouds.solr.apache.org",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return SolrOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// SolrOperatorAddon is the component of the solr-operator addon.
+type SolrOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	Crds        *pulumiyaml.ConfigGroup
+	HelmRelease *helm.Release
+}
+
 // SolrOperator installs the Solr Operator in the Kubernetes cluster using Helm.
 // It creates the necessary namespace, applies CRD resources, and deploys the Helm chart.
 //
@@ -19,18 +55,27 @@ import (
 // - ctx: The Pulumi context used for defining cloud resources.
 // - locals: A struct containing local configuration and metadata.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *SolrOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a namespace for the Solr Operator and labels it with metadata from locals.
-// 2. Applies the Solr Operator CRDs by downloading and adding the manifest file.
-// 3. Deploys the Solr Operator Helm chart into the created namespace with specific values.
-// 4. Uses Helm chart repository and version specified in the vars package.
-// 5. Handles errors and returns any errors encountered during the namespace creation, CRD application, or Helm release deployment.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Solr Operator and labels it with metadata from locals.
+// 3. Applies the Solr Operator CRDs from the manifest vendored into the module.
+// 4. Deploys the Solr Operator Helm chart into the created namespace with specific values.
+// 5. Uses Helm chart repository and version specified in the vars package.
+// 6. Handles errors and returns any errors encountered during the namespace creation, CRD application, or Helm release deployment.
 func SolrOperator(ctx *pulumi.Context, locals *localz.Locals,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*SolrOperatorAddon, error) {
+	createdAddon := &SolrOperatorAddon{}
+	err := ctx.RegisterComponentResource(SolrOperatorResourceType, solrOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register solr-operator addon component")
+	}
 	//create namespace resource
 	createdNamespace, err := corev1.NewNamespace(ctx,
 		vars.SolrOperator.Namespace,
@@ -38,45 +83,60 @@ func SolrOperator(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.SolrOperator.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.SolrOperator.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create namespace")
+		return nil, errors.Wrapf(err, "failed to create namespace")
 	}
+	createdAddon.Namespace = createdNamespace
 
-	//create solr-operator crd resources
-	createdCrdsManifestFile, err := pulumiyaml.NewConfigFile(ctx, "solr-operator-crds",
-		&pulumiyaml.ConfigFileArgs{
-			File: vars.SolrOperator.CrdManifestDownloadUrl,
-		}, pulumi.Provider(kubernetesProvider),
+	//create solr-operator crd resources from the manifest vendored into the module
+	crdsArgs, err := crdManifestConfigGroupArgs(vars.SolrOperatorCrdManifest())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read solr-operator crds manifest")
+	}
+	createdCrdsManifestFile, err := pulumiyaml.NewConfigGroup(ctx, "solr-operator-crds",
+		crdsArgs, pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon),
+		pulumi.Aliases(configFileAliases),
 	)
 	if err != nil {
-		return errors.Wrap(err, "failed to add solr-operator crds manifest")
+		return nil, errors.Wrap(err, "failed to add solr-operator crds manifest")
 	}
+	createdAddon.Crds = createdCrdsManifestFile
+
+	releaseSettings := helmReleaseSettings(locals, solrOperatorAddonName)
+	chartSource := helmChartSource(locals, solrOperatorAddonName, vars.SolrOperator.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, "solr-operator",
+	createdHelmRelease, err := helm.NewRelease(ctx, "solr-operator",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.SolrOperator.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.SolrOperator.HelmChartName),
-			Version:         pulumi.String(vars.SolrOperator.HelmChartVersion),
+			Chart:           chartSource.chart(vars.SolrOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, solrOperatorAddonName, vars.SolrOperator.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values:          pulumi.Map{},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.SolrOperator.HelmChartRepo),
-			},
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values:          helmValues(locals, solrOperatorAddonName, vars.SolrOperator.HelmChartName, pulumi.Map{}),
+			RepositoryOpts:  chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
 		pulumi.DependsOn([]pulumi.Resource{createdCrdsManifestFile}),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register solr-operator addon outputs")
 	}
-	return nil
+	return createdAddon, nil
 }
@@ -11,6 +11,49 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	strimziKafkaOperatorAddonName = "strimzi-kafka-operator"
+	// StrimziKafkaOperatorResourceType is the pulumi type token of the strimzi-kafka-operator addon component.
+	StrimziKafkaOperatorResourceType = "planton:gke:StrimziKafkaOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: strimziKafkaOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallKafkaOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.StrimziKafkaOperator.HelmChartName},
+		Removal: &Removal{
+			Crds: []string{
+				"kafkatopics.kafka.strimzi.io",
+				"kafkausers.kafka.strimzi.io",
+				"kafkaconnectors.kafka.strimzi.io",
+				"kafkarebalances.kafka.strimzi.io",
+				"kafkaconnects.kafka.strimzi.io",
+				"kafkamirrormaker2s.kafka.strimzi.io",
+				"kafkamirrormakers.kafka.strimzi.io",
+				"kafkabridges.kafka.strimzi.io",
+				"kafkanodepools.kafka.strimzi.io",
+				"kafkas.kafka.strimzi.io",
+				"strimzipodsets.core.strimzi.io",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return StrimziKafkaOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// StrimziKafkaOperatorAddon is the component of the strimzi-kafka-operator addon.
+type StrimziKafkaOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
 // StrimziKafkaOperator installs the Strimzi Kafka Operator in the Kubernetes cluster using Helm.
 // It creates the necessary namespace and deploys the Helm chart with specific values.
 //
@@ -18,17 +61,26 @@ import (
 // - ctx: The Pulumi context used for defining cloud resources.
 // - locals: A struct containing local configuration and metadata.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *StrimziKafkaOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a namespace for the Strimzi Kafka Operator and labels it with metadata from locals.
-// 2. Deploys the Strimzi Kafka Operator Helm chart into the created namespace with specific values, including enabling watching any namespace.
-// 3. Uses Helm chart repository and version specified in the vars package.
-// 4. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Strimzi Kafka Operator and labels it with metadata from locals.
+// 3. Deploys the Strimzi Kafka Operator Helm chart into the created namespace with specific values, including enabling watching any namespace.
+// 4. Uses Helm chart repository and version specified in the vars package.
+// 5. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
 func StrimziKafkaOperator(ctx *pulumi.Context, locals *localz.Locals,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*StrimziKafkaOperatorAddon, error) {
+	createdAddon := &StrimziKafkaOperatorAddon{}
+	err := ctx.RegisterComponentResource(StrimziKafkaOperatorResourceType, strimziKafkaOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register strimzi-kafka-operator addon component")
+	}
 	//create namespace resource
 	createdNamespace, err := corev1.NewNamespace(ctx,
 		vars.StrimziKafkaOperator.Namespace,
@@ -36,36 +88,47 @@ func StrimziKafkaOperator(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.StrimziKafkaOperator.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.StrimziKafkaOperator.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create namespace")
+		return nil, errors.Wrapf(err, "failed to create namespace")
 	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, strimziKafkaOperatorAddonName)
+	chartSource := helmChartSource(locals, strimziKafkaOperatorAddonName, vars.StrimziKafkaOperator.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, "strimzi-kafka-operator",
+	createdHelmRelease, err := helm.NewRelease(ctx, "strimzi-kafka-operator",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.StrimziKafkaOperator.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.StrimziKafkaOperator.HelmChartName),
-			Version:         pulumi.String(vars.StrimziKafkaOperator.HelmChartVersion),
+			Chart:           chartSource.chart(vars.StrimziKafkaOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, strimziKafkaOperatorAddonName, vars.StrimziKafkaOperator.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, strimziKafkaOperatorAddonName, vars.StrimziKafkaOperator.HelmChartName, pulumi.Map{
 				"watchAnyNamespace": pulumi.Bool(true),
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.StrimziKafkaOperator.HelmChartRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register strimzi-kafka-operator addon outputs")
 	}
-	return nil
+	return createdAddon, nil
 }
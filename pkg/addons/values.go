@@ -0,0 +1,150 @@
+package addons
+
+import (
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"sort"
+	"strings"
+)
+
+// helmValues returns the module's values for the chart of the addon with the values from the addon settings
+// deep-merged on top of them.
+//
+// values of the addon are merged first and the values of the chart are merged on top of the result. maps are merged
+// key by key while any other value, including lists, replaces the value of the module. when an image registry
+// mirror is configured, the values that pull the images of the chart from the mirror are merged into the module's
+// values, so they can still be overridden from the addon settings.
+func helmValues(locals *localz.Locals, addonName, chartName string, defaults pulumi.Map) pulumi.Map {
+	if mirror := mirrorSettings(locals); mirror != nil && mirror.ImageRegistry != "" {
+		if registryValues, ok := imageRegistryValues[chartName]; ok {
+			defaults = mergeValues(defaults, registryValues(strings.TrimSuffix(mirror.ImageRegistry, "/")))
+		}
+	}
+	settings := locals.Options.Addon(addonName)
+	if settings == nil {
+		return defaults
+	}
+	merged := mergeValues(defaults, settings.Values)
+	return mergeValues(merged, settings.ChartValues[chartName])
+}
+
+// mergeValues deep-merges the overrides into a copy of the values.
+func mergeValues(values pulumi.Map, overrides map[string]interface{}) pulumi.Map {
+	merged := pulumi.Map{}
+	for k, v := range values {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		if overrideMap, ok := v.(map[string]interface{}); ok {
+			if valueMap, ok := toValuesMap(merged[k]); ok {
+				merged[k] = mergeValues(valueMap, overrideMap)
+				continue
+			}
+		}
+		merged[k] = toValuesInput(v)
+	}
+	return merged
+}
+
+// toValuesMap returns the map when the module's value is one that can be merged into.
+func toValuesMap(value pulumi.Input) (pulumi.Map, bool) {
+	switch v := value.(type) {
+	case pulumi.Map:
+		return v, true
+	case pulumi.StringMap:
+		m := pulumi.Map{}
+		for k, s := range v {
+			m[k] = s
+		}
+		return m, true
+	}
+	return nil, false
+}
+
+// toValuesInput converts a value decoded from a values document into a pulumi input.
+func toValuesInput(value interface{}) pulumi.Input {
+	switch v := value.(type) {
+	case nil:
+		//null removes the default of the chart in helm, so it is passed through as is
+		return nil
+	case map[string]interface{}:
+		return mergeValues(pulumi.Map{}, v)
+	case []interface{}:
+		a := pulumi.Array{}
+		for _, item := range v {
+			a = append(a, toValuesInput(item))
+		}
+		return a
+	case string:
+		return pulumi.String(v)
+	case bool:
+		return pulumi.Bool(v)
+	case int:
+		return pulumi.Int(v)
+	case float64:
+		return pulumi.Float64(v)
+	}
+	return pulumi.Any(value)
+}
+
+// validateValues returns an error when the addon settings override a value protected by the addon or set values
+// for a chart the addon does not install.
+func (a *Addon) validateValues(settings *options.AddonSettings) error {
+	if settings == nil {
+		return nil
+	}
+	if err := a.validateProtectedValues(settings.Values); err != nil {
+		return errors.Wrap(err, "values")
+	}
+	chartNames := make([]string, 0)
+	for chartName := range settings.ChartValues {
+		chartNames = append(chartNames, chartName)
+	}
+	sort.Strings(chartNames)
+	for _, chartName := range chartNames {
+		if !contains(a.HelmCharts, chartName) {
+			return errors.Errorf("chartValues: %s is not one of the charts of the addon: %s",
+				chartName, strings.Join(a.HelmCharts, ", "))
+		}
+		if err := a.validateProtectedValues(settings.ChartValues[chartName]); err != nil {
+			return errors.Wrapf(err, "chartValues.%s", chartName)
+		}
+	}
+	return nil
+}
+
+func (a *Addon) validateProtectedValues(values map[string]interface{}) error {
+	for _, path := range a.ProtectedValues {
+		if isPathSet(values, strings.Split(path, ".")) {
+			return errors.Errorf("%s is managed by the module and can not be overridden", path)
+		}
+	}
+	return nil
+}
+
+// isPathSet reports whether the values set the path or replace one of its parents with something other than a map.
+func isPathSet(values map[string]interface{}, path []string) bool {
+	value, ok := values[path[0]]
+	if !ok {
+		return false
+	}
+	if len(path) == 1 {
+		return true
+	}
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return true
+	}
+	return isPathSet(child, path[1:])
+}
+
+func contains(items []string, item string) bool {
+	for _, i := range items {
+		if i == item {
+			return true
+		}
+	}
+	return false
+}
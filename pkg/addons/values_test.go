@@ -0,0 +1,215 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMergeValues(t *testing.T) {
+	tests := []struct {
+		name      string
+		values    pulumi.Map
+		overrides map[string]interface{}
+		want      pulumi.Map
+	}{
+		{
+			name:      "no overrides",
+			values:    pulumi.Map{"replicas": pulumi.Int(1)},
+			overrides: nil,
+			want:      pulumi.Map{"replicas": pulumi.Int(1)},
+		},
+		{
+			name:      "scalar replaced",
+			values:    pulumi.Map{"replicas": pulumi.Int(1), "image": pulumi.String("nginx")},
+			overrides: map[string]interface{}{"replicas": 3},
+			want:      pulumi.Map{"replicas": pulumi.Int(3), "image": pulumi.String("nginx")},
+		},
+		{
+			name: "maps merged key by key",
+			values: pulumi.Map{
+				"controller": pulumi.Map{"replicas": pulumi.Int(1), "image": pulumi.String("nginx")},
+			},
+			overrides: map[string]interface{}{
+				"controller": map[string]interface{}{"replicas": 2, "debug": true},
+			},
+			want: pulumi.Map{
+				"controller": pulumi.Map{
+					"replicas": pulumi.Int(2),
+					"image":    pulumi.String("nginx"),
+					"debug":    pulumi.Bool(true),
+				},
+			},
+		},
+		{
+			name:      "string map merged",
+			values:    pulumi.Map{"labels": pulumi.StringMap{"team": pulumi.String("platform")}},
+			overrides: map[string]interface{}{"labels": map[string]interface{}{"tier": "system"}},
+			want: pulumi.Map{
+				"labels": pulumi.Map{"team": pulumi.String("platform"), "tier": pulumi.String("system")},
+			},
+		},
+		{
+			name:      "lists replaced",
+			values:    pulumi.Map{"args": pulumi.Array{pulumi.String("--v=1")}},
+			overrides: map[string]interface{}{"args": []interface{}{"--v=2", 0.5}},
+			want:      pulumi.Map{"args": pulumi.Array{pulumi.String("--v=2"), pulumi.Float64(0.5)}},
+		},
+		{
+			name:      "map replaced by scalar",
+			values:    pulumi.Map{"resources": pulumi.Map{"limits": pulumi.Map{}}},
+			overrides: map[string]interface{}{"resources": "none"},
+			want:      pulumi.Map{"resources": pulumi.String("none")},
+		},
+		{
+			name:      "null passed through",
+			values:    pulumi.Map{"nodeSelector": pulumi.StringMap{"pool": pulumi.String("system")}},
+			overrides: map[string]interface{}{"nodeSelector": nil},
+			want:      pulumi.Map{"nodeSelector": nil},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mergeValues(tt.values, tt.overrides); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeValues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMergeValuesKeepsValues(t *testing.T) {
+	values := pulumi.Map{"controller": pulumi.Map{"replicas": pulumi.Int(1)}}
+	mergeValues(values, map[string]interface{}{"controller": map[string]interface{}{"replicas": 2}})
+	want := pulumi.Map{"controller": pulumi.Map{"replicas": pulumi.Int(1)}}
+	if !reflect.DeepEqual(values, want) {
+		t.Errorf("mergeValues() changed the values to %v", values)
+	}
+}
+
+func TestIsPathSet(t *testing.T) {
+	tests := []struct {
+		name   string
+		values map[string]interface{}
+		path   []string
+		want   bool
+	}{
+		{
+			name:   "not set",
+			values: map[string]interface{}{"replicas": 2},
+			path:   []string{"serviceAccount", "name"},
+			want:   false,
+		},
+		{
+			name:   "sibling set",
+			values: map[string]interface{}{"serviceAccount": map[string]interface{}{"annotations": map[string]interface{}{}}},
+			path:   []string{"serviceAccount", "name"},
+			want:   false,
+		},
+		{
+			name:   "set",
+			values: map[string]interface{}{"serviceAccount": map[string]interface{}{"name": "other"}},
+			path:   []string{"serviceAccount", "name"},
+			want:   true,
+		},
+		{
+			name:   "set to null",
+			values: map[string]interface{}{"serviceAccount": map[string]interface{}{"name": nil}},
+			path:   []string{"serviceAccount", "name"},
+			want:   true,
+		},
+		{
+			name:   "parent replaced",
+			values: map[string]interface{}{"serviceAccount": "other"},
+			path:   []string{"serviceAccount", "name"},
+			want:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isPathSet(tt.values, tt.path); got != tt.want {
+				t.Errorf("isPathSet() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddonValidateValues(t *testing.T) {
+	addon := &Addon{
+		Name:            "test",
+		HelmCharts:      []string{"base", "istiod"},
+		ProtectedValues: []string{"serviceAccount.name", "fullnameOverride"},
+	}
+	tests := []struct {
+		name     string
+		settings *options.AddonSettings
+		wantErr  bool
+	}{
+		{
+			name:     "no settings",
+			settings: nil,
+		},
+		{
+			name: "unprotected values",
+			settings: &options.AddonSettings{
+				Values: options.HelmValues{"serviceAccount": map[string]interface{}{"annotations": map[string]interface{}{}}},
+				ChartValues: map[string]options.HelmValues{
+					"istiod": {"pilot": map[string]interface{}{"replicaCount": 2}},
+				},
+			},
+		},
+		{
+			name: "protected value",
+			settings: &options.AddonSettings{
+				Values: options.HelmValues{"fullnameOverride": "other"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "protected value replaced by parent",
+			settings: &options.AddonSettings{
+				Values: options.HelmValues{"serviceAccount": nil},
+			},
+			wantErr: true,
+		},
+		{
+			name: "protected value of a chart",
+			settings: &options.AddonSettings{
+				ChartValues: map[string]options.HelmValues{
+					"base": {"serviceAccount": map[string]interface{}{"name": "other"}},
+				},
+			},
+			wantErr: true,
+		},
+		{
+			name: "values of a chart not installed by the addon",
+			settings: &options.AddonSettings{
+				ChartValues: map[string]options.HelmValues{"gateway": {}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := addon.validateValues(tt.settings); (err != nil) != tt.wantErr {
+				t.Errorf("validateValues() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+// valueAt returns the value at the dot separated path of the helm values, or nil when the path is not set.
+func valueAt(values pulumi.Map, path string) pulumi.Input {
+	var value pulumi.Input = values
+	for _, key := range strings.Split(path, ".") {
+		m, ok := toValuesMap(value)
+		if !ok {
+			return nil
+		}
+		if value, ok = m[key]; !ok {
+			return nil
+		}
+	}
+	return value
+}
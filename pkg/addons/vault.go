@@ -0,0 +1,397 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	externalsecretsv1 "github.com/plantoncloud/kubernetes-crd-pulumi-types/pkg/externalsecrets/externalsecrets/v1beta1"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/kms"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+const (
+	vaultAddonName = "vault"
+	// VaultResourceType is the pulumi type token of the vault addon component.
+	VaultResourceType = "planton:gke:VaultAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: vaultAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Vault != nil
+		},
+		Requires: []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		//the addons used for the ingress of vault and for the cluster-secret-store, when enabled
+		After:      []string{istioAddonName, ingressNginxAddonName, certManagerAddonName, externalSecretsAddonName},
+		HelmCharts: []string{vars.Vault.HelmChartName},
+		ProtectedValues: []string{
+			"server.serviceAccount.create",
+			"server.serviceAccount.name",
+			"server.ha.raft.config",
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Vault(ctx, input.Locals, input.CreatedCluster, input.GcpProvider, input.KubernetesProvider,
+				input.Installed, input.opts...)
+		},
+	})
+}
+
+// VaultAddon is the component of the vault addon.
+type VaultAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+	// Url of the vault api and ui, empty when vault is not exposed on an ingress.
+	Url pulumi.StringOutput
+
+	KmsService *projects.Service
+	// KmsKeyRing and KmsCryptoKey are retained when the addon is removed, vault can not be unsealed without the key.
+	KmsKeyRing       *kms.KeyRing
+	KmsCryptoKey     *kms.CryptoKey
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	HelmRelease      *helm.Release
+	// Ingress is only created when an ingress is configured.
+	Ingress *IngressExposure
+	// ClusterSecretStore is only created when the external-secrets addon is installed.
+	ClusterSecretStore *externalsecretsv1.ClusterSecretStore
+}
+
+// Vault installs HashiCorp Vault in the Kubernetes cluster using Helm in high availability mode with raft storage,
+// auto-unsealed with a Cloud KMS key.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where Vault will be installed.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - installedAddons: The components of the installed addons keyed by the addon name, used for the ingress and the
+// cluster-secret-store.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *VaultAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Enables the Cloud KMS api and creates the key ring and the unseal key in the project and region of the cluster.
+//  3. Creates a namespace for Vault and labels it with metadata from locals.
+//  4. Creates the workload identity for Vault, i.e. a Google Service Account (GSA), the Workload Identity binding
+//     and a Kubernetes Service Account (KSA) annotated with the GSA email.
+//  5. Grants the GSA the permissions to encrypt and decrypt with the unseal key and exports its email.
+//  6. Deploys the Vault Helm chart into the created namespace in ha mode with raft storage and the gcpckms seal.
+//  7. When an ingress is configured, exposes the active vault server on the hostname through istio or ingress-nginx.
+//  8. When the external-secrets addon is installed, creates a ClusterSecretStore for the kv secrets engine of vault.
+//
+// vault is not initialized by the module, as that would store the root token in the pulumi state. the replicas are
+// ready while vault is uninitialized, so that the helm release does not wait for the initialization.
+func Vault(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider,
+	installedAddons map[string]pulumi.Resource, opts ...pulumi.ResourceOption) (*VaultAddon, error) {
+	settings := locals.Options.Vault
+
+	createdAddon := &VaultAddon{Url: pulumi.String("").ToStringOutput()}
+	err := ctx.RegisterComponentResource(VaultResourceType, vaultAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register vault addon component")
+	}
+
+	//the api is left enabled when the addon is removed as the retained key ring and key are still needed
+	createdKmsService, err := projects.NewService(ctx,
+		"vault-cloudkms.googleapis.com",
+		&projects.ServiceArgs{
+			Project:          createdCluster.Project,
+			Service:          pulumi.String("cloudkms.googleapis.com"),
+			DisableOnDestroy: pulumi.Bool(false),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to enable cloudkms api for vault")
+	}
+	createdAddon.KmsService = createdKmsService
+
+	//key rings can not be deleted and the data of vault can not be decrypted without the key. the retained key ring
+	//and key of a previous installation are adopted when the addon is enabled again, as they can not be created again.
+	keyRingName := locals.KmsKeyRingName(vars.Vault.KmsKeyRingName)
+	keyRingImport, cryptoKeyImport := vaultKmsImports(settings, locals.GkeCluster.Spec.ClusterProjectId,
+		locals.GkeCluster.Spec.Region, keyRingName)
+	keyRingOpts := append([]pulumi.ResourceOption{pulumi.Parent(createdAddon),
+		pulumi.DependsOn([]pulumi.Resource{createdKmsService}),
+		pulumi.RetainOnDelete(true)}, keyRingImport...)
+	cryptoKeyOpts := append([]pulumi.ResourceOption{pulumi.RetainOnDelete(true)}, cryptoKeyImport...)
+	createdKeyRing, err := kms.NewKeyRing(ctx,
+		keyRingName,
+		&kms.KeyRingArgs{
+			Name:     pulumi.String(keyRingName),
+			Project:  createdCluster.Project,
+			Location: pulumi.String(locals.GkeCluster.Spec.Region),
+		}, keyRingOpts...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vault kms key ring")
+	}
+	createdAddon.KmsKeyRing = createdKeyRing
+
+	createdCryptoKey, err := kms.NewCryptoKey(ctx,
+		vars.Vault.KmsCryptoKeyName,
+		&kms.CryptoKeyArgs{
+			Name:           pulumi.String(vars.Vault.KmsCryptoKeyName),
+			KeyRing:        createdKeyRing.ID(),
+			Purpose:        pulumi.String("ENCRYPT_DECRYPT"),
+			RotationPeriod: pulumi.String(vars.Vault.KmsCryptoKeyRotationPeriod),
+			Labels:         pulumi.ToStringMap(locals.GcpLabels),
+		}, append([]pulumi.ResourceOption{pulumi.Parent(createdKeyRing)}, cryptoKeyOpts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vault kms unseal key")
+	}
+	createdAddon.KmsCryptoKey = createdCryptoKey
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.Vault.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.Vault.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Vault.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create vault namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	//create the google service account and the kubernetes service account to be used by the vault server
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
+		vars.Vault.KsaName,
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.Vault.KsaName),
+			GsaAccountId:   locals.GsaAccountId(vars.Vault.KsaName),
+			GsaDescription: "vault service account for unsealing vault with the cloud kms key",
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for vault")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export vault gsa email
+	ctx.Export(outputs.VaultGsaEmail, createdWorkloadIdentity.GsaEmail)
+
+	//the seal needs to read the key in addition to encrypting and decrypting with it
+	//https://developer.hashicorp.com/vault/docs/configuration/seal/gcpckms#authentication-permissions
+	keyIamMembers := make([]pulumi.Resource, 0)
+	for _, role := range []string{"roles/cloudkms.cryptoKeyEncrypterDecrypter", "roles/cloudkms.viewer"} {
+		createdKeyIamMember, err := kms.NewCryptoKeyIAMMember(ctx,
+			fmt.Sprintf("%s-%s", vars.Vault.KsaName, strings.TrimPrefix(role, "roles/")),
+			&kms.CryptoKeyIAMMemberArgs{
+				CryptoKeyId: createdCryptoKey.ID(),
+				Member:      pulumi.Sprintf("serviceAccount:%s", createdWorkloadIdentity.GsaEmail),
+				Role:        pulumi.String(role),
+			}, pulumi.Parent(createdCryptoKey))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to grant %s role on the vault unseal key", role)
+		}
+		keyIamMembers = append(keyIamMembers, createdKeyIamMember)
+	}
+
+	var ingress *resolvedIngress
+	if settings.Ingress != nil {
+		ingress, err = resolveIngress(locals, vars.Vault.HostnamePrefix, settings.Ingress)
+		if err != nil {
+			return nil, errors.Wrap(err, "invalid ingress")
+		}
+		createdAddon.Url = pulumi.String(ingress.url()).ToStringOutput()
+	}
+
+	replicas := settings.Replicas
+	if replicas == 0 {
+		replicas = vars.Vault.Replicas
+	}
+	storageSize := settings.StorageSize
+	if storageSize == "" {
+		storageSize = vars.Vault.StorageSize
+	}
+
+	releaseSettings := helmReleaseSettings(locals, vaultAddonName)
+	chartSource := helmChartSource(locals, vaultAddonName, vars.Vault.HelmChartRepo)
+
+	//create helm-release
+	createdHelmRelease, err := helm.NewRelease(ctx, "vault",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.Vault.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.Vault.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, vaultAddonName, vars.Vault.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, vaultAddonName, vars.Vault.HelmChartName, pulumi.Map{
+				"server": pulumi.Map{
+					"serviceAccount": pulumi.Map{
+						"create": pulumi.Bool(false),
+						"name":   pulumi.String(vars.Vault.KsaName),
+					},
+					//uninitialized and sealed replicas are reported ready, see the doc comment of the function
+					"readinessProbe": pulumi.Map{
+						"path": pulumi.String("/v1/sys/health?standbyok=true&sealedcode=204&uninitcode=204"),
+					},
+					"dataStorage": pulumi.Map{
+						"size": pulumi.String(storageSize),
+					},
+					"ha": pulumi.Map{
+						"enabled":  pulumi.Bool(true),
+						"replicas": pulumi.Int(replicas),
+						"raft": pulumi.Map{
+							"enabled":   pulumi.Bool(true),
+							"setNodeId": pulumi.Bool(true),
+							"config": vaultRaftConfig(replicas, createdCluster.Project,
+								locals.GkeCluster.Spec.Region, keyRingName),
+						},
+					},
+				},
+				"ui": pulumi.Map{
+					"enabled": pulumi.Bool(true),
+				},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.DependsOn(append([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount}, keyIamMembers...)),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create vault helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if ingress != nil {
+		createdIngress, err := exposeService(ctx, locals, ingress,
+			&exposedService{
+				addonName: vaultAddonName,
+				namespace: vars.Vault.Namespace,
+				name:      vars.Vault.ActiveServiceName,
+				port:      vars.Vault.Port,
+			}, installedAddons, createdHelmRelease)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to expose vault")
+		}
+		createdAddon.Ingress = createdIngress
+	}
+
+	if installedExternalSecrets, ok := installedAddons[externalSecretsAddonName]; ok {
+		//the kubernetes auth method and the role of external-secrets are configured in vault after its initialization
+		createdClusterSecretStore, err := externalsecretsv1.NewClusterSecretStore(ctx,
+			"vault-cluster-secret-store",
+			&externalsecretsv1.ClusterSecretStoreArgs{
+				Metadata: metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.Vault.ClusterSecretStoreName),
+					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+				},
+				Spec: externalsecretsv1.ClusterSecretStoreSpecArgs{
+					Provider: externalsecretsv1.ClusterSecretStoreSpecProviderArgs{
+						Vault: externalsecretsv1.ClusterSecretStoreSpecProviderVaultArgs{
+							Server: pulumi.String(fmt.Sprintf("http://%s.%s.svc.cluster.local:%d",
+								vars.Vault.ActiveServiceName, vars.Vault.Namespace, vars.Vault.Port)),
+							Path:    pulumi.String(vars.Vault.KvMountPath),
+							Version: pulumi.String("v2"),
+							Auth: externalsecretsv1.ClusterSecretStoreSpecProviderVaultAuthArgs{
+								Kubernetes: externalsecretsv1.ClusterSecretStoreSpecProviderVaultAuthKubernetesArgs{
+									MountPath: pulumi.String(vars.Vault.KubernetesAuthMountPath),
+									Role:      pulumi.String(vars.Vault.ExternalSecretsVaultRole),
+									ServiceAccountRef: externalsecretsv1.ClusterSecretStoreSpecProviderVaultAuthKubernetesServiceAccountRefArgs{
+										Name:      pulumi.String(vars.ExternalSecrets.KsaName),
+										Namespace: pulumi.String(vars.ExternalSecrets.Namespace),
+									},
+								},
+							},
+						},
+					},
+				},
+			}, pulumi.Parent(createdHelmRelease),
+			pulumi.DependsOn([]pulumi.Resource{installedExternalSecrets}))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create vault cluster-secret-store")
+		}
+		createdAddon.ClusterSecretStore = createdClusterSecretStore
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+		"gsaEmail":  createdWorkloadIdentity.GsaEmail,
+		"url":       createdAddon.Url,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register vault addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// vaultRaftConfig returns the configuration of the vault server in ha mode. the replicas join the raft cluster
+// through the headless service of the statefulset, and vault is unsealed with the cloud kms key.
+//
+// tls is not enabled on the listener, the api is only reachable within the cluster and through the ingress.
+func vaultRaftConfig(replicas int, projectId pulumi.StringInput, region, keyRingName string) pulumi.StringOutput {
+	retryJoins := make([]string, 0)
+	for i := 0; i < replicas; i++ {
+		retryJoins = append(retryJoins, fmt.Sprintf(`  retry_join {
+    leader_api_addr = "http://%s-%d.%s-internal:%d"
+  }`, vars.Vault.HelmChartName, i, vars.Vault.HelmChartName, vars.Vault.Port))
+	}
+	return pulumi.Sprintf(`ui = true
+
+listener "tcp" {
+  tls_disable     = 1
+  address         = "[::]:%[1]d"
+  cluster_address = "[::]:%[2]d"
+}
+
+storage "raft" {
+  path = "/vault/data"
+%[3]s
+}
+
+seal "gcpckms" {
+  project    = "%[4]s"
+  region     = "%[5]s"
+  key_ring   = "%[6]s"
+  crypto_key = "%[7]s"
+}
+
+service_registration "kubernetes" {}
+`, vars.Vault.Port, vars.Vault.Port+1, strings.Join(retryJoins, "\n"), projectId, region, keyRingName,
+		vars.Vault.KmsCryptoKeyName)
+}
+
+// vaultKmsImports returns the options which adopt the kms key ring and unseal key retained by a previous
+// installation of the addon, or no options when the import is not configured.
+func vaultKmsImports(settings *options.Vault, projectId, region, keyRingName string) ([]pulumi.ResourceOption,
+	[]pulumi.ResourceOption) {
+	if !settings.ImportKmsKey {
+		return nil, nil
+	}
+	keyRingId := fmt.Sprintf("projects/%s/locations/%s/keyRings/%s", projectId, region, keyRingName)
+	return []pulumi.ResourceOption{pulumi.Import(pulumi.ID(keyRingId))},
+		[]pulumi.ResourceOption{
+			pulumi.Import(pulumi.ID(fmt.Sprintf("%s/cryptoKeys/%s", keyRingId, vars.Vault.KmsCryptoKeyName))),
+		}
+}
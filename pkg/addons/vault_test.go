@@ -0,0 +1,93 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/kms"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVaultKmsImports(t *testing.T) {
+	const keyRingId = "projects/my-project/locations/europe-west1/keyRings/vault-my-cluster"
+	tests := []struct {
+		name            string
+		vault           *options.Vault
+		wantKeyRingId   string
+		wantCryptoKeyId string
+	}{
+		{
+			name:  "created",
+			vault: &options.Vault{},
+		},
+		{
+			//the retained key ring and key are adopted as they can not be created again
+			name:            "retained key imported",
+			vault:           &options.Vault{ImportKmsKey: true},
+			wantKeyRingId:   keyRingId,
+			wantCryptoKeyId: keyRingId + "/cryptoKeys/" + vars.Vault.KmsCryptoKeyName,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := &testMocks{}
+			runWithMocks(t, mocks, func(ctx *pulumi.Context) error {
+				keyRingImport, cryptoKeyImport := vaultKmsImports(tt.vault, "my-project", "europe-west1",
+					"vault-my-cluster")
+				createdKeyRing, err := kms.NewKeyRing(ctx, "vault-my-cluster",
+					&kms.KeyRingArgs{Location: pulumi.String("europe-west1")}, keyRingImport...)
+				if err != nil {
+					return err
+				}
+				_, err = kms.NewCryptoKey(ctx, vars.Vault.KmsCryptoKeyName,
+					&kms.CryptoKeyArgs{KeyRing: createdKeyRing.ID()}, cryptoKeyImport...)
+				return err
+			})
+			if got := mocks.resource(t, "gcp:kms/keyRing:KeyRing", "vault-my-cluster").ID; string(got) !=
+				tt.wantKeyRingId {
+				t.Errorf("key ring import id = %q, want %q", got, tt.wantKeyRingId)
+			}
+			if got := mocks.resource(t, "gcp:kms/cryptoKey:CryptoKey", vars.Vault.KmsCryptoKeyName).ID; string(got) !=
+				tt.wantCryptoKeyId {
+				t.Errorf("crypto key import id = %q, want %q", got, tt.wantCryptoKeyId)
+			}
+		})
+	}
+}
+
+func TestVaultRaftConfig(t *testing.T) {
+	configs := make(chan string, 1)
+	runWithMocks(t, &testMocks{}, func(ctx *pulumi.Context) error {
+		vaultRaftConfig(3, pulumi.String("my-project"), "europe-west1", "vault-my-cluster").ApplyT(
+			func(config string) string {
+				configs <- config
+				return config
+			})
+		return nil
+	})
+	var config string
+	select {
+	case config = <-configs:
+	case <-time.After(10 * time.Second):
+		t.Fatal("vaultRaftConfig() was not resolved")
+	}
+
+	//vault is unsealed with the key of the key ring, and each replica joins the raft cluster
+	for _, want := range []string{
+		`project    = "my-project"`,
+		`region     = "europe-west1"`,
+		`key_ring   = "vault-my-cluster"`,
+		`crypto_key = "` + vars.Vault.KmsCryptoKeyName + `"`,
+		`leader_api_addr = "http://vault-0.vault-internal:8200"`,
+		`leader_api_addr = "http://vault-2.vault-internal:8200"`,
+	} {
+		if !strings.Contains(config, want) {
+			t.Errorf("vaultRaftConfig() = %s, want %s", config, want)
+		}
+	}
+	if strings.Contains(config, "vault-3.vault-internal") {
+		t.Errorf("vaultRaftConfig() = %s, want 3 replicas joined", config)
+	}
+}
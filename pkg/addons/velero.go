@@ -0,0 +1,397 @@
+package addons
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/storage"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/apiextensions"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	"github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/helm/v3"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+const (
+	veleroAddonName = "velero"
+	// VeleroResourceType is the pulumi type token of the velero addon component.
+	VeleroResourceType = "planton:gke:VeleroAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: veleroAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.Options.Velero != nil
+		},
+		Requires:   []Requirement{RequiresCreatedCluster, RequiresGcpProvider, RequiresKubernetesProvider},
+		HelmCharts: []string{vars.Velero.HelmChartName},
+		ProtectedValues: []string{
+			"serviceAccount.server.create",
+			"serviceAccount.server.name",
+			"credentials.useSecret",
+			"configuration.backupStorageLocation",
+			"configuration.volumeSnapshotLocation",
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return Velero(ctx, input.Locals, input.CreatedCluster, input.GcpProvider,
+				input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// VeleroAddon is the component of the velero addon.
+type VeleroAddon struct {
+	pulumi.ResourceState
+
+	GsaEmail   pulumi.StringOutput
+	BucketName pulumi.StringOutput
+
+	// Bucket holds the backups and is retained when the addon is removed, so that the cluster can be restored.
+	Bucket           *storage.Bucket
+	CustomRole       *projects.IAMCustomRole
+	Namespace        *corev1.Namespace
+	WorkloadIdentity *workloadidentity.WorkloadIdentity
+	HelmRelease      *helm.Release
+	// Schedules are keyed by the namespace they back up, or by "*" for the schedule of all namespaces.
+	Schedules map[string]*apiextensions.CustomResource
+}
+
+// Velero installs Velero in the Kubernetes cluster using Helm, backing up the cluster to a cloud storage bucket and
+// the persistent volumes as compute disk snapshots, and creates the schedules of the backups.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster where Velero will be installed.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
+//
+// Returns:
+// - *VeleroAddon: The component of the addon which is the parent of all the resources of the addon.
+// - error: An error object if there is any issue during the installation.
+//
+// The function performs the following steps:
+//  1. Registers the component of the addon.
+//  2. Creates the backup bucket in the project of the cluster with lifecycle rules that delete expired backups.
+//  3. Creates a namespace for Velero and labels it with metadata from locals.
+//  4. Creates the workload identity for Velero, i.e. a Google Service Account (GSA), the Workload Identity binding
+//     and a Kubernetes Service Account (KSA) annotated with the GSA email.
+//  5. Grants the GSA access to the objects of the bucket and a custom role with the disk snapshot permissions.
+//  6. Exports the email of the created GSA and the name of the bucket.
+//  7. Deploys the Velero Helm chart with the gcp plugin into the created namespace.
+//  8. Waits for the CRDs to be established and the server to be available, then creates the schedules.
+func Velero(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*VeleroAddon, error) {
+	settings := locals.Options.Velero
+
+	createdAddon := &VeleroAddon{Schedules: make(map[string]*apiextensions.CustomResource)}
+	err := ctx.RegisterComponentResource(VeleroResourceType, veleroAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Providers(gcpProvider, kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register velero addon component")
+	}
+
+	retentionDays := settings.RetentionDays
+	if retentionDays == 0 {
+		retentionDays = vars.Velero.DefaultRetentionDays
+	}
+	bucketLocation := settings.BucketLocation
+	if bucketLocation == "" {
+		bucketLocation = locals.GkeCluster.Spec.Region
+	}
+
+	//create the backup bucket, velero deletes expired backups itself and the lifecycle rules are a safety net
+	bucketName := locals.BucketName(vars.Velero.BucketNameBase)
+	createdBucket, err := storage.NewBucket(ctx,
+		bucketName,
+		&storage.BucketArgs{
+			Name:                     pulumi.String(bucketName),
+			Project:                  createdCluster.Project,
+			Location:                 pulumi.String(bucketLocation),
+			UniformBucketLevelAccess: pulumi.Bool(true),
+			PublicAccessPrevention:   pulumi.String("enforced"),
+			Labels:                   pulumi.ToStringMap(locals.GcpLabels),
+			LifecycleRules: storage.BucketLifecycleRuleArray{
+				storage.BucketLifecycleRuleArgs{
+					Action: storage.BucketLifecycleRuleActionArgs{
+						Type: pulumi.String("Delete"),
+					},
+					Condition: storage.BucketLifecycleRuleConditionArgs{
+						Age: pulumi.Int(retentionDays + vars.Velero.BucketLifecycleGraceDays),
+					},
+				},
+				storage.BucketLifecycleRuleArgs{
+					Action: storage.BucketLifecycleRuleActionArgs{
+						Type: pulumi.String("AbortIncompleteMultipartUpload"),
+					},
+					Condition: storage.BucketLifecycleRuleConditionArgs{
+						Age: pulumi.Int(1),
+					},
+				},
+			},
+		}, pulumi.Parent(createdAddon), pulumi.RetainOnDelete(true))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create velero backup bucket")
+	}
+	createdAddon.Bucket = createdBucket
+	createdAddon.BucketName = createdBucket.Name
+
+	//create namespace resource
+	createdNamespace, err := corev1.NewNamespace(ctx,
+		vars.Velero.Namespace,
+		&corev1.NamespaceArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:   pulumi.String(vars.Velero.Namespace),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.Velero.Namespace)),
+				}),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to create velero namespace")
+	}
+	createdAddon.Namespace = createdNamespace
+
+	//create the google service account and the kubernetes service account to be used by the velero server
+	createdWorkloadIdentity, err := workloadidentity.New(ctx,
+		vars.Velero.KsaName,
+		&workloadidentity.Args{
+			ProjectId:      createdCluster.Project,
+			Namespace:      createdNamespace.Metadata.Name().Elem(),
+			KsaName:        pulumi.String(vars.Velero.KsaName),
+			GsaAccountId:   locals.GsaAccountId(vars.Velero.KsaName),
+			GsaDescription: "velero service account for backing up the cluster and snapshotting its volumes",
+		}, pulumi.Providers(gcpProvider, kubernetesProvider), pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create workload identity for velero")
+	}
+	createdAddon.WorkloadIdentity = createdWorkloadIdentity
+	createdAddon.GsaEmail = createdWorkloadIdentity.GsaEmail
+
+	//export velero gsa email and bucket name
+	ctx.Export(outputs.VeleroGsaEmail, createdWorkloadIdentity.GsaEmail)
+	ctx.Export(outputs.VeleroBucketName, createdBucket.Name)
+
+	//grant access to the objects of the backup bucket only
+	createdBucketIamMember, err := storage.NewBucketIAMMember(ctx,
+		fmt.Sprintf("%s-object-admin", vars.Velero.KsaName),
+		&storage.BucketIAMMemberArgs{
+			Bucket: createdBucket.Name,
+			Member: pulumi.Sprintf("serviceAccount:%s", createdWorkloadIdentity.GsaEmail),
+			Role:   pulumi.String("roles/storage.objectAdmin"),
+		}, pulumi.Parent(createdBucket))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to grant velero access to the backup bucket")
+	}
+
+	//https://github.com/vmware-tanzu/velero-plugin-for-gcp#create-custom-role-with-permissions-for-the-velero-gsa
+	createdCustomRole, err := projects.NewIAMCustomRole(ctx,
+		"velero-server-role",
+		&projects.IAMCustomRoleArgs{
+			Description: pulumi.String("This role allows velero to snapshot the persistent disks of the cluster " +
+				"and to sign the urls used to download backups."),
+			Project: createdCluster.Project,
+			Permissions: pulumi.StringArray{
+				pulumi.String("compute.disks.get"),
+				pulumi.String("compute.disks.create"),
+				pulumi.String("compute.disks.createSnapshot"),
+				pulumi.String("compute.projects.get"),
+				pulumi.String("compute.snapshots.get"),
+				pulumi.String("compute.snapshots.create"),
+				pulumi.String("compute.snapshots.useReadOnly"),
+				pulumi.String("compute.snapshots.delete"),
+				pulumi.String("compute.zones.get"),
+				pulumi.String("iam.serviceAccounts.signBlob"),
+			},
+			RoleId: pulumi.String(locals.CustomRoleId(vars.Velero.CustomRoleId)),
+			Title:  pulumi.String("Velero Server"),
+		}, pulumi.Parent(createdAddon))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create custom-iam role for velero")
+	}
+	createdAddon.CustomRole = createdCustomRole
+
+	createdCustomRoleIamMember, err := projects.NewIAMMember(ctx,
+		fmt.Sprintf("%s-server-role", vars.Velero.KsaName),
+		&projects.IAMMemberArgs{
+			Member:  pulumi.Sprintf("serviceAccount:%s", createdWorkloadIdentity.GsaEmail),
+			Project: createdCluster.Project,
+			Role:    createdCustomRole.Name,
+		}, pulumi.Parent(createdCustomRole))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to grant velero custom role")
+	}
+
+	releaseSettings := helmReleaseSettings(locals, veleroAddonName)
+	chartSource := helmChartSource(locals, veleroAddonName, vars.Velero.HelmChartRepo)
+
+	//the gcp plugin is installed by an init container into the plugins volume of the server
+	//https://github.com/vmware-tanzu/velero-plugin-for-gcp#install-and-start-velero
+	createdHelmRelease, err := helm.NewRelease(ctx, "velero",
+		&helm.ReleaseArgs{
+			Name:            pulumi.String(vars.Velero.HelmChartName),
+			Namespace:       createdNamespace.Metadata.Name(),
+			Chart:           chartSource.chart(vars.Velero.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, veleroAddonName, vars.Velero.HelmChartVersion)),
+			CreateNamespace: pulumi.Bool(false),
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, veleroAddonName, vars.Velero.HelmChartName, pulumi.Map{
+				"initContainers": pulumi.Array{
+					pulumi.Map{
+						"name":  pulumi.String("velero-plugin-for-gcp"),
+						"image": pulumi.String(mirroredImage(locals, vars.Velero.GcpPluginImage)),
+						"volumeMounts": pulumi.Array{
+							pulumi.Map{
+								"mountPath": pulumi.String("/target"),
+								"name":      pulumi.String("plugins"),
+							},
+						},
+					},
+				},
+				"configuration": pulumi.Map{
+					"backupStorageLocation": pulumi.Array{
+						pulumi.Map{
+							"name":     pulumi.String(vars.Velero.LocationName),
+							"provider": pulumi.String("gcp"),
+							"bucket":   createdBucket.Name,
+							"default":  pulumi.Bool(true),
+							//needed to sign the download urls of backups with workload identity
+							"config": pulumi.Map{
+								"serviceAccount": createdWorkloadIdentity.GsaEmail,
+							},
+						},
+					},
+					"volumeSnapshotLocation": pulumi.Array{
+						pulumi.Map{
+							"name":     pulumi.String(vars.Velero.LocationName),
+							"provider": pulumi.String("gcp"),
+							"config": pulumi.Map{
+								"project": createdCluster.Project,
+							},
+						},
+					},
+				},
+				"credentials": pulumi.Map{
+					"useSecret": pulumi.Bool(false),
+				},
+				"serviceAccount": pulumi.Map{
+					"server": pulumi.Map{
+						"create": pulumi.Bool(false),
+						"name":   pulumi.String(vars.Velero.KsaName),
+					},
+				},
+				"snapshotsEnabled": pulumi.Bool(true),
+				"deployNodeAgent":  pulumi.Bool(false),
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
+		}, pulumi.Parent(createdNamespace),
+		pulumi.DependsOn([]pulumi.Resource{createdWorkloadIdentity.KubernetesServiceAccount,
+			createdBucketIamMember, createdCustomRoleIamMember}),
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create velero helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	createdReadinessJob, err := waitForReadiness(ctx, locals,
+		&readinessGate{
+			addonName:   veleroAddonName,
+			namespace:   vars.Velero.Namespace,
+			crds:        []string{"schedules.velero.io", "backupstoragelocations.velero.io"},
+			deployments: []string{vars.Velero.HelmChartName},
+		}, createdHelmRelease)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to wait for velero readiness")
+	}
+
+	for _, schedule := range veleroSchedules(settings, retentionDays) {
+		createdSchedule, err := apiextensions.NewCustomResource(ctx,
+			fmt.Sprintf("velero-%s", schedule.name),
+			&apiextensions.CustomResourceArgs{
+				ApiVersion: pulumi.String("velero.io/v1"),
+				Kind:       pulumi.String("Schedule"),
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:      pulumi.String(schedule.name),
+						Namespace: createdNamespace.Metadata.Name(),
+						Labels:    pulumi.ToStringMap(locals.KubernetesLabels),
+					}),
+				OtherFields: pulumikubernetes.UntypedArgs{
+					"spec": schedule.spec,
+				},
+			}, pulumi.Parent(createdHelmRelease),
+			pulumi.DependsOn([]pulumi.Resource{createdReadinessJob}))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to create velero schedule for %s namespace", schedule.namespace)
+		}
+		createdAddon.Schedules[schedule.namespace] = createdSchedule
+	}
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace":  createdNamespace.Metadata.Name(),
+		"gsaEmail":   createdWorkloadIdentity.GsaEmail,
+		"bucketName": createdBucket.Name,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register velero addon outputs")
+	}
+	return createdAddon, nil
+}
+
+// veleroSchedule is the backup schedule of a namespace, or of all namespaces when the namespace is "*".
+type veleroSchedule struct {
+	name      string
+	namespace string
+	spec      pulumi.Map
+}
+
+// veleroSchedules returns one schedule per namespace, so that the backups of a namespace can be restored on their
+// own, or a single schedule of all namespaces when no namespaces are configured. backups expire after the retention
+// days.
+func veleroSchedules(settings *options.Velero, retentionDays int) []*veleroSchedule {
+	scheduledNamespaces := settings.Namespaces
+	if len(scheduledNamespaces) == 0 {
+		scheduledNamespaces = []string{"*"}
+	}
+	cron := settings.Schedule
+	if cron == "" {
+		cron = vars.Velero.DefaultSchedule
+	}
+	schedules := make([]*veleroSchedule, 0)
+	for _, namespace := range scheduledNamespaces {
+		name := fmt.Sprintf("namespace-%s", namespace)
+		if namespace == "*" {
+			name = "all-namespaces"
+		}
+		schedules = append(schedules, &veleroSchedule{
+			name:      name,
+			namespace: namespace,
+			spec: pulumi.Map{
+				"schedule": pulumi.String(cron),
+				"template": pulumi.Map{
+					"includedNamespaces":      pulumi.ToStringArray([]string{namespace}),
+					"ttl":                     pulumi.String(fmt.Sprintf("%dh0m0s", retentionDays*24)),
+					"snapshotVolumes":         pulumi.Bool(true),
+					"storageLocation":         pulumi.String(vars.Velero.LocationName),
+					"volumeSnapshotLocations": pulumi.ToStringArray([]string{vars.Velero.LocationName}),
+				},
+			},
+		})
+	}
+	return schedules
+}
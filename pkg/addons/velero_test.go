@@ -0,0 +1,55 @@
+package addons
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"testing"
+)
+
+func TestVeleroSchedules(t *testing.T) {
+	spec := func(cron, namespace, ttl string) pulumi.Map {
+		return pulumi.Map{
+			"schedule": pulumi.String(cron),
+			"template": pulumi.Map{
+				"includedNamespaces":      pulumi.ToStringArray([]string{namespace}),
+				"ttl":                     pulumi.String(ttl),
+				"snapshotVolumes":         pulumi.Bool(true),
+				"storageLocation":         pulumi.String(vars.Velero.LocationName),
+				"volumeSnapshotLocations": pulumi.ToStringArray([]string{vars.Velero.LocationName}),
+			},
+		}
+	}
+	tests := []struct {
+		name          string
+		settings      *options.Velero
+		retentionDays int
+		want          []*veleroSchedule
+	}{
+		{
+			name:          "all namespaces on the default schedule",
+			settings:      &options.Velero{},
+			retentionDays: 7,
+			want: []*veleroSchedule{
+				{name: "all-namespaces", namespace: "*", spec: spec(vars.Velero.DefaultSchedule, "*", "168h0m0s")},
+			},
+		},
+		{
+			name:          "one schedule per namespace",
+			settings:      &options.Velero{Schedule: "0 3 * * *", Namespaces: []string{"orders", "billing"}},
+			retentionDays: 30,
+			want: []*veleroSchedule{
+				{name: "namespace-orders", namespace: "orders", spec: spec("0 3 * * *", "orders", "720h0m0s")},
+				{name: "namespace-billing", namespace: "billing", spec: spec("0 3 * * *", "billing", "720h0m0s")},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := veleroSchedules(tt.settings, tt.retentionDays); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("veleroSchedules() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -12,6 +12,40 @@ import (
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
 )
 
+const (
+	zalandoPostgresOperatorAddonName = "zalando-postgres-operator"
+	// ZalandoPostgresOperatorResourceType is the pulumi type token of the zalando-postgres-operator addon component.
+	ZalandoPostgresOperatorResourceType = "planton:gke:ZalandoPostgresOperatorAddon"
+)
+
+func init() {
+	register(&Addon{
+		Name: zalandoPostgresOperatorAddonName,
+		IsEnabled: func(locals *localz.Locals) bool {
+			return locals.GkeCluster.Spec.GetKubernetesAddons().GetIsInstallPostgresOperator()
+		},
+		Requires:   []Requirement{RequiresKubernetesProvider},
+		HelmCharts: []string{vars.ZalandoPostgresOperator.HelmChartName},
+		Removal: &Removal{
+			Crds: []string{
+				"postgresqls.acid.zalan.do",
+				"postgresteams.acid.zalan.do",
+			},
+		},
+		Install: func(ctx *pulumi.Context, input *Input) (pulumi.Resource, error) {
+			return ZalandoPostgresOperator(ctx, input.Locals, input.KubernetesProvider, input.opts...)
+		},
+	})
+}
+
+// ZalandoPostgresOperatorAddon is the component of the zalando-postgres-operator addon.
+type ZalandoPostgresOperatorAddon struct {
+	pulumi.ResourceState
+
+	Namespace   *corev1.Namespace
+	HelmRelease *helm.Release
+}
+
 // ZalandoPostgresOperator installs the Zalando Postgres Operator in the Kubernetes cluster using Helm.
 // It creates the necessary namespace and deploys the Helm chart with specific values.
 //
@@ -19,17 +53,26 @@ import (
 // - ctx: The Pulumi context used for defining cloud resources.
 // - locals: A struct containing local configuration and metadata.
 // - kubernetesProvider: The Kubernetes provider for Pulumi.
+// - opts: Options for the addon component, ex: transformations.
 //
 // Returns:
+// - *ZalandoPostgresOperatorAddon: The component of the addon which is the parent of all the resources of the addon.
 // - error: An error object if there is any issue during the installation.
 //
 // The function performs the following steps:
-// 1. Creates a namespace for the Zalando Postgres Operator and labels it with metadata from locals.
-// 2. Deploys the Zalando Postgres Operator Helm chart into the created namespace with specific inherited labels and other configurations.
-// 3. Uses Helm chart repository and version specified in the vars package.
-// 4. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
+// 1. Registers the component of the addon.
+// 2. Creates a namespace for the Zalando Postgres Operator and labels it with metadata from locals.
+// 3. Deploys the Zalando Postgres Operator Helm chart into the created namespace with specific inherited labels and other configurations.
+// 4. Uses Helm chart repository and version specified in the vars package.
+// 5. Handles errors and returns any errors encountered during the namespace creation or Helm release deployment.
 func ZalandoPostgresOperator(ctx *pulumi.Context, locals *localz.Locals,
-	kubernetesProvider *pulumikubernetes.Provider) error {
+	kubernetesProvider *pulumikubernetes.Provider, opts ...pulumi.ResourceOption) (*ZalandoPostgresOperatorAddon, error) {
+	createdAddon := &ZalandoPostgresOperatorAddon{}
+	err := ctx.RegisterComponentResource(ZalandoPostgresOperatorResourceType, zalandoPostgresOperatorAddonName, createdAddon,
+		append([]pulumi.ResourceOption{pulumi.Provider(kubernetesProvider)}, opts...)...)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to register zalando-postgres-operator addon component")
+	}
 	//create namespace resource
 	createdNamespace, err := corev1.NewNamespace(ctx,
 		vars.ZalandoPostgresOperator.Namespace,
@@ -37,27 +80,32 @@ func ZalandoPostgresOperator(ctx *pulumi.Context, locals *localz.Locals,
 			Metadata: metav1.ObjectMetaPtrInput(
 				&metav1.ObjectMetaArgs{
 					Name:   pulumi.String(vars.ZalandoPostgresOperator.Namespace),
-					Labels: pulumi.ToStringMap(locals.KubernetesLabels),
+					Labels: pulumi.ToStringMap(locals.NamespaceLabels(vars.ZalandoPostgresOperator.Namespace)),
 				}),
 		},
-		pulumi.Provider(kubernetesProvider))
+		pulumi.Provider(kubernetesProvider), pulumi.Parent(createdAddon), movedFromRoot())
 	if err != nil {
-		return errors.Wrapf(err, "failed to create namespace")
+		return nil, errors.Wrapf(err, "failed to create namespace")
 	}
+	createdAddon.Namespace = createdNamespace
+
+	releaseSettings := helmReleaseSettings(locals, zalandoPostgresOperatorAddonName)
+	chartSource := helmChartSource(locals, zalandoPostgresOperatorAddonName, vars.ZalandoPostgresOperator.HelmChartRepo)
 
 	//create helm-release
-	_, err = helm.NewRelease(ctx, "zalando-postgres-operator",
+	createdHelmRelease, err := helm.NewRelease(ctx, "zalando-postgres-operator",
 		&helm.ReleaseArgs{
 			Name:            pulumi.String(vars.ZalandoPostgresOperator.HelmChartName),
 			Namespace:       createdNamespace.Metadata.Name(),
-			Chart:           pulumi.String(vars.ZalandoPostgresOperator.HelmChartName),
-			Version:         pulumi.String(vars.ZalandoPostgresOperator.HelmChartVersion),
+			Chart:           chartSource.chart(vars.ZalandoPostgresOperator.HelmChartName),
+			Version:         pulumi.String(chartVersion(locals, zalandoPostgresOperatorAddonName, vars.ZalandoPostgresOperator.HelmChartVersion)),
 			CreateNamespace: pulumi.Bool(false),
-			Atomic:          pulumi.Bool(false),
-			CleanupOnFail:   pulumi.Bool(true),
-			WaitForJobs:     pulumi.Bool(true),
-			Timeout:         pulumi.Int(180),
-			Values: pulumi.Map{
+			Atomic:          pulumi.Bool(releaseSettings.atomic),
+			CleanupOnFail:   pulumi.Bool(releaseSettings.cleanupOnFail),
+			WaitForJobs:     pulumi.Bool(releaseSettings.waitForJobs),
+			SkipAwait:       releaseSettings.skipAwait(),
+			Timeout:         pulumi.Int(releaseSettings.timeoutSeconds),
+			Values: helmValues(locals, zalandoPostgresOperatorAddonName, vars.ZalandoPostgresOperator.HelmChartName, pulumi.Map{
 				"configKubernetes": pulumi.Map{
 					"inherited_labels": pulumi.ToStringArray(
 						[]string{
@@ -69,14 +117,20 @@ func ZalandoPostgresOperator(ctx *pulumi.Context, locals *localz.Locals,
 						},
 					),
 				},
-			},
-			RepositoryOpts: helm.RepositoryOptsArgs{
-				Repo: pulumi.String(vars.ZalandoPostgresOperator.HelmChartRepo),
-			},
+			}),
+			RepositoryOpts: chartSource.repositoryOpts(),
 		}, pulumi.Parent(createdNamespace),
-		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}))
+		pulumi.IgnoreChanges([]string{"status", "description", "resourceNames"}),
+		releaseSettings.customTimeouts())
 	if err != nil {
-		return errors.Wrap(err, "failed to create helm release")
+		return nil, errors.Wrap(err, "failed to create helm release")
+	}
+	createdAddon.HelmRelease = createdHelmRelease
+
+	if err := ctx.RegisterResourceOutputs(createdAddon, pulumi.Map{
+		"namespace": createdNamespace.Metadata.Name(),
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register zalando-postgres-operator addon outputs")
 	}
-	return nil
+	return createdAddon, nil
 }
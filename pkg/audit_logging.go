@@ -0,0 +1,65 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// auditLogging configures data access audit logs in the container cluster project so that access to the
+// kubernetes api and secret manager is logged.
+//
+// note: the audit config of a service is authoritative for the project. when the project is shared by more than
+// one cluster, the audit logging option should be enabled for only one of them.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster whose project is configured.
+// - gcpProvider: The GCP provider for Pulumi.
+//
+// Returns:
+// - error: An error object if there is any issue during the creation of the audit configs.
+//
+// The function performs the following steps:
+// 1. Resolves the services and log types from the auditLogging option, falling back to the defaults in vars.
+// 2. Creates an IAM audit config for each service with the log types and the exempted members.
+func auditLogging(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster, gcpProvider *gcp.Provider) error {
+	services := vars.AuditLogging.Services
+	if len(locals.Options.AuditLogging.Services) > 0 {
+		services = locals.Options.AuditLogging.Services
+	}
+
+	logTypes := vars.AuditLogging.LogTypes
+	if len(locals.Options.AuditLogging.LogTypes) > 0 {
+		logTypes = locals.Options.AuditLogging.LogTypes
+	}
+
+	auditLogConfigs := projects.IAMAuditConfigAuditLogConfigArray{}
+	for _, logType := range logTypes {
+		auditLogConfigs = append(auditLogConfigs, projects.IAMAuditConfigAuditLogConfigArgs{
+			LogType:         pulumi.String(logType),
+			ExemptedMembers: pulumi.ToStringArray(locals.Options.AuditLogging.ExemptedMembers),
+		})
+	}
+
+	for _, service := range services {
+		_, err := projects.NewIAMAuditConfig(ctx,
+			fmt.Sprintf("audit-config-%s", service),
+			&projects.IAMAuditConfigArgs{
+				Project:         createdCluster.Project,
+				Service:         pulumi.String(service),
+				AuditLogConfigs: auditLogConfigs,
+			}, pulumi.Parent(createdCluster), pulumi.Provider(gcpProvider))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create audit config for %s", service)
+		}
+	}
+	return nil
+}
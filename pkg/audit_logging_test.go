@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	"github.com/pulumi/pulumi/sdk/v3/go/common/resource"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// auditConfigType is the type token of the iam audit configs created for the services.
+const auditConfigType = "gcp:projects/iAMAuditConfig:IAMAuditConfig"
+
+// auditConfigMocks records the audit configs registered by the program.
+type auditConfigMocks struct {
+	mu           sync.Mutex
+	auditConfigs map[string]resource.PropertyMap
+}
+
+func (m *auditConfigMocks) NewResource(args pulumi.MockResourceArgs) (string, resource.PropertyMap, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if args.TypeToken == auditConfigType {
+		m.auditConfigs[args.Inputs["service"].StringValue()] = args.Inputs
+	}
+	return args.Name + "-id", args.Inputs, nil
+}
+
+func (m *auditConfigMocks) Call(args pulumi.MockCallArgs) (resource.PropertyMap, error) {
+	return resource.PropertyMap{}, nil
+}
+
+func TestAuditLogging(t *testing.T) {
+	tests := []struct {
+		name         string
+		auditLogging *options.AuditLogging
+		wantServices []string
+		wantLogTypes []string
+		wantExempted []string
+	}{
+		{
+			name:         "default services and log types",
+			auditLogging: &options.AuditLogging{IsEnabled: true},
+			wantServices: vars.AuditLogging.Services,
+			wantLogTypes: vars.AuditLogging.LogTypes,
+			wantExempted: []string{},
+		},
+		{
+			name: "configured services, log types and exempted members",
+			auditLogging: &options.AuditLogging{
+				IsEnabled:       true,
+				Services:        []string{"storage.googleapis.com"},
+				LogTypes:        []string{"DATA_WRITE"},
+				ExemptedMembers: []string{"serviceAccount:ci@example-project.iam.gserviceaccount.com"},
+			},
+			wantServices: []string{"storage.googleapis.com"},
+			wantLogTypes: []string{"DATA_WRITE"},
+			wantExempted: []string{"serviceAccount:ci@example-project.iam.gserviceaccount.com"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mocks := &auditConfigMocks{auditConfigs: make(map[string]resource.PropertyMap)}
+			err := pulumi.RunErr(func(ctx *pulumi.Context) error {
+				gcpProvider, err := gcp.NewProvider(ctx, "gcp", &gcp.ProviderArgs{})
+				if err != nil {
+					return err
+				}
+				createdCluster, err := container.NewCluster(ctx, "cluster",
+					&container.ClusterArgs{Project: pulumi.String("cluster-project")})
+				if err != nil {
+					return err
+				}
+				locals := &localz.Locals{Options: &options.Options{AuditLogging: tt.auditLogging}}
+				return auditLogging(ctx, locals, createdCluster, gcpProvider)
+			}, pulumi.WithMocks("project", "stack", mocks))
+			if err != nil {
+				t.Fatalf("auditLogging() error = %v", err)
+			}
+
+			if len(mocks.auditConfigs) != len(tt.wantServices) {
+				t.Errorf("auditLogging() created audit configs for %d services, want %v", len(mocks.auditConfigs),
+					tt.wantServices)
+			}
+			for _, service := range tt.wantServices {
+				auditConfig, ok := mocks.auditConfigs[service]
+				if !ok {
+					t.Errorf("auditLogging() created no audit config for %s", service)
+					continue
+				}
+				if project := auditConfig["project"].StringValue(); project != "cluster-project" {
+					t.Errorf("audit config of %s is in project %q, want the cluster project", service, project)
+				}
+				logTypes := make([]string, 0)
+				for _, logConfig := range auditConfig["auditLogConfigs"].ArrayValue() {
+					logTypes = append(logTypes, logConfig.ObjectValue()["logType"].StringValue())
+					exempted := make([]string, 0)
+					for _, member := range logConfig.ObjectValue()["exemptedMembers"].ArrayValue() {
+						exempted = append(exempted, member.StringValue())
+					}
+					if !reflect.DeepEqual(exempted, tt.wantExempted) {
+						t.Errorf("exempted members of %s = %v, want %v", service, exempted, tt.wantExempted)
+					}
+				}
+				if !reflect.DeepEqual(logTypes, tt.wantLogTypes) {
+					t.Errorf("log types of %s = %v, want %v", service, logTypes, tt.wantLogTypes)
+				}
+			}
+		})
+	}
+}
@@ -34,7 +34,8 @@ import (
 //  5. Creates the VPC network, subnetwork, firewall rules, and router.
 //  6. Configures NAT for the router with an external IP address.
 //  7. Creates shared VPC IAM resources if shared VPC is enabled.
-//  8. Configures the cluster with autoscaling, network policies, logging, and other settings.
+//  8. Configures the cluster with autoscaling, network policies, logging, managed prometheus when enabled in the
+//     monitoring options, and other settings.
 //  9. Exports important attributes of the created resources, such as network self-link, subnetwork self-link,
 //     firewall self-link, router self-link, NAT IP address, and cluster name.
 func cluster(ctx *pulumi.Context, locals *localz.Locals, gcpProvider *gcp.Provider) (*container.Cluster, error) {
@@ -204,6 +205,15 @@ func cluster(ctx *pulumi.Context, locals *localz.Locals, gcpProvider *gcp.Provid
 		}
 	}
 
+	//google cloud managed service for prometheus collects the metrics of the addons with the monitoring addon.
+	//it is always set explicitly, as gke enables it by default on new clusters.
+	monitoringConfigArgs := &container.ClusterMonitoringConfigArgs{
+		EnableComponents: pulumi.ToStringArray([]string{"SYSTEM_COMPONENTS"}),
+		ManagedPrometheus: &container.ClusterMonitoringConfigManagedPrometheusArgs{
+			Enabled: pulumi.Bool(locals.Options.Monitoring.IsManagedPrometheus()),
+		},
+	}
+
 	//create container cluster
 	createdCluster, err := container.NewCluster(ctx,
 		"cluster",
@@ -240,6 +250,10 @@ func cluster(ctx *pulumi.Context, locals *localz.Locals, gcpProvider *gcp.Provid
 				NetworkPolicyConfig: container.ClusterAddonsConfigNetworkPolicyConfigPtrInput(
 					&container.ClusterAddonsConfigNetworkPolicyConfigArgs{
 						Disabled: pulumi.Bool(true)}),
+				//the config-connector resource of the addon is created by the config-connector addon of the module
+				ConfigConnectorConfig: container.ClusterAddonsConfigConfigConnectorConfigPtrInput(
+					&container.ClusterAddonsConfigConfigConnectorConfigArgs{
+						Enabled: pulumi.Bool(locals.Options.ConfigConnector != nil)}),
 			}),
 			PrivateClusterConfig: container.ClusterPrivateClusterConfigPtrInput(&container.ClusterPrivateClusterConfigArgs{
 				EnablePrivateEndpoint: pulumi.Bool(false),
@@ -272,6 +286,7 @@ func cluster(ctx *pulumi.Context, locals *localz.Locals, gcpProvider *gcp.Provid
 				&container.ClusterLoggingConfigArgs{
 					EnableComponents: pulumi.ToStringArray(locals.ContainerClusterLoggingComponentList),
 				}),
+			MonitoringConfig: monitoringConfigArgs,
 		},
 		pulumi.Provider(gcpProvider))
 	if err != nil {
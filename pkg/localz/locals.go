@@ -5,6 +5,9 @@ import (
 	gcpcredentialv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/credential/gcpcredential/v1"
 	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
 	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
 	"github.com/plantoncloud/pulumi-module-golang-commons/pkg/provider/gcp/gcplabelkeys"
 	"github.com/plantoncloud/pulumi-module-golang-commons/pkg/provider/kubernetes/kuberneteslabelkeys"
 	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
@@ -20,13 +23,23 @@ type Locals struct {
 	GcpLabels                             map[string]string
 	ContainerClusterLoggingComponentList  []string
 	NetworkTag                            string
+	Options                               *options.Options
+	//enforce pod-security level per namespace, namespaces not in the map get the default level
+	PodSecurityLevels       map[string]string
+	DefaultPodSecurityLevel string
 }
 
-func Initialize(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInput) *Locals {
+func Initialize(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInput) (*Locals, error) {
 	gkeCluster := stackInput.Target
 
 	locals := &Locals{}
 
+	moduleOptions, err := options.Load(ctx)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to load options")
+	}
+	locals.Options = moduleOptions
+
 	locals.GcpCredentialSpec = stackInput.GcpCredential
 	locals.GkeCluster = stackInput.Target
 
@@ -61,5 +74,38 @@ func Initialize(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInp
 			"WORKLOADS")
 	}
 
-	return locals
+	locals.DefaultPodSecurityLevel = vars.PodSecurity.DefaultLevel
+	locals.PodSecurityLevels = make(map[string]string)
+	for namespace, level := range vars.PodSecurity.NamespaceLevels {
+		locals.PodSecurityLevels[namespace] = level
+	}
+	if locals.Options.PodSecurity != nil {
+		if locals.Options.PodSecurity.DefaultLevel != "" {
+			locals.DefaultPodSecurityLevel = locals.Options.PodSecurity.DefaultLevel
+		}
+		for namespace, level := range locals.Options.PodSecurity.NamespaceLevels {
+			locals.PodSecurityLevels[namespace] = level
+		}
+	}
+
+	return locals, nil
+}
+
+// NamespaceLabels returns the kubernetes labels for a namespace created by the module along with the
+// pod-security-admission labels for the enforce level configured for the namespace.
+func (l *Locals) NamespaceLabels(namespace string) map[string]string {
+	labels := make(map[string]string)
+	for k, v := range l.KubernetesLabels {
+		labels[k] = v
+	}
+
+	enforceLevel, ok := l.PodSecurityLevels[namespace]
+	if !ok {
+		enforceLevel = l.DefaultPodSecurityLevel
+	}
+
+	labels[fmt.Sprintf("%s/enforce", vars.PodSecurity.LabelKeyPrefix)] = enforceLevel
+	labels[fmt.Sprintf("%s/audit", vars.PodSecurity.LabelKeyPrefix)] = vars.PodSecurity.AuditLevel
+	labels[fmt.Sprintf("%s/warn", vars.PodSecurity.LabelKeyPrefix)] = vars.PodSecurity.WarnLevel
+	return labels
 }
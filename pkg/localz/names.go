@@ -0,0 +1,104 @@
+package localz
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+const (
+	// gsaAccountIdMaxLength is the maximum length of the account id of a google service account.
+	gsaAccountIdMaxLength = 30
+	// customRoleIdMaxLength is the maximum length of the role id of a project level custom iam role.
+	customRoleIdMaxLength = 64
+	// computeAddressNameMaxLength is the maximum length of the name of a compute address.
+	computeAddressNameMaxLength = 63
+	// bucketNameMaxLength is the maximum length of the name of a cloud storage bucket without dots.
+	bucketNameMaxLength = 63
+	// kmsKeyRingNameMaxLength is the maximum length of the name of a cloud kms key ring.
+	kmsKeyRingNameMaxLength = 63
+	// clusterNameHashLength is the number of hex characters of the cluster name hash used when the
+	//cluster name itself does not fit into the resource name.
+	clusterNameHashLength = 6
+)
+
+// clusterScopedName folds the cluster name into the base name so that resources which are unique per project
+// do not collide when more than one cluster is created in the same project.
+//
+// the name is "<base><separator><cluster-name>" when it fits into maxLength. otherwise the cluster name is replaced
+// with a short hash of it and the base is trimmed as needed, which keeps the name deterministic for a given cluster.
+func clusterScopedName(base, separator, clusterName string, maxLength int) string {
+	name := fmt.Sprintf("%s%s%s", base, separator, clusterName)
+	if len(name) <= maxLength {
+		return name
+	}
+
+	hash := clusterNameHash(clusterName)
+	if len(base)+len(separator)+len(hash) > maxLength {
+		base = strings.TrimRight(base[:maxLength-len(separator)-len(hash)], "-_.")
+	}
+	return fmt.Sprintf("%s%s%s", base, separator, hash)
+}
+
+// clusterNameHash returns a short hex encoded sha256 hash of the cluster name.
+func clusterNameHash(clusterName string) string {
+	sum := sha256.Sum256([]byte(clusterName))
+	return hex.EncodeToString(sum[:])[:clusterNameHashLength]
+}
+
+// GsaAccountId returns the cluster scoped account id for a google service account created by the module.
+// the account id is always within the 6-30 character limit enforced by gcp.
+func (l *Locals) GsaAccountId(base string) string {
+	return clusterScopedName(base, "-", l.GkeCluster.Metadata.Name, gsaAccountIdMaxLength)
+}
+
+// LegacyGsaAccountId returns the account id of a google service account that the module used to create with a fixed
+// account id. the fixed account id is kept, so that the service account of an existing stack is not replaced, unless
+// cluster scoped naming is enabled in the module options.
+func (l *Locals) LegacyGsaAccountId(accountId string) string {
+	if !l.Options.Naming.IsClusterScoped() {
+		return accountId
+	}
+	return l.GsaAccountId(accountId)
+}
+
+// CustomRoleId returns the cluster scoped role id for a project level custom iam role created by the module.
+// role ids only allow letters, numbers, periods and underscores, so hyphens in the cluster name are replaced.
+func (l *Locals) CustomRoleId(base string) string {
+	return clusterScopedName(base, ".", strings.ReplaceAll(l.GkeCluster.Metadata.Name, "-", "_"),
+		customRoleIdMaxLength)
+}
+
+// LegacyCustomRoleId returns the role id of a custom iam role that the module used to create with a fixed role id.
+// the fixed role id is kept unless cluster scoped naming is enabled in the module options.
+func (l *Locals) LegacyCustomRoleId(roleId string) string {
+	if !l.Options.Naming.IsClusterScoped() {
+		return roleId
+	}
+	return l.CustomRoleId(roleId)
+}
+
+// ComputeAddressName returns the name of a compute address created by the module as "gke-<cluster-name>-<suffix>".
+// the cluster name is replaced with a short hash of it when the name does not fit into the limit.
+func (l *Locals) ComputeAddressName(suffix string) string {
+	name := fmt.Sprintf("gke-%s-%s", l.GkeCluster.Metadata.Name, suffix)
+	if len(name) <= computeAddressNameMaxLength {
+		return name
+	}
+	return fmt.Sprintf("gke-%s-%s", clusterNameHash(l.GkeCluster.Metadata.Name), suffix)
+}
+
+// BucketName returns the cluster scoped name for a cloud storage bucket created by the module as
+// "<base>-<project-id>-<cluster-name>". bucket names are globally unique, so the project id of the cluster is part of
+// the name as well.
+func (l *Locals) BucketName(base string) string {
+	return clusterScopedName(fmt.Sprintf("%s-%s", base, l.GkeCluster.Spec.ClusterProjectId), "-",
+		l.GkeCluster.Metadata.Name, bucketNameMaxLength)
+}
+
+// KmsKeyRingName returns the cluster scoped name for a cloud kms key ring created by the module. key rings can not be
+// deleted, so the name of a key ring can not be reused for another one in the same project and location.
+func (l *Locals) KmsKeyRingName(base string) string {
+	return clusterScopedName(base, "-", l.GkeCluster.Metadata.Name, kmsKeyRingNameMaxLength)
+}
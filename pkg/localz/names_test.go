@@ -0,0 +1,85 @@
+package localz
+
+import (
+	"testing"
+)
+
+func TestClusterScopedName(t *testing.T) {
+	tests := []struct {
+		name        string
+		base        string
+		separator   string
+		clusterName string
+		maxLength   int
+		want        string
+	}{
+		{
+			name:        "cluster name fits",
+			base:        "cert-manager",
+			separator:   "-",
+			clusterName: "prod-us-central1",
+			maxLength:   gsaAccountIdMaxLength,
+			want:        "cert-manager-prod-us-central1",
+		},
+		{
+			name:        "cluster name fits exactly",
+			base:        "cert-manager1",
+			separator:   "-",
+			clusterName: "prod-us-central1",
+			maxLength:   gsaAccountIdMaxLength,
+			want:        "cert-manager1-prod-us-central1",
+		},
+		{
+			name:        "cluster name replaced with hash",
+			base:        "external-dns",
+			separator:   "-",
+			clusterName: "a-very-long-cluster-name-for-production",
+			maxLength:   gsaAccountIdMaxLength,
+			want:        "external-dns-40a82c",
+		},
+		{
+			name:        "base trimmed without trailing separator",
+			base:        "abcdefghijklmnopqrstuv-wxyz",
+			separator:   "-",
+			clusterName: "prod-us-central1",
+			maxLength:   gsaAccountIdMaxLength,
+			want:        "abcdefghijklmnopqrstuv-186cb3",
+		},
+		{
+			name:        "custom role id separator",
+			base:        "network.admin",
+			separator:   ".",
+			clusterName: "prod_us_central1",
+			maxLength:   customRoleIdMaxLength,
+			want:        "network.admin.prod_us_central1",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterScopedName(tt.base, tt.separator, tt.clusterName, tt.maxLength)
+			if got != tt.want {
+				t.Errorf("clusterScopedName() = %q, want %q", got, tt.want)
+			}
+			if len(got) > tt.maxLength {
+				t.Errorf("clusterScopedName() = %q is longer than %d", got, tt.maxLength)
+			}
+		})
+	}
+}
+
+func TestClusterNameHash(t *testing.T) {
+	tests := []struct {
+		clusterName string
+		want        string
+	}{
+		{clusterName: "prod-us-central1", want: "186cb3"},
+		{clusterName: "another-very-long-cluster-name-for-prod", want: "ae3511"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.clusterName, func(t *testing.T) {
+			if got := clusterNameHash(tt.clusterName); got != tt.want {
+				t.Errorf("clusterNameHash() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package pkg
 import (
 	gkeclusterv1 "buf.build/gen/go/plantoncloud/project-planton/protocolbuffers/go/project/planton/provider/gcp/gkecluster/v1"
 	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/addons"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/pulumi-module-golang-commons/pkg/provider/gcp/pulumigkekubernetesprovider"
 	"github.com/plantoncloud/pulumi-module-golang-commons/pkg/provider/gcp/pulumigoogleprovider"
@@ -23,11 +24,17 @@ import (
 // 3. Creates a GCP folder for organizing the projects.
 // 4. Creates the GKE cluster within the specified folder.
 // 5. Creates the node pools for the GKE cluster.
-// 6. Creates a service account and key for deploying workloads to the cluster.
-// 7. If Kubernetes addons are specified, creates a Kubernetes provider for the cluster.
-// 8. Installs the specified Kubernetes addons using the created providers.
+// 6. Configures data access audit logs for the cluster project if enabled.
+// 7. Creates a service account and key for deploying workloads to the cluster.
+// 8. Returns early when no addon is enabled and no workload identity is configured.
+// 9. Creates a Kubernetes provider for the cluster.
+// 10. Sets up workload identity for the configured application namespaces.
+// 11. Installs the Kubernetes addons enabled by kubernetes-addons or the module options using the created providers.
 func Resources(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInput) error {
-	locals := localz.Initialize(ctx, stackInput)
+	locals, err := localz.Initialize(ctx, stackInput)
+	if err != nil {
+		return errors.Wrap(err, "failed to initialize locals")
+	}
 
 	//create gcp-provider using the gcp-credential from input
 	gcpProvider, err := pulumigoogleprovider.Get(ctx, stackInput.GcpCredential)
@@ -47,14 +54,23 @@ func Resources(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInpu
 		return errors.Wrap(err, "failed to create cluster node-pools")
 	}
 
+	//configure data access audit logs for the cluster project
+	if locals.Options.AuditLogging != nil && locals.Options.AuditLogging.IsEnabled {
+		if err := auditLogging(ctx, locals, createdCluster, gcpProvider); err != nil {
+			return errors.Wrap(err, "failed to configure audit logging")
+		}
+	}
+
 	//create workload-deployer google service account resources
-	createdWorkloadDeployerServiceAccountKey, err := workloadDeployer(ctx, createdCluster)
+	createdWorkloadDeployerServiceAccountKey, err := workloadDeployer(ctx, locals, createdCluster)
 	if err != nil {
 		return errors.Wrap(err, "failed to create workload-deployer resources")
 	}
 
-	//if kubernetes-addons is nil, nothing more to do
-	if locals.GkeCluster.Spec.KubernetesAddons == nil {
+	//if no addon is enabled, by kubernetes-addons or by the module options, and no workload identity is configured,
+	//nothing more to do. the removal of the addons of the previous update is not guarded in that case, so the last
+	//addons of a cluster are removed without checking for their custom resources.
+	if !addons.IsAnyEnabled(locals) && len(locals.Options.WorkloadIdentities) == 0 {
 		return nil
 	}
 
@@ -69,6 +85,13 @@ func Resources(ctx *pulumi.Context, stackInput *gkeclusterv1.GkeClusterStackInpu
 		return errors.Wrap(err, "failed to create kubernetes provider")
 	}
 
+	//create workload identities for application namespaces
+	if len(locals.Options.WorkloadIdentities) > 0 {
+		if err := workloadIdentities(ctx, locals, createdCluster, gcpProvider, kubernetesProvider); err != nil {
+			return errors.Wrap(err, "failed to create workload identities")
+		}
+	}
+
 	//create addons
 	if err := clusterAddons(ctx, locals, createdCluster, gcpProvider, kubernetesProvider); err != nil {
 		return errors.Wrap(err, "failed to create addons")
@@ -0,0 +1,75 @@
+package options
+
+import (
+	"encoding/json"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v3"
+)
+
+// AddonSettings customizes an addon installed by the module. the settings are keyed by the addon name, ex: "istio".
+type AddonSettings struct {
+	// ChartVersion overrides the version of the helm charts of the addon that is pinned by the module.
+	ChartVersion string `json:"chartVersion,omitempty"`
+	// ChartRepo overrides the repository of the helm charts of the addon.
+	ChartRepo string `json:"chartRepo,omitempty"`
+	// ReadinessTimeoutSeconds is how long to wait for the addon to be ready before its custom resources are created.
+	ReadinessTimeoutSeconds int `json:"readinessTimeoutSeconds,omitempty"`
+	// Values are deep-merged on top of the module's values for every helm release of the addon.
+	Values HelmValues `json:"values,omitempty"`
+	// ChartValues are deep-merged on top of Values for the helm release of the chart with the key as the name.
+	//only useful for addons that install more than one chart, ex: "istiod" chart of the istio addon.
+	ChartValues map[string]HelmValues `json:"chartValues,omitempty"`
+	// HelmRelease customizes the helm releases of the addon, on top of the helmReleaseDefaults.
+	HelmRelease *HelmRelease `json:"helmRelease,omitempty"`
+	// ForceRemoval deletes the custom resources of a disabled addon, ex: the postgres clusters of the postgres
+	//operator, instead of refusing to remove the addon while they exist.
+	ForceRemoval bool `json:"forceRemoval,omitempty"`
+}
+
+// HelmValues is a free-form helm values document.
+//
+// it can be set either as an object in the stack config or as a string containing a yaml or json document.
+type HelmValues map[string]interface{}
+
+// UnmarshalJSON decodes the values from a json object or from a string containing a yaml or json document.
+func (v *HelmValues) UnmarshalJSON(data []byte) error {
+	var document string
+	if err := json.Unmarshal(data, &document); err != nil {
+		values := make(map[string]interface{})
+		if err := json.Unmarshal(data, &values); err != nil {
+			return errors.Wrap(err, "values must be an object or a yaml or json document")
+		}
+		*v = values
+		return nil
+	}
+	//yaml is a superset of json, so a json document is also decoded here
+	values := make(map[string]interface{})
+	if err := yaml.Unmarshal([]byte(document), &values); err != nil {
+		return errors.Wrap(err, "failed to parse values document")
+	}
+	*v = values
+	return nil
+}
+
+func validateAddons(addons map[string]*AddonSettings) error {
+	for name, a := range addons {
+		if a == nil {
+			continue
+		}
+		if a.ReadinessTimeoutSeconds < 0 {
+			return errors.Errorf("%s: readinessTimeoutSeconds must not be negative", name)
+		}
+		if err := a.HelmRelease.validate(); err != nil {
+			return errors.Wrapf(err, "%s: helmRelease", name)
+		}
+	}
+	return nil
+}
+
+// Addon returns the settings of the addon or nil if the addon is not customized.
+func (o *Options) Addon(name string) *AddonSettings {
+	if o == nil {
+		return nil
+	}
+	return o.Addons[name]
+}
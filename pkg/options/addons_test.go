@@ -0,0 +1,87 @@
+package options
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHelmValuesUnmarshalJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    HelmValues
+		wantErr bool
+	}{
+		{
+			name: "object",
+			data: `{"controller": {"replicas": 2}}`,
+			want: HelmValues{"controller": map[string]interface{}{"replicas": float64(2)}},
+		},
+		{
+			name: "yaml document",
+			data: `"controller:\n  replicas: 2\n"`,
+			want: HelmValues{"controller": map[string]interface{}{"replicas": 2}},
+		},
+		{
+			name: "json document",
+			data: `"{\"controller\": {\"replicas\": 2}}"`,
+			want: HelmValues{"controller": map[string]interface{}{"replicas": 2}},
+		},
+		{
+			name:    "list",
+			data:    `["controller"]`,
+			wantErr: true,
+		},
+		{
+			name:    "invalid document",
+			data:    `"controller: ["`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got HelmValues
+			err := got.UnmarshalJSON([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("UnmarshalJSON() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("UnmarshalJSON() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestValidateAddons(t *testing.T) {
+	tests := []struct {
+		name    string
+		addons  map[string]*AddonSettings
+		wantErr bool
+	}{
+		{
+			name:   "not set",
+			addons: nil,
+		},
+		{
+			name:   "valid",
+			addons: map[string]*AddonSettings{"istio": {ChartVersion: "1.27.1", ReadinessTimeoutSeconds: 600}, "keda": nil},
+		},
+		{
+			name:    "negative readiness timeout",
+			addons:  map[string]*AddonSettings{"istio": {ReadinessTimeoutSeconds: -1}},
+			wantErr: true,
+		},
+		{
+			name:    "invalid helm release",
+			addons:  map[string]*AddonSettings{"istio": {HelmRelease: &HelmRelease{TimeoutSeconds: -1}}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateAddons(tt.addons); (err != nil) != tt.wantErr {
+				t.Errorf("validateAddons() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,56 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// ArgoCd installs argo cd in the cluster.
+type ArgoCd struct {
+	Ingress *Ingress `json:"ingress,omitempty"`
+	// Sso signs users in with google, requires the ingress.
+	Sso *ArgoCdSso `json:"sso,omitempty"`
+	// RootApplication bootstraps the applications of the cluster from a git repository, ex: an app-of-apps.
+	RootApplication *ArgoCdRootApplication `json:"rootApplication,omitempty"`
+}
+
+// ArgoCdSso configures sign-in with a google oauth client whose credentials are read from secret manager in the
+// cluster project by external-secrets.
+type ArgoCdSso struct {
+	// ClientIdSecret and ClientSecretSecret are the names of the secret manager secrets holding the client id and the
+	//client secret of the google oauth client.
+	ClientIdSecret     string `json:"clientIdSecret"`
+	ClientSecretSecret string `json:"clientSecretSecret"`
+	// AdminEmails are granted the admin role. everyone else who signs in gets read-only access.
+	AdminEmails []string `json:"adminEmails,omitempty"`
+}
+
+// ArgoCdRootApplication is synced automatically, along with the applications it contains.
+type ArgoCdRootApplication struct {
+	// RepoUrl is the url of a git repository readable by argo cd, ex: "https://github.com/my-org/cluster-apps.git".
+	RepoUrl string `json:"repoUrl"`
+	// Path of the applications in the repository, defaults to the root of the repository.
+	Path string `json:"path,omitempty"`
+	// TargetRevision is a branch, tag or commit, defaults to HEAD.
+	TargetRevision string `json:"targetRevision,omitempty"`
+}
+
+func (a *ArgoCd) validate() error {
+	if a == nil {
+		return nil
+	}
+	if err := a.Ingress.validate(); err != nil {
+		return errors.Wrap(err, "ingress")
+	}
+	if a.Sso != nil {
+		if a.Ingress == nil {
+			return errors.New("sso requires the ingress")
+		}
+		if a.Sso.ClientIdSecret == "" || a.Sso.ClientSecretSecret == "" {
+			return errors.New("sso: clientIdSecret and clientSecretSecret are required")
+		}
+	}
+	if a.RootApplication != nil && a.RootApplication.RepoUrl == "" {
+		return errors.New("rootApplication: repoUrl is required")
+	}
+	return nil
+}
@@ -0,0 +1,30 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// AuditLogging configures data access audit logs for services in the cluster project.
+type AuditLogging struct {
+	IsEnabled bool `json:"isEnabled"`
+	// Services defaults to the kubernetes and secret manager apis when not set.
+	Services []string `json:"services,omitempty"`
+	// LogTypes defaults to DATA_READ, DATA_WRITE and ADMIN_READ when not set.
+	LogTypes []string `json:"logTypes,omitempty"`
+	// ExemptedMembers are identities whose access is not logged, ex: "serviceAccount:ci@my-project.iam.gserviceaccount.com".
+	ExemptedMembers []string `json:"exemptedMembers,omitempty"`
+}
+
+func (a *AuditLogging) validate() error {
+	if a == nil {
+		return nil
+	}
+	for _, logType := range a.LogTypes {
+		switch logType {
+		case "DATA_READ", "DATA_WRITE", "ADMIN_READ":
+		default:
+			return errors.Errorf("logType %q is not one of DATA_READ, DATA_WRITE or ADMIN_READ", logType)
+		}
+	}
+	return nil
+}
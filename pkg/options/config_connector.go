@@ -0,0 +1,68 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// ConfigConnectorModeCluster manages the resources of all namespaces with the controller google service account.
+	ConfigConnectorModeCluster = "cluster"
+	// ConfigConnectorModeNamespaced runs a controller for each namespace with a config-connector-context.
+	ConfigConnectorModeNamespaced = "namespaced"
+)
+
+// ConfigConnector enables the config connector addon of gke, which lets teams manage gcp resources, ex: cloud sql
+// instances, pub/sub topics and buckets, as kubernetes objects.
+type ConfigConnector struct {
+	// Mode is either "cluster" or "namespaced", defaults to cluster.
+	Mode string `json:"mode,omitempty"`
+	// ProjectRoles are granted to the controller google service account. defaults to roles/editor in the cluster
+	//project.
+	ProjectRoles []*ProjectRole `json:"projectRoles,omitempty"`
+	// Contexts are the namespaces managed by config connector in namespaced mode.
+	Contexts []*ConfigConnectorContext `json:"contexts,omitempty"`
+}
+
+// ConfigConnectorContext is a namespace whose gcp resources are managed by config connector in namespaced mode.
+// the namespace is created by the addon.
+type ConfigConnectorContext struct {
+	Namespace string `json:"namespace"`
+	// ProjectId of the resources of the namespace, defaults to the cluster project.
+	ProjectId string `json:"projectId,omitempty"`
+}
+
+func (c *ConfigConnector) validate() error {
+	if c == nil {
+		return nil
+	}
+	switch c.Mode {
+	case "", ConfigConnectorModeCluster, ConfigConnectorModeNamespaced:
+	default:
+		return errors.Errorf("mode %q is not one of %s or %s", c.Mode,
+			ConfigConnectorModeCluster, ConfigConnectorModeNamespaced)
+	}
+	for _, r := range c.ProjectRoles {
+		if r.Role == "" {
+			return errors.New("role is required for project roles")
+		}
+	}
+	if len(c.Contexts) > 0 && !c.IsNamespaced() {
+		return errors.Errorf("contexts are only supported in %s mode", ConfigConnectorModeNamespaced)
+	}
+	seen := make(map[string]bool)
+	for _, context := range c.Contexts {
+		if context.Namespace == "" {
+			return errors.New("namespace is required for contexts")
+		}
+		if seen[context.Namespace] {
+			return errors.Errorf("context of %s namespace is configured more than once", context.Namespace)
+		}
+		seen[context.Namespace] = true
+	}
+	return nil
+}
+
+// IsNamespaced reports whether config connector runs in namespaced mode.
+func (c *ConfigConnector) IsNamespaced() bool {
+	return c != nil && c.Mode == ConfigConnectorModeNamespaced
+}
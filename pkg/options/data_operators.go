@@ -0,0 +1,27 @@
+package options
+
+// DataOperators installs the operators of the data services that are not part of the kubernetes addons of the
+// cluster spec, ex: the postgres and kafka operators.
+type DataOperators struct {
+	// RabbitMq installs the rabbitmq cluster operator.
+	RabbitMq bool `json:"rabbitMq,omitempty"`
+	// MongoDb installs the percona operator for mongodb.
+	MongoDb bool `json:"mongoDb,omitempty"`
+	// Redis installs the redis operator of opstree.
+	Redis bool `json:"redis,omitempty"`
+}
+
+// IsInstallRabbitMqOperator reports whether the rabbitmq cluster operator is installed.
+func (d *DataOperators) IsInstallRabbitMqOperator() bool {
+	return d != nil && d.RabbitMq
+}
+
+// IsInstallMongoDbOperator reports whether the percona operator for mongodb is installed.
+func (d *DataOperators) IsInstallMongoDbOperator() bool {
+	return d != nil && d.MongoDb
+}
+
+// IsInstallRedisOperator reports whether the redis operator is installed.
+func (d *DataOperators) IsInstallRedisOperator() bool {
+	return d != nil && d.Redis
+}
@@ -0,0 +1,35 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// HelmRelease customizes how the helm releases of the addons are installed and upgraded.
+//
+// the settings are read from "helmReleaseDefaults" for all addons and from the "helmRelease" settings of an addon,
+// which take precedence. settings that are set in neither fall back to the defaults of the module.
+type HelmRelease struct {
+	// Atomic rolls back a release when its install or upgrade fails. helm waits for the release to be ready when set.
+	Atomic *bool `json:"atomic,omitempty"`
+	// CleanupOnFail deletes the resources created by a failed upgrade.
+	CleanupOnFail *bool `json:"cleanupOnFail,omitempty"`
+	// Wait waits for the resources of a release to be ready before the release is marked as successful.
+	Wait *bool `json:"wait,omitempty"`
+	// WaitForJobs waits for the jobs of a release to complete before the release is marked as successful.
+	WaitForJobs *bool `json:"waitForJobs,omitempty"`
+	// TimeoutSeconds is how long helm waits for a release to be installed, upgraded or uninstalled.
+	TimeoutSeconds int `json:"timeoutSeconds,omitempty"`
+}
+
+func (h *HelmRelease) validate() error {
+	if h == nil {
+		return nil
+	}
+	if h.TimeoutSeconds < 0 {
+		return errors.New("timeoutSeconds must not be negative")
+	}
+	if h.Atomic != nil && *h.Atomic && h.Wait != nil && !*h.Wait {
+		return errors.New("wait can not be disabled for atomic releases")
+	}
+	return nil
+}
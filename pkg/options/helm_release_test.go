@@ -0,0 +1,44 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestHelmReleaseValidate(t *testing.T) {
+	enabled, disabled := true, false
+	tests := []struct {
+		name        string
+		helmRelease *HelmRelease
+		wantErr     bool
+	}{
+		{
+			name:        "not set",
+			helmRelease: nil,
+		},
+		{
+			name:        "atomic",
+			helmRelease: &HelmRelease{Atomic: &enabled, Wait: &enabled, TimeoutSeconds: 600},
+		},
+		{
+			name:        "not atomic without wait",
+			helmRelease: &HelmRelease{Atomic: &disabled, Wait: &disabled},
+		},
+		{
+			name:        "negative timeout",
+			helmRelease: &HelmRelease{TimeoutSeconds: -1},
+			wantErr:     true,
+		},
+		{
+			name:        "atomic without wait",
+			helmRelease: &HelmRelease{Atomic: &enabled, Wait: &disabled},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.helmRelease.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
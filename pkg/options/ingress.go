@@ -0,0 +1,47 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// IngressControllerIstio exposes an addon through a gateway-api gateway of the istio ingress load-balancer.
+	IngressControllerIstio = "istio"
+	// IngressControllerIngressNginx exposes an addon through an ingress of the ingress-nginx controller.
+	IngressControllerIngressNginx = "ingress-nginx"
+)
+
+// Ingress exposes the web interface of an addon on one of the ingress dns domains of the cluster.
+type Ingress struct {
+	// Domain is the name of one of the ingress dns domains of the cluster, ex: "example.com". a certificate is issued
+	//for the hostname when tls is enabled for the domain.
+	Domain string `json:"domain"`
+	// Hostname defaults to a hostname named after the addon in the domain, ex: "argocd.example.com".
+	Hostname string `json:"hostname,omitempty"`
+	// Controller is either "istio", the default, or "ingress-nginx".
+	Controller string `json:"controller,omitempty"`
+}
+
+func (i *Ingress) validate() error {
+	if i == nil {
+		return nil
+	}
+	if i.Domain == "" {
+		return errors.New("domain is required")
+	}
+	switch i.Controller {
+	case "", IngressControllerIstio, IngressControllerIngressNginx:
+	default:
+		return errors.Errorf("controller %q is not one of %s or %s", i.Controller,
+			IngressControllerIstio, IngressControllerIngressNginx)
+	}
+	return nil
+}
+
+// ControllerName returns the controller of the ingress, which defaults to istio.
+func (i *Ingress) ControllerName() string {
+	if i.Controller == "" {
+		return IngressControllerIstio
+	}
+	return i.Controller
+}
@@ -0,0 +1,25 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Keda installs the keda operator with a cluster-trigger-authentication for the gcp scalers through workload
+// identity.
+type Keda struct {
+	// MonitoringProjectIds are the projects, in addition to the project of the cluster, whose cloud monitoring metrics
+	//are read by the gcp scalers, ex: the projects of the pub/sub subscriptions of the workloads.
+	MonitoringProjectIds []string `json:"monitoringProjectIds,omitempty"`
+}
+
+func (k *Keda) validate() error {
+	if k == nil {
+		return nil
+	}
+	for _, projectId := range k.MonitoringProjectIds {
+		if projectId == "" {
+			return errors.New("monitoringProjectIds must not be empty")
+		}
+	}
+	return nil
+}
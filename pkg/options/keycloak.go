@@ -0,0 +1,30 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Keycloak creates a keycloak instance with the keycloak operator, backed by a postgres cluster of the zalando
+// postgres operator. both operators need to be installed.
+type Keycloak struct {
+	// Instances of the keycloak server, defaults to 1.
+	Instances int `json:"instances,omitempty"`
+	// DatabaseInstances of the postgres cluster, defaults to 1.
+	DatabaseInstances int `json:"databaseInstances,omitempty"`
+	// DatabaseVolumeSize of each instance of the postgres cluster, ex: "20Gi". defaults to 10Gi.
+	DatabaseVolumeSize string   `json:"databaseVolumeSize,omitempty"`
+	Ingress            *Ingress `json:"ingress,omitempty"`
+}
+
+func (k *Keycloak) validate() error {
+	if k == nil {
+		return nil
+	}
+	if k.Instances < 0 || k.DatabaseInstances < 0 {
+		return errors.New("instances and databaseInstances must not be negative")
+	}
+	if err := k.Ingress.validate(); err != nil {
+		return errors.Wrap(err, "ingress")
+	}
+	return nil
+}
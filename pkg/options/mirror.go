@@ -0,0 +1,48 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Mirror pulls the helm charts and container images of the addons from mirrors, for environments that can not reach
+// the public chart repositories and image registries.
+type Mirror struct {
+	// ChartRepo is either an oci registry, ex: "oci://europe-docker.pkg.dev/my-project/charts", with the charts under
+	// their chart names, or a helm repository, ex: "https://charts.example.com", serving all the charts. the chart
+	// repositories configured in the addon settings take precedence.
+	ChartRepo string `json:"chartRepo,omitempty"`
+	// Username and Password authenticate with the chart mirror, ex: "_json_key" and the json key of a google service
+	// account for artifact registry. the password is kept as a secret in the stack state.
+	Username string `json:"username,omitempty"`
+	Password string `json:"password,omitempty"`
+	// ImageRegistry is the registry the images are pulled from, ex: "europe-docker.pkg.dev/my-project/images". images
+	// are expected under their path without the original registry, ex: "<image-registry>/jetstack/cert-manager-controller"
+	// for "quay.io/jetstack/cert-manager-controller".
+	ImageRegistry string `json:"imageRegistry,omitempty"`
+}
+
+func (m *Mirror) validate() error {
+	if m == nil {
+		return nil
+	}
+	if m.ChartRepo != "" && !m.IsOciChartRepo() && !strings.HasPrefix(m.ChartRepo, "https://") &&
+		!strings.HasPrefix(m.ChartRepo, "http://") {
+		return errors.Errorf("chartRepo %q is neither an oci:// registry nor an http(s):// repository", m.ChartRepo)
+	}
+	if (m.Username == "") != (m.Password == "") {
+		return errors.New("username and password must be set together")
+	}
+	if m.Username != "" && m.ChartRepo == "" {
+		return errors.New("username and password are only used with a chartRepo")
+	}
+	if strings.Contains(m.ImageRegistry, "://") {
+		return errors.Errorf("imageRegistry %q must not have a scheme", m.ImageRegistry)
+	}
+	return nil
+}
+
+// IsOciChartRepo reports whether the chart mirror is an oci registry.
+func (m *Mirror) IsOciChartRepo() bool {
+	return m != nil && strings.HasPrefix(m.ChartRepo, "oci://")
+}
@@ -0,0 +1,54 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestMirrorValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		mirror  *Mirror
+		wantErr bool
+	}{
+		{
+			name:   "not set",
+			mirror: nil,
+		},
+		{
+			name: "oci registry with credentials",
+			mirror: &Mirror{ChartRepo: "oci://europe-docker.pkg.dev/my-project/charts", Username: "_json_key",
+				Password: "{}", ImageRegistry: "europe-docker.pkg.dev/my-project/images"},
+		},
+		{
+			name:   "helm repository",
+			mirror: &Mirror{ChartRepo: "https://charts.example.com"},
+		},
+		{
+			name:    "chart repository without scheme",
+			mirror:  &Mirror{ChartRepo: "charts.example.com"},
+			wantErr: true,
+		},
+		{
+			name:    "username without password",
+			mirror:  &Mirror{ChartRepo: "https://charts.example.com", Username: "user"},
+			wantErr: true,
+		},
+		{
+			name:    "credentials without chart repository",
+			mirror:  &Mirror{Username: "user", Password: "password"},
+			wantErr: true,
+		},
+		{
+			name:    "image registry with scheme",
+			mirror:  &Mirror{ImageRegistry: "https://europe-docker.pkg.dev/my-project/images"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.mirror.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,87 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+const (
+	// MonitoringModeManagedPrometheus enables google cloud managed service for prometheus on the cluster.
+	MonitoringModeManagedPrometheus = "managed-prometheus"
+	// MonitoringModeKubePrometheusStack installs the kube-prometheus-stack helm chart in the cluster.
+	MonitoringModeKubePrometheusStack = "kube-prometheus-stack"
+)
+
+// prometheusDurationPattern is the format of durations in prometheus and in the pod monitorings of managed
+// prometheus, ex: "1m30s".
+var prometheusDurationPattern = regexp.MustCompile(
+	`^((([0-9]+)y)?(([0-9]+)w)?(([0-9]+)d)?(([0-9]+)h)?(([0-9]+)m)?(([0-9]+)s)?(([0-9]+)ms)?|0)$`)
+
+// prometheusDurationUnits are the units of the submatches of prometheusDurationPattern holding their numbers.
+var prometheusDurationUnits = map[int]time.Duration{
+	3:  365 * 24 * time.Hour,
+	5:  7 * 24 * time.Hour,
+	7:  24 * time.Hour,
+	9:  time.Hour,
+	11: time.Minute,
+	13: time.Second,
+	15: time.Millisecond,
+}
+
+// Monitoring collects the metrics of the addons installed by the module.
+type Monitoring struct {
+	// Mode is either "managed-prometheus" or "kube-prometheus-stack".
+	Mode string `json:"mode"`
+	// ScrapeInterval of the metrics of the addons, ex: "60s". defaults to 30s.
+	ScrapeInterval string `json:"scrapeInterval,omitempty"`
+}
+
+func (m *Monitoring) validate() error {
+	if m == nil {
+		return nil
+	}
+	switch m.Mode {
+	case MonitoringModeManagedPrometheus, MonitoringModeKubePrometheusStack:
+	default:
+		return errors.Errorf("mode %q is not one of %s or %s", m.Mode,
+			MonitoringModeManagedPrometheus, MonitoringModeKubePrometheusStack)
+	}
+	if m.ScrapeInterval != "" {
+		interval, err := parsePrometheusDuration(m.ScrapeInterval)
+		if err != nil {
+			return errors.Wrapf(err, "invalid scrapeInterval %q", m.ScrapeInterval)
+		}
+		if interval < time.Second {
+			return errors.Errorf("scrapeInterval %q must be at least 1s", m.ScrapeInterval)
+		}
+	}
+	return nil
+}
+
+// IsManagedPrometheus reports whether the metrics are collected by google cloud managed service for prometheus.
+func (m *Monitoring) IsManagedPrometheus() bool {
+	return m != nil && m.Mode == MonitoringModeManagedPrometheus
+}
+
+// parsePrometheusDuration parses a duration in the format of prometheus, which unlike go durations has units of days,
+// weeks and years and no fractions.
+func parsePrometheusDuration(duration string) (time.Duration, error) {
+	matches := prometheusDurationPattern.FindStringSubmatch(duration)
+	if duration == "" || matches == nil {
+		return 0, errors.Errorf("%q does not match %s", duration, prometheusDurationPattern)
+	}
+	var parsed time.Duration
+	for index, unit := range prometheusDurationUnits {
+		if matches[index] == "" {
+			continue
+		}
+		number, err := strconv.ParseInt(matches[index], 10, 64)
+		if err != nil {
+			return 0, errors.Wrapf(err, "failed to parse %q", matches[index])
+		}
+		parsed += time.Duration(number) * unit
+	}
+	return parsed, nil
+}
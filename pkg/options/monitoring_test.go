@@ -0,0 +1,38 @@
+package options
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParsePrometheusDuration(t *testing.T) {
+	tests := []struct {
+		duration string
+		want     time.Duration
+		wantErr  bool
+	}{
+		{duration: "30s", want: 30 * time.Second},
+		{duration: "1m30s", want: 90 * time.Second},
+		{duration: "1s500ms", want: 1500 * time.Millisecond},
+		{duration: "1d", want: 24 * time.Hour},
+		{duration: "2w1h", want: 2*7*24*time.Hour + time.Hour},
+		{duration: "0", want: 0},
+		//valid go durations that prometheus rejects
+		{duration: "1.5s", wantErr: true},
+		{duration: "30m1h", wantErr: true},
+		{duration: "100us", wantErr: true},
+		{duration: "15", wantErr: true},
+		{duration: "", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.duration, func(t *testing.T) {
+			got, err := parsePrometheusDuration(tt.duration)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parsePrometheusDuration() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("parsePrometheusDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
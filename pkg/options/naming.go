@@ -0,0 +1,16 @@
+package options
+
+// Naming of the project-scoped resources that the module created with fixed names before they were named after the
+// cluster: the google service accounts of the workload-deployer, cert-manager, external-dns and external-secrets, and
+// the network-admin custom role of the shared vpc host project.
+type Naming struct {
+	// ClusterScoped names these resources after the cluster and grants the project roles of the service accounts with
+	//iam members instead of authoritative iam bindings, so that several clusters can share a project. it is off by
+	//default as it replaces the service accounts, and the key of the workload-deployer, of existing stacks.
+	ClusterScoped bool `json:"clusterScoped"`
+}
+
+// IsClusterScoped reports whether the project-scoped resources are named after the cluster.
+func (n *Naming) IsClusterScoped() bool {
+	return n != nil && n.ClusterScoped
+}
@@ -0,0 +1,43 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// OpenTelemetryModeDeployment runs the collector as a gateway deployment behind a service.
+	OpenTelemetryModeDeployment = "deployment"
+	// OpenTelemetryModeDaemonSet runs the collector on every node, the service only routes to the collector of the
+	//node of the sender.
+	OpenTelemetryModeDaemonSet = "daemonset"
+)
+
+// OpenTelemetry installs an opentelemetry collector which exports the traces and metrics sent to it to cloud trace
+// and cloud monitoring. istio sends the spans of the mesh to the collector when it is installed.
+type OpenTelemetry struct {
+	// Mode is either "deployment" or "daemonset", defaults to deployment.
+	Mode string `json:"mode,omitempty"`
+	// TracingSamplingPercentage of the requests traced by istio, from 0 to 100. defaults to 1.
+	TracingSamplingPercentage *float64 `json:"tracingSamplingPercentage,omitempty"`
+}
+
+func (o *OpenTelemetry) validate() error {
+	if o == nil {
+		return nil
+	}
+	switch o.Mode {
+	case "", OpenTelemetryModeDeployment, OpenTelemetryModeDaemonSet:
+	default:
+		return errors.Errorf("mode %q is not one of %s or %s", o.Mode,
+			OpenTelemetryModeDeployment, OpenTelemetryModeDaemonSet)
+	}
+	if p := o.TracingSamplingPercentage; p != nil && (*p < 0 || *p > 100) {
+		return errors.Errorf("tracingSamplingPercentage %v must be between 0 and 100", *p)
+	}
+	return nil
+}
+
+// IsDaemonSet reports whether the collector runs on every node.
+func (o *OpenTelemetry) IsDaemonSet() bool {
+	return o != nil && o.Mode == OpenTelemetryModeDaemonSet
+}
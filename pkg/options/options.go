@@ -0,0 +1,159 @@
+// Package options loads the module specific settings which are not part of the GkeCluster api resource.
+//
+// the settings are read from pulumi stack config in the "gke-cluster" namespace, one key per section, ex:
+//
+//	config:
+//	  gke-cluster:podSecurity:
+//	    defaultLevel: baseline
+package options
+
+import (
+	"github.com/pkg/errors"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi/config"
+)
+
+// ConfigNamespace is the pulumi config namespace from which the options are read.
+const ConfigNamespace = "gke-cluster"
+
+type Options struct {
+	PodSecurity        *PodSecurity        `json:"podSecurity,omitempty"`
+	WorkloadIdentities []*WorkloadIdentity `json:"workloadIdentities,omitempty"`
+	AuditLogging       *AuditLogging       `json:"auditLogging,omitempty"`
+	Naming             *Naming             `json:"naming,omitempty"`
+	// Addons is keyed by the addon name.
+	Addons map[string]*AddonSettings `json:"addons,omitempty"`
+	// HelmReleaseDefaults apply to the helm releases of all addons.
+	HelmReleaseDefaults *HelmRelease     `json:"helmReleaseDefaults,omitempty"`
+	Mirror              *Mirror          `json:"mirror,omitempty"`
+	Monitoring          *Monitoring      `json:"monitoring,omitempty"`
+	ArgoCd              *ArgoCd          `json:"argoCd,omitempty"`
+	Keycloak            *Keycloak        `json:"keycloak,omitempty"`
+	Velero              *Velero          `json:"velero,omitempty"`
+	PolicyEngine        *PolicyEngine    `json:"policyEngine,omitempty"`
+	OpenTelemetry       *OpenTelemetry   `json:"openTelemetry,omitempty"`
+	Keda                *Keda            `json:"keda,omitempty"`
+	Vault               *Vault           `json:"vault,omitempty"`
+	ConfigConnector     *ConfigConnector `json:"configConnector,omitempty"`
+	DataOperators       *DataOperators   `json:"dataOperators,omitempty"`
+}
+
+// Load reads all the option sections from the stack config. sections that are not set are left nil.
+func Load(ctx *pulumi.Context) (*Options, error) {
+	c := config.New(ctx, ConfigNamespace)
+	o := &Options{}
+
+	if err := tryObject(c, "podSecurity", &o.PodSecurity); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "workloadIdentities", &o.WorkloadIdentities); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "auditLogging", &o.AuditLogging); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "naming", &o.Naming); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "addons", &o.Addons); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "helmReleaseDefaults", &o.HelmReleaseDefaults); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "mirror", &o.Mirror); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "monitoring", &o.Monitoring); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "argoCd", &o.ArgoCd); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "keycloak", &o.Keycloak); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "velero", &o.Velero); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "policyEngine", &o.PolicyEngine); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "openTelemetry", &o.OpenTelemetry); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "keda", &o.Keda); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "vault", &o.Vault); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "configConnector", &o.ConfigConnector); err != nil {
+		return nil, err
+	}
+	if err := tryObject(c, "dataOperators", &o.DataOperators); err != nil {
+		return nil, err
+	}
+
+	if err := o.validate(); err != nil {
+		return nil, errors.Wrap(err, "invalid options")
+	}
+	return o, nil
+}
+
+// tryObject decodes the config key into output and ignores the key if it is not set.
+func tryObject(c *config.Config, key string, output interface{}) error {
+	if err := c.TryObject(key, output); err != nil && !errors.Is(err, config.ErrMissingVar) {
+		return errors.Wrapf(err, "failed to read %s:%s config", ConfigNamespace, key)
+	}
+	return nil
+}
+
+func (o *Options) validate() error {
+	if err := o.PodSecurity.validate(); err != nil {
+		return errors.Wrap(err, "podSecurity")
+	}
+	if err := validateWorkloadIdentities(o.WorkloadIdentities); err != nil {
+		return errors.Wrap(err, "workloadIdentities")
+	}
+	if err := o.AuditLogging.validate(); err != nil {
+		return errors.Wrap(err, "auditLogging")
+	}
+	if err := validateAddons(o.Addons); err != nil {
+		return errors.Wrap(err, "addons")
+	}
+	if err := o.HelmReleaseDefaults.validate(); err != nil {
+		return errors.Wrap(err, "helmReleaseDefaults")
+	}
+	if err := o.Mirror.validate(); err != nil {
+		return errors.Wrap(err, "mirror")
+	}
+	if err := o.Monitoring.validate(); err != nil {
+		return errors.Wrap(err, "monitoring")
+	}
+	if err := o.ArgoCd.validate(); err != nil {
+		return errors.Wrap(err, "argoCd")
+	}
+	if err := o.Keycloak.validate(); err != nil {
+		return errors.Wrap(err, "keycloak")
+	}
+	if err := o.Velero.validate(); err != nil {
+		return errors.Wrap(err, "velero")
+	}
+	if err := o.PolicyEngine.validate(); err != nil {
+		return errors.Wrap(err, "policyEngine")
+	}
+	if err := o.OpenTelemetry.validate(); err != nil {
+		return errors.Wrap(err, "openTelemetry")
+	}
+	if err := o.Keda.validate(); err != nil {
+		return errors.Wrap(err, "keda")
+	}
+	if err := o.Vault.validate(); err != nil {
+		return errors.Wrap(err, "vault")
+	}
+	if err := o.ConfigConnector.validate(); err != nil {
+		return errors.Wrap(err, "configConnector")
+	}
+	return nil
+}
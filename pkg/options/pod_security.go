@@ -0,0 +1,43 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	PodSecurityLevelPrivileged = "privileged"
+	PodSecurityLevelBaseline   = "baseline"
+	PodSecurityLevelRestricted = "restricted"
+)
+
+// PodSecurity configures the pod-security-admission levels applied to the namespaces created by the module.
+type PodSecurity struct {
+	// DefaultLevel is the enforce level for namespaces that are not addon namespaces, ex: tenant namespaces.
+	DefaultLevel string `json:"defaultLevel,omitempty"`
+	// NamespaceLevels overrides the enforce level of individual namespaces, including the addon namespaces.
+	NamespaceLevels map[string]string `json:"namespaceLevels,omitempty"`
+}
+
+func (p *PodSecurity) validate() error {
+	if p == nil {
+		return nil
+	}
+	if p.DefaultLevel != "" && !isValidPodSecurityLevel(p.DefaultLevel) {
+		return errors.Errorf("defaultLevel %q is not one of privileged, baseline or restricted", p.DefaultLevel)
+	}
+	for namespace, level := range p.NamespaceLevels {
+		if !isValidPodSecurityLevel(level) {
+			return errors.Errorf("namespaceLevels.%s %q is not one of privileged, baseline or restricted",
+				namespace, level)
+		}
+	}
+	return nil
+}
+
+func isValidPodSecurityLevel(level string) bool {
+	switch level {
+	case PodSecurityLevelPrivileged, PodSecurityLevelBaseline, PodSecurityLevelRestricted:
+		return true
+	}
+	return false
+}
@@ -0,0 +1,42 @@
+package options
+
+import (
+	"testing"
+)
+
+func TestPodSecurityValidate(t *testing.T) {
+	tests := []struct {
+		name        string
+		podSecurity *PodSecurity
+		wantErr     bool
+	}{
+		{
+			name:        "not set",
+			podSecurity: nil,
+		},
+		{
+			name: "valid levels",
+			podSecurity: &PodSecurity{
+				DefaultLevel:    PodSecurityLevelRestricted,
+				NamespaceLevels: map[string]string{"istio-ingress": PodSecurityLevelPrivileged},
+			},
+		},
+		{
+			name:        "invalid default level",
+			podSecurity: &PodSecurity{DefaultLevel: "strict"},
+			wantErr:     true,
+		},
+		{
+			name:        "invalid namespace level",
+			podSecurity: &PodSecurity{NamespaceLevels: map[string]string{"istio-ingress": ""}},
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.podSecurity.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,62 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+const (
+	// PolicyModeAudit reports the violations of a policy in policy reports without blocking the resources.
+	PolicyModeAudit = "audit"
+	// PolicyModeEnforce rejects the resources which violate a policy.
+	PolicyModeEnforce = "enforce"
+)
+
+// PolicyEngine installs kyverno along with the baseline policy set shipped with the module. the addon and system
+// namespaces are always excluded from the policies.
+type PolicyEngine struct {
+	// BaselineVersion of the baseline policy set, ex: "v1". defaults to the version of the module.
+	BaselineVersion string `json:"baselineVersion,omitempty"`
+	// DefaultMode of the policies, "audit" or "enforce". defaults to audit.
+	DefaultMode string `json:"defaultMode,omitempty"`
+	// Policies overrides the mode of individual policies keyed by the policy name, ex: "disallow-latest-tag".
+	Policies map[string]string `json:"policies,omitempty"`
+	// ExcludedNamespaces are excluded from all the policies in addition to the addon and system namespaces.
+	ExcludedNamespaces []string `json:"excludedNamespaces,omitempty"`
+}
+
+func (p *PolicyEngine) validate() error {
+	if p == nil {
+		return nil
+	}
+	if err := validatePolicyMode(p.DefaultMode); err != nil {
+		return errors.Wrap(err, "defaultMode")
+	}
+	for name, mode := range p.Policies {
+		if mode == "" {
+			return errors.Errorf("mode of %s policy is empty", name)
+		}
+		if err := validatePolicyMode(mode); err != nil {
+			return errors.Wrapf(err, "%s policy", name)
+		}
+	}
+	return nil
+}
+
+// PolicyMode returns the mode of the policy, which is the mode configured for the policy or the default mode.
+func (p *PolicyEngine) PolicyMode(name string) string {
+	if mode, ok := p.Policies[name]; ok {
+		return mode
+	}
+	if p.DefaultMode != "" {
+		return p.DefaultMode
+	}
+	return PolicyModeAudit
+}
+
+func validatePolicyMode(mode string) error {
+	switch mode {
+	case "", PolicyModeAudit, PolicyModeEnforce:
+		return nil
+	}
+	return errors.Errorf("mode %q must be one of %s or %s", mode, PolicyModeAudit, PolicyModeEnforce)
+}
@@ -0,0 +1,34 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+)
+
+// Vault installs hashicorp vault in high availability mode with raft storage, unsealed automatically with a cloud kms
+// key created in the project of the cluster.
+type Vault struct {
+	// Replicas of the vault server, an odd number for the raft quorum. defaults to 3.
+	Replicas int `json:"replicas,omitempty"`
+	// StorageSize of the raft storage of each replica, ex: "20Gi". defaults to 10Gi.
+	StorageSize string   `json:"storageSize,omitempty"`
+	Ingress     *Ingress `json:"ingress,omitempty"`
+	// ImportKmsKey adopts the kms key ring and key retained by a previous installation of the addon, which can not
+	//be created again. required when the addon is enabled again after it was removed.
+	ImportKmsKey bool `json:"importKmsKey,omitempty"`
+}
+
+func (v *Vault) validate() error {
+	if v == nil {
+		return nil
+	}
+	if v.Replicas < 0 {
+		return errors.New("replicas must not be negative")
+	}
+	if v.Replicas > 0 && v.Replicas%2 == 0 {
+		return errors.Errorf("replicas %d must be an odd number for the raft quorum", v.Replicas)
+	}
+	if err := v.Ingress.validate(); err != nil {
+		return errors.Wrap(err, "ingress")
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package options
+
+import (
+	"github.com/pkg/errors"
+	"strings"
+)
+
+// Velero backs up the cluster to a cloud storage bucket in the project of the cluster, taking compute disk snapshots
+// of the persistent volumes.
+type Velero struct {
+	// Namespaces to be backed up, each by a schedule of its own. all namespaces are backed up by a single schedule
+	//when empty.
+	Namespaces []string `json:"namespaces,omitempty"`
+	// Schedule of the backups as a cron expression in utc, defaults to daily at 03:00.
+	Schedule string `json:"schedule,omitempty"`
+	// RetentionDays after which velero deletes a backup along with its volume snapshots, defaults to 30.
+	RetentionDays int `json:"retentionDays,omitempty"`
+	// BucketLocation of the backup bucket, ex: "US". defaults to the region of the cluster.
+	BucketLocation string `json:"bucketLocation,omitempty"`
+}
+
+func (v *Velero) validate() error {
+	if v == nil {
+		return nil
+	}
+	if v.RetentionDays < 0 {
+		return errors.New("retentionDays must not be negative")
+	}
+	if v.Schedule != "" && len(strings.Fields(v.Schedule)) != 5 {
+		return errors.Errorf("schedule %q is not a cron expression with five fields", v.Schedule)
+	}
+	seen := make(map[string]bool)
+	for _, namespace := range v.Namespaces {
+		if namespace == "" {
+			return errors.New("namespaces must not be empty")
+		}
+		if seen[namespace] {
+			return errors.Errorf("namespace %s is listed more than once", namespace)
+		}
+		seen[namespace] = true
+	}
+	return nil
+}
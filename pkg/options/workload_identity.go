@@ -0,0 +1,120 @@
+package options
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"regexp"
+	"strings"
+)
+
+const (
+	// gsaAccountIdMinLength is the minimum length of the account id of a google service account.
+	gsaAccountIdMinLength = 6
+	// gsaAccountIdMaxLength is the maximum length of the account id of a google service account.
+	gsaAccountIdMaxLength = 30
+)
+
+var (
+	// gsaAccountIdPattern is the format of the account id of a google service account enforced by gcp.
+	gsaAccountIdPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*[a-z0-9]$`)
+	// derivedGsaAccountIdPrefixPattern is the format of a ksa name from which the account id of a google service
+	//account can be derived. the ksa name is the prefix of the account id, followed by the cluster name.
+	derivedGsaAccountIdPrefixPattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+)
+
+// WorkloadIdentity describes a kubernetes service account in an application namespace that should act as a google
+// service account through workload identity.
+type WorkloadIdentity struct {
+	Namespace string `json:"namespace"`
+	KsaName   string `json:"ksaName"`
+	// GsaAccountId overrides the account id of the google service account created for the ksa.
+	GsaAccountId string `json:"gsaAccountId,omitempty"`
+	// ExistingGsaEmail is the email of an existing google service account to bind the ksa to.
+	//no google service account is created when this is set.
+	ExistingGsaEmail string         `json:"existingGsaEmail,omitempty"`
+	ProjectRoles     []*ProjectRole `json:"projectRoles,omitempty"`
+}
+
+// ProjectRole is an iam role to be granted to a google service account in a project.
+type ProjectRole struct {
+	Role string `json:"role"`
+	// ProjectId defaults to the cluster project when not set.
+	ProjectId string `json:"projectId,omitempty"`
+}
+
+// Key uniquely identifies the workload identity as "<namespace>/<ksa-name>".
+func (w *WorkloadIdentity) Key() string {
+	return fmt.Sprintf("%s/%s", w.Namespace, w.KsaName)
+}
+
+func validateWorkloadIdentities(workloadIdentities []*WorkloadIdentity) error {
+	seen := make(map[string]bool)
+	for _, w := range workloadIdentities {
+		if w.Namespace == "" || w.KsaName == "" {
+			return errors.New("namespace and ksaName are required")
+		}
+		if seen[w.Key()] {
+			return errors.Errorf("%s is configured more than once", w.Key())
+		}
+		seen[w.Key()] = true
+		if err := validateWorkloadIdentityNamespace(w.Namespace); err != nil {
+			return errors.Wrapf(err, "%s: invalid namespace", w.Key())
+		}
+
+		if w.ExistingGsaEmail != "" && w.GsaAccountId != "" {
+			return errors.Errorf("%s: gsaAccountId and existingGsaEmail are mutually exclusive", w.Key())
+		}
+		if w.ExistingGsaEmail != "" && !strings.HasSuffix(w.ExistingGsaEmail, ".iam.gserviceaccount.com") {
+			return errors.Errorf("%s: existingGsaEmail %q is not a user managed service account email",
+				w.Key(), w.ExistingGsaEmail)
+		}
+		if w.GsaAccountId != "" {
+			if err := ValidateGsaAccountId(w.GsaAccountId); err != nil {
+				return errors.Wrapf(err, "%s: invalid gsaAccountId", w.Key())
+			}
+		}
+		if w.ExistingGsaEmail == "" && w.GsaAccountId == "" && !derivedGsaAccountIdPrefixPattern.MatchString(w.KsaName) {
+			return errors.Errorf("%s: gsa account id can not be derived from ksaName %q as it does not match %s, "+
+				"set gsaAccountId", w.Key(), w.KsaName, derivedGsaAccountIdPrefixPattern)
+		}
+		for _, r := range w.ProjectRoles {
+			if r.Role == "" {
+				return errors.Errorf("%s: role is required for project roles", w.Key())
+			}
+		}
+	}
+	return nil
+}
+
+// validateWorkloadIdentityNamespace returns an error when the namespace is reserved for kubernetes and gke, or is
+// the namespace of an addon, whose service accounts are bound by the addons themselves.
+func validateWorkloadIdentityNamespace(namespace string) error {
+	if strings.HasPrefix(namespace, vars.WorkloadIdentities.ReservedNamespacePrefix) {
+		return errors.Errorf("%s namespace is managed by gke", namespace)
+	}
+	for _, reservedNamespace := range vars.WorkloadIdentities.ReservedNamespaces {
+		if namespace == reservedNamespace {
+			return errors.Errorf("%s namespace is reserved", namespace)
+		}
+	}
+	for _, addonNamespace := range vars.WorkloadIdentities.AddonNamespaces {
+		if namespace == addonNamespace {
+			return errors.Errorf("%s namespace is the namespace of an addon", namespace)
+		}
+	}
+	return nil
+}
+
+// ValidateGsaAccountId returns an error when the account id of a google service account is not 6-30 characters
+// long, or is not made of lowercase letters, digits and hyphens starting with a letter and not ending with a hyphen.
+func ValidateGsaAccountId(accountId string) error {
+	if len(accountId) < gsaAccountIdMinLength || len(accountId) > gsaAccountIdMaxLength {
+		return errors.Errorf("account id %q is not %d-%d characters long", accountId,
+			gsaAccountIdMinLength, gsaAccountIdMaxLength)
+	}
+	if !gsaAccountIdPattern.MatchString(accountId) {
+		return errors.Errorf("account id %q does not match %s", accountId, gsaAccountIdPattern)
+	}
+	return nil
+}
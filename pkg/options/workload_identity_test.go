@@ -0,0 +1,149 @@
+package options
+
+import (
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"testing"
+)
+
+func TestValidateWorkloadIdentities(t *testing.T) {
+	tests := []struct {
+		name               string
+		workloadIdentities []*WorkloadIdentity
+		wantErr            bool
+	}{
+		{
+			name: "derived gsa account id",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders-api", ProjectRoles: []*ProjectRole{{Role: "roles/pubsub.publisher"}}},
+			},
+		},
+		{
+			name: "existing gsa email",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "billing", KsaName: "invoicer",
+					ExistingGsaEmail: "invoicer@billing-project.iam.gserviceaccount.com"},
+			},
+		},
+		{
+			name: "missing ksa name",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "duplicate",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders-api"},
+				{Namespace: "orders", KsaName: "orders-api", GsaAccountId: "orders-api-2"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "addon namespace",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: vars.CertManager.Namespace, KsaName: "orders-api"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "gsa account id and existing gsa email",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "billing", KsaName: "invoicer", GsaAccountId: "invoicer",
+					ExistingGsaEmail: "invoicer@billing-project.iam.gserviceaccount.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "existing gsa email of a google managed service account",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "billing", KsaName: "invoicer", ExistingGsaEmail: "123456789@cloudbuild.gserviceaccount.com"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid gsa account id",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders-api", GsaAccountId: "Orders_Api"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ksa name not usable in derived gsa account id",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders.api"},
+			},
+			wantErr: true,
+		},
+		{
+			name: "ksa name not usable in derived gsa account id with gsa account id",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders.api", GsaAccountId: "orders-api"},
+			},
+		},
+		{
+			name: "missing role",
+			workloadIdentities: []*WorkloadIdentity{
+				{Namespace: "orders", KsaName: "orders-api", ProjectRoles: []*ProjectRole{{ProjectId: "shared-db-project"}}},
+			},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := validateWorkloadIdentities(tt.workloadIdentities); (err != nil) != tt.wantErr {
+				t.Errorf("validateWorkloadIdentities() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateWorkloadIdentityNamespace(t *testing.T) {
+	tests := []struct {
+		namespace string
+		wantErr   bool
+	}{
+		{namespace: "orders"},
+		{namespace: "kube-system-tools"},
+		{namespace: "default", wantErr: true},
+		{namespace: "kube-system", wantErr: true},
+		{namespace: "kube-public", wantErr: true},
+		{namespace: "kube-node-lease", wantErr: true},
+		{namespace: "gmp-system", wantErr: true},
+		{namespace: "gke-managed-system", wantErr: true},
+		{namespace: "gke-gmp-system", wantErr: true},
+		{namespace: vars.CertManager.Namespace, wantErr: true},
+		{namespace: vars.ConfigConnector.Namespace, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			if err := validateWorkloadIdentityNamespace(tt.namespace); (err != nil) != tt.wantErr {
+				t.Errorf("validateWorkloadIdentityNamespace() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateGsaAccountId(t *testing.T) {
+	tests := []struct {
+		accountId string
+		wantErr   bool
+	}{
+		{accountId: "orders-api"},
+		{accountId: "abcdef"},
+		{accountId: "abcdefghijklmnopqrstuvwxyz1234"},
+		{accountId: "abcde", wantErr: true},
+		{accountId: "abcdefghijklmnopqrstuvwxyz12345", wantErr: true},
+		{accountId: "1orders-api", wantErr: true},
+		{accountId: "orders-api-", wantErr: true},
+		{accountId: "orders_api", wantErr: true},
+		{accountId: "Orders-api", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.accountId, func(t *testing.T) {
+			if err := ValidateGsaAccountId(tt.accountId); (err != nil) != tt.wantErr {
+				t.Errorf("ValidateGsaAccountId() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -5,22 +5,31 @@ const (
 	ExternalDnsGsaEmail           = "external-dns-gsa-email"
 	ClusterCaData                 = "cluster-ca-data"
 	ClusterEndpoint               = "cluster-endpoint"
+	ConfigConnectorGsaEmail       = "config-connector-gsa-email"
 	ContainerClusterProjectId     = "container-cluster-project-id"
 	ContainerClusterProjectNumber = "container-cluster-project-number"
 	ExternalSecretsGsaEmail       = "external-secrets-gsa-email"
 	IngressExternalIp             = "ingress-external-ip"
 	IngressInternalIp             = "ingress-internal-ip"
+	InstalledAddons               = "installed-addons"
+	KedaGsaEmail                  = "keda-gsa-email"
 	FolderDisplayName             = "folder-name"
 	FolderId                      = "folder-id"
 	FolderParent                  = "folder-parent"
 	GkeWebhooksFirewallSelfLink   = "gke-webhooks-firewall-self-link"
+	GuardedAddonRemovals          = "guarded-addon-removals"
 	NatIpAddress                  = "nat-ip-address"
 	NetworkSelfLink               = "network-self-link"
+	OpenTelemetryGsaEmail         = "open-telemetry-gsa-email"
 	RouterNatName                 = "router-nat-name"
 	RouterSelfLink                = "router-self-link"
 	SubNetworkSelfLink            = "sub-network-self-link"
+	VaultGsaEmail                 = "vault-gsa-email"
+	VeleroBucketName              = "velero-bucket-name"
+	VeleroGsaEmail                = "velero-gsa-email"
 	VpcNetworkProjectId           = "vpc-network-project-id"
 	VpcNetworkProjectNumber       = "vpc-network-project-number"
 	WorkloadDeployerGsaEmail      = "workload-deployer-gsa-email"
 	WorkloadDeployerGsaKey        = "workload-deployer-gsa-key"
+	WorkloadIdentityGsaEmails     = "workload-identity-gsa-emails"
 )
@@ -3,6 +3,7 @@ package pkg
 import (
 	"github.com/pkg/errors"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/compute"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/organizations"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
@@ -50,7 +51,7 @@ func sharedVpcIam(ctx *pulumi.Context,
 				pulumi.String("compute.firewalls.update"),
 				pulumi.String("compute.networks.updatePolicy"),
 			},
-			RoleId: pulumi.String("network.admin"),
+			RoleId: pulumi.String(locals.LegacyCustomRoleId(vars.NetworkAdminCustomRoleId)),
 			Title:  pulumi.String("Host Project Network and Security Admin"),
 		}, pulumi.Parent(createdSubNetwork))
 	if err != nil {
@@ -122,8 +123,9 @@ func sharedVpcIam(ctx *pulumi.Context,
 			},
 			Project: pulumi.String(locals.GkeCluster.Spec.ClusterProjectId),
 			Role: pulumi.Sprintf(
-				"projects/%s/roles/network.admin",
-				createdNetworkProject.ProjectId),
+				"projects/%s/roles/%s",
+				createdNetworkProject.ProjectId,
+				locals.LegacyCustomRoleId(vars.NetworkAdminCustomRoleId)),
 		}, pulumi.Parent(createdSubNetwork))
 	if err != nil {
 		return nil, errors.Wrap(err, "failed to create role binding for network-admin role")
@@ -0,0 +1,121 @@
+package vars
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"github.com/pkg/errors"
+	"path"
+	"strings"
+)
+
+const (
+	// CrdManifestsDir is the directory, relative to this package, into which crd manifests are vendored.
+	CrdManifestsDir = "crds"
+	// CrdManifestChecksumsFile lists the sha256 checksums of the vendored manifests in the format of sha256sum.
+	CrdManifestChecksumsFile = "SHA256SUMS"
+)
+
+// crdManifests contains the vendored crd manifests so that they are not downloaded at deploy time.
+//
+//go:embed crds
+var crdManifests embed.FS
+
+// CrdManifest is a crd manifest vendored from its download url.
+type CrdManifest struct {
+	// Path of the manifest relative to the crds directory, ex: "gateway-api/v1.1.0/gateway.networking.k8s.io_gateways.yaml".
+	Path        string
+	DownloadUrl string
+}
+
+// GatewayApisCrdManifests returns the gateway-api crd manifests in the order of the crd files.
+func GatewayApisCrdManifests() []*CrdManifest {
+	manifests := make([]*CrdManifest, 0)
+	for _, crdFile := range GatewayApis.CrdFiles {
+		manifests = append(manifests, &CrdManifest{
+			Path:        path.Join("gateway-api", GatewayApis.CrdVersion, crdFile),
+			DownloadUrl: fmt.Sprintf("%s/%s", GatewayApis.CrdDownloadBaseUrl, crdFile),
+		})
+	}
+	return manifests
+}
+
+// SolrOperatorCrdManifest returns the solr-operator crd manifest.
+func SolrOperatorCrdManifest() *CrdManifest {
+	return &CrdManifest{
+		Path:        path.Join("solr-operator", SolrOperator.CrdVersion, path.Base(SolrOperator.CrdManifestDownloadUrl)),
+		DownloadUrl: SolrOperator.CrdManifestDownloadUrl,
+	}
+}
+
+// KeycloakOperatorCrdManifests returns the keycloak operator crd manifests in the order of the crd files.
+func KeycloakOperatorCrdManifests() []*CrdManifest {
+	manifests := make([]*CrdManifest, 0)
+	for _, crdFile := range KeycloakOperator.CrdFiles {
+		manifests = append(manifests, keycloakOperatorManifest(crdFile))
+	}
+	return manifests
+}
+
+// KeycloakOperatorManifest returns the manifest of the keycloak operator deployment and its rbac, which is vendored
+// along with the crds as the operator has no helm chart.
+func KeycloakOperatorManifest() *CrdManifest {
+	return keycloakOperatorManifest(KeycloakOperator.OperatorManifestFile)
+}
+
+func keycloakOperatorManifest(file string) *CrdManifest {
+	return &CrdManifest{
+		Path:        path.Join("keycloak-operator", KeycloakOperator.Version, file),
+		DownloadUrl: fmt.Sprintf("%s/%s", KeycloakOperator.ManifestDownloadBaseUrl, file),
+	}
+}
+
+// CrdManifests returns all the crd manifests vendored into the module.
+func CrdManifests() []*CrdManifest {
+	manifests := append(GatewayApisCrdManifests(), SolrOperatorCrdManifest())
+	manifests = append(manifests, KeycloakOperatorCrdManifests()...)
+	return append(manifests, KeycloakOperatorManifest())
+}
+
+// ReadCrdManifest returns the content of the vendored manifest after verifying it against its recorded checksum.
+func ReadCrdManifest(m *CrdManifest) (string, error) {
+	content, err := crdManifests.ReadFile(path.Join(CrdManifestsDir, m.Path))
+	if err != nil {
+		return "", errors.Wrapf(err, "%s is not vendored, run \"make update-crds\"", m.Path)
+	}
+	checksums, err := crdManifests.ReadFile(path.Join(CrdManifestsDir, CrdManifestChecksumsFile))
+	if err != nil {
+		return "", errors.Wrap(err, "failed to read crd manifest checksums")
+	}
+	expected, ok := ParseChecksums(checksums)[m.Path]
+	if !ok {
+		return "", errors.Errorf("no checksum is recorded for %s, run \"make update-crds\"", m.Path)
+	}
+	if actual := Checksum(content); actual != expected {
+		return "", errors.Errorf("checksum of %s is %s but %s is recorded", m.Path, actual, expected)
+	}
+	return string(content), nil
+}
+
+// Checksum returns the hex encoded sha256 checksum of the content.
+func Checksum(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseChecksums parses the content of a checksums file into checksums keyed by path.
+func ParseChecksums(content []byte) map[string]string {
+	checksums := make(map[string]string)
+	scanner := bufio.NewScanner(bytes.NewReader(content))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 2 {
+			continue
+		}
+		checksums[fields[1]] = fields[0]
+	}
+	return checksums
+}
@@ -0,0 +1,63 @@
+package vars
+
+import (
+	"embed"
+	"github.com/pkg/errors"
+	"path"
+	"strings"
+)
+
+// PoliciesDir is the directory, relative to this package, of the policy sets shipped with the module. each policy set
+// has a directory per version, ex: "baseline/v1", so that changed policies are released as a new version.
+const PoliciesDir = "policies"
+
+// policySets contains the policy sets shipped with the module.
+//
+//go:embed policies
+var policySets embed.FS
+
+// Policy is a policy of a policy set shipped with the module.
+type Policy struct {
+	// Name of the policy, which is the name of its file without the extension, ex: "disallow-latest-tag".
+	Name    string
+	Content string
+}
+
+// BaselinePolicyVersions returns the versions of the baseline policy set shipped with the module.
+func BaselinePolicyVersions() ([]string, error) {
+	entries, err := policySets.ReadDir(path.Join(PoliciesDir, PolicyEngine.BaselinePolicySet))
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to read baseline policy set versions")
+	}
+	versions := make([]string, 0)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			versions = append(versions, entry.Name())
+		}
+	}
+	return versions, nil
+}
+
+// BaselinePolicies returns the policies of the version of the baseline policy set sorted by name.
+func BaselinePolicies(version string) ([]*Policy, error) {
+	dir := path.Join(PoliciesDir, PolicyEngine.BaselinePolicySet, version)
+	entries, err := policySets.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Errorf("version %s of the baseline policy set is not shipped with the module", version)
+	}
+	policies := make([]*Policy, 0)
+	for _, entry := range entries {
+		if entry.IsDir() || path.Ext(entry.Name()) != ".yaml" {
+			continue
+		}
+		content, err := policySets.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read %s baseline policy", entry.Name())
+		}
+		policies = append(policies, &Policy{
+			Name:    strings.TrimSuffix(entry.Name(), ".yaml"),
+			Content: string(content),
+		})
+	}
+	return policies, nil
+}
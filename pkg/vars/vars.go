@@ -20,6 +20,27 @@ var (
 
 	WorkloadIdentityKubeAnnotationKey = "iam.gke.io/gcp-service-account"
 
+	// AuditLogging defaults for data access audit logs in the container cluster project
+	//https://cloud.google.com/logging/docs/audit/configure-data-access
+	AuditLogging = struct {
+		Services []string
+		LogTypes []string
+	}{
+		Services: []string{
+			"container.googleapis.com",
+			"secretmanager.googleapis.com",
+		},
+		LogTypes: []string{
+			"ADMIN_READ",
+			"DATA_READ",
+			"DATA_WRITE",
+		},
+	}
+
+	// CloudDnsAdminRole is granted to cert-manager and external-dns google service accounts
+	//in the projects hosting the cloud-dns zones of the ingress-dns-domains
+	CloudDnsAdminRole = "roles/dns.admin"
+
 	// SubNetworkCidr 10.0.0.0/14
 	// this subnet will be divided into two equal halves for pod-secondary-ip-range and service-secondary-ip-range
 	//https://jodies.de/ipcalc?host=10.0.0.0&mask1=14&mask2=15
@@ -40,11 +61,152 @@ var (
 	//be used for deploying workloads to the gke cluster.
 	WorkloadDeployServiceAccountName = "workload-deployer"
 
+	// NetworkAdminCustomRoleId id of the custom role created in the host project for shared vpc setup.
+	//the cluster name is appended to it when cluster scoped naming is enabled, as custom role ids are unique per project.
+	NetworkAdminCustomRoleId = "network.admin"
+
+	// PodSecurity pod-security-admission settings for the namespaces created by the module.
+	//https://kubernetes.io/docs/concepts/security/pod-security-admission/#pod-security-admission-labels-for-namespaces
+	PodSecurity = struct {
+		LabelKeyPrefix string
+		DefaultLevel   string
+		AuditLevel     string
+		WarnLevel      string
+		//enforce levels of the addon namespaces, chosen so that the addon workloads are admitted
+		NamespaceLevels map[string]string
+	}{
+		LabelKeyPrefix: "pod-security.kubernetes.io",
+		DefaultLevel:   "baseline",
+		//audit and warn are always restricted so that violations are visible before tightening enforce level
+		AuditLevel: "restricted",
+		WarnLevel:  "restricted",
+		NamespaceLevels: map[string]string{
+			CertManager.Namespace:             "restricted",
+			ExternalDns.Namespace:             "restricted",
+			ExternalSecrets.Namespace:         "restricted",
+			ElasticOperator.Namespace:         "restricted",
+			IngressNginx.Namespace:            "baseline",
+			Istio.SystemNamespace:             "baseline",
+			Istio.GatewayNamespace:            "baseline",
+			ZalandoPostgresOperator.Namespace: "baseline",
+			SolrOperator.Namespace:            "baseline",
+			StrimziKafkaOperator.Namespace:    "baseline",
+			//node-exporter uses the network and pid namespaces of the nodes
+			Monitoring.Namespace:       "privileged",
+			ArgoCd.Namespace:           "baseline",
+			KeycloakOperator.Namespace: "baseline",
+			Velero.Namespace:           "baseline",
+			PolicyEngine.Namespace:     "baseline",
+			OpenTelemetry.Namespace:    "baseline",
+			Keda.Namespace:             "restricted",
+			Vault.Namespace:            "baseline",
+			RabbitMqOperator.Namespace: "baseline",
+			MongoDbOperator.Namespace:  "baseline",
+			RedisOperator.Namespace:    "baseline",
+		},
+	}
+
+	// WorkloadIdentities settings of the workload identities declared in the module options.
+	WorkloadIdentities = struct {
+		//namespaces created by the addons, the service accounts in them are bound by the addons themselves
+		AddonNamespaces []string
+		//namespaces of kubernetes and of gke managed prometheus
+		ReservedNamespaces []string
+		//prefix of the namespaces managed by gke, ex: "gke-managed-system"
+		ReservedNamespacePrefix string
+	}{
+		AddonNamespaces: []string{
+			CertManager.Namespace,
+			ExternalDns.Namespace,
+			ExternalSecrets.Namespace,
+			ElasticOperator.Namespace,
+			IngressNginx.Namespace,
+			Istio.SystemNamespace,
+			Istio.GatewayNamespace,
+			ZalandoPostgresOperator.Namespace,
+			SolrOperator.Namespace,
+			StrimziKafkaOperator.Namespace,
+			Monitoring.Namespace,
+			ArgoCd.Namespace,
+			KeycloakOperator.Namespace,
+			Velero.Namespace,
+			PolicyEngine.Namespace,
+			OpenTelemetry.Namespace,
+			Keda.Namespace,
+			Vault.Namespace,
+			RabbitMqOperator.Namespace,
+			MongoDbOperator.Namespace,
+			RedisOperator.Namespace,
+			ConfigConnector.Namespace,
+			ConfigConnector.OperatorNamespace,
+		},
+		ReservedNamespaces: []string{
+			"default",
+			"kube-system",
+			"kube-public",
+			"kube-node-lease",
+			"gmp-system",
+			"gmp-public",
+		},
+		ReservedNamespacePrefix: "gke-",
+	}
+
+	// HelmRelease defaults of the helm releases of the addons, which can be changed from the module options.
+	HelmRelease = struct {
+		Atomic         bool
+		CleanupOnFail  bool
+		Wait           bool
+		WaitForJobs    bool
+		TimeoutSeconds int
+		//added to the helm timeout for pulumi to wait for a release, as helm only starts its timer once the chart
+		//is downloaded and rendered
+		TimeoutMarginSeconds int
+	}{
+		Atomic:               false,
+		CleanupOnFail:        true,
+		Wait:                 true,
+		WaitForJobs:          true,
+		TimeoutSeconds:       180,
+		TimeoutMarginSeconds: 120,
+	}
+
+	// Readiness settings of the jobs that wait for addons to be ready before their custom resources are created.
+	Readiness = struct {
+		//https://github.com/kubernetes/kubectl, the image has kubectl as the entrypoint
+		KubectlImage   string
+		TimeoutSeconds int
+		//added to the timeout for pulumi to wait for the job, to allow for scheduling and pulling the image
+		JobStartupSeconds int
+	}{
+		KubectlImage:      "registry.k8s.io/kubectl:v1.30.5",
+		TimeoutSeconds:    300,
+		JobStartupSeconds: 120,
+	}
+
+	// AddonRemoval settings of the jobs that check, and clean up, the custom resources of addons that are disabled.
+	AddonRemoval = struct {
+		//the jobs run in a namespace that outlives the addons
+		Namespace string
+		//https://hub.docker.com/r/alpine/k8s, the check is a shell script and the kubectl image has no shell
+		Image          string
+		TimeoutSeconds int
+		//reference to the stack itself, from which the addons of the previous update are read
+		PreviousStackReferenceName string
+	}{
+		Namespace:                  "kube-system",
+		Image:                      "docker.io/alpine/k8s:1.30.5",
+		TimeoutSeconds:             600,
+		PreviousStackReferenceName: "previous-update",
+	}
+
+	// GatewayApis crds are vendored into the crds directory of this package, run "make update-crds" after a change.
 	GatewayApis = struct {
+		CrdVersion         string
 		CrdDownloadBaseUrl string
 		CrdFiles           []string
 	}{
-		//version is included in the base-url
+		CrdVersion: "v1.1.0",
+		//version in the base-url should match the crd version
 		CrdDownloadBaseUrl: "https://raw.githubusercontent.com/kubernetes-sigs/gateway-api/v1.1.0/config/crd/standard",
 		CrdFiles: []string{
 			"gateway.networking.k8s.io_gatewayclasses.yaml",
@@ -101,6 +263,7 @@ var (
 		KsaName                                 string
 		SecretsPollingIntervalSeconds           int
 		GcpSecretsManagerClusterSecretStoreName string
+		MetricsPort                             int
 	}{
 		Namespace:        "external-secrets",
 		HelmChartName:    "external-secrets",
@@ -110,6 +273,7 @@ var (
 		//caution: polling interval frequency may have effect on provider costs on some platforms
 		SecretsPollingIntervalSeconds:           10,
 		GcpSecretsManagerClusterSecretStoreName: "gcp-secrets-manager",
+		MetricsPort:                             3001,
 	}
 
 	IngressNginx = struct {
@@ -138,15 +302,19 @@ var (
 		HelmChartVersion: "1.12.2",
 	}
 
+	// SolrOperator crds are vendored into the crds directory of this package, run "make update-crds" after a change.
 	SolrOperator = struct {
 		Namespace              string
+		CrdVersion             string
 		CrdManifestDownloadUrl string
 		HelmChartName          string
 		HelmChartRepo          string
 		HelmChartVersion       string
 	}{
 		Namespace: "solr-operator",
-		//version in the url should match the helm-chart version and should be prefixed with 'v'
+		//crd version should match the helm-chart version and should be prefixed with 'v'
+		CrdVersion: "v0.7.0",
+		//version in the url should match the crd version
 		CrdManifestDownloadUrl: "https://solr.apache.org/operator/downloads/crds/v0.7.0/all-with-dependencies.yaml",
 		HelmChartName:          "solr-operator",
 		HelmChartRepo:          "https://solr.apache.org/charts",
@@ -168,6 +336,48 @@ var (
 		HelmChartVersion: "0.42.0",
 	}
 
+	RabbitMqOperator = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+	}{
+		Namespace:     "rabbitmq-operator",
+		HelmChartName: "rabbitmq-cluster-operator",
+		//https://artifacthub.io/packages/helm/bitnami/rabbitmq-cluster-operator
+		HelmChartRepo: "https://charts.bitnami.com/bitnami",
+		//check artifact-hub for the latest version
+		HelmChartVersion: "4.3.16",
+	}
+
+	MongoDbOperator = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+	}{
+		Namespace:     "mongodb-operator",
+		HelmChartName: "psmdb-operator",
+		//https://artifacthub.io/packages/helm/percona/psmdb-operator
+		HelmChartRepo: "https://percona.github.io/percona-helm-charts/",
+		//check artifact-hub for the latest version
+		HelmChartVersion: "1.16.2",
+	}
+
+	RedisOperator = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+	}{
+		Namespace:     "redis-operator",
+		HelmChartName: "redis-operator",
+		//https://artifacthub.io/packages/helm/ot-helm/redis-operator
+		HelmChartRepo: "https://ot-container-kit.github.io/helm-charts/",
+		//check artifact-hub for the latest version
+		HelmChartVersion: "0.18.0",
+	}
+
 	Istio = struct {
 		SystemNamespace                        string
 		GatewayNamespace                       string
@@ -184,6 +394,7 @@ var (
 		HttpPort                               int
 		HttpsPort                              int
 		IstiodStatusPort                       int
+		GatewayClassName                       string
 	}{
 		SystemNamespace:  "istio-system",
 		GatewayNamespace: "istio-ingress",
@@ -211,6 +422,8 @@ var (
 		HttpPort:         80,
 		HttpsPort:        443,
 		IstiodStatusPort: 15021,
+		//created by istiod for gateway-api gateways
+		GatewayClassName: "istio",
 	}
 
 	ElasticOperator = struct {
@@ -225,4 +438,289 @@ var (
 		//https://github.com/elastic/cloud-on-k8s/blob/main/deploy/eck-operator/values.yaml
 		HelmChartVersion: "2.14.0",
 	}
+
+	Monitoring = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		ScrapeInterval   string
+		//namespace in which the readiness of the managed prometheus crds is checked
+		ManagedPrometheusReadinessNamespace string
+	}{
+		Namespace:     "monitoring",
+		HelmChartName: "kube-prometheus-stack",
+		//https://artifacthub.io/packages/helm/prometheus-community/kube-prometheus-stack
+		HelmChartRepo:                       "https://prometheus-community.github.io/helm-charts",
+		HelmChartVersion:                    "61.9.0",
+		ScrapeInterval:                      "30s",
+		ManagedPrometheusReadinessNamespace: "kube-system",
+	}
+
+	ArgoCd = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		//names of the argo cd resources are prefixed with it, ex: "argocd-server"
+		FullName string
+		//the argo cd server is exposed with this name in the ingress domain unless a hostname is configured
+		HostnamePrefix string
+		ServerPort     int
+		//the secret holding the google oauth client, referenced from the oidc config of argo cd
+		OidcSecretName      string
+		RootApplicationName string
+	}{
+		Namespace:     "argocd",
+		HelmChartName: "argo-cd",
+		HelmChartRepo: "https://argoproj.github.io/argo-helm",
+		//https://artifacthub.io/packages/helm/argo/argo-cd
+		HelmChartVersion:    "7.4.4",
+		FullName:            "argocd",
+		HostnamePrefix:      "argocd",
+		ServerPort:          80,
+		OidcSecretName:      "argocd-oidc-google",
+		RootApplicationName: "root",
+	}
+
+	// KeycloakOperator manifests are vendored into the crds directory of this package, run "make update-crds" after a
+	//change. the operator has no helm chart and only watches the namespace it is installed in.
+	KeycloakOperator = struct {
+		Namespace               string
+		Version                 string
+		ManifestDownloadBaseUrl string
+		CrdFiles                []string
+		OperatorManifestFile    string
+		DeploymentName          string
+	}{
+		Namespace: "keycloak",
+		Version:   "25.0.4",
+		//version in the base-url should match the operator version
+		ManifestDownloadBaseUrl: "https://raw.githubusercontent.com/keycloak/keycloak-k8s-resources/25.0.4/kubernetes",
+		CrdFiles: []string{
+			"keycloaks.k8s.keycloak.org-v1.yml",
+			"keycloakrealmimports.k8s.keycloak.org-v1.yml",
+		},
+		OperatorManifestFile: "kubernetes.yml",
+		DeploymentName:       "keycloak-operator",
+	}
+
+	// Keycloak instance created with the keycloak operator, backed by a postgres cluster of the zalando operator.
+	Keycloak = struct {
+		Name string
+		//the keycloak server is exposed with this name in the ingress domain unless a hostname is configured
+		HostnamePrefix string
+		//http port of the service created by the operator for the instance, tls is terminated by the ingress
+		HttpPort int
+		//the name of the postgres cluster is prefixed with the team id as required by the zalando operator
+		PostgresTeamId     string
+		PostgresName       string
+		PostgresVersion    string
+		PostgresVolumeSize string
+		DatabaseName       string
+		DatabaseUser       string
+	}{
+		Name:               "keycloak",
+		HostnamePrefix:     "keycloak",
+		HttpPort:           8080,
+		PostgresTeamId:     "keycloak",
+		PostgresName:       "keycloak-db",
+		PostgresVersion:    "16",
+		PostgresVolumeSize: "10Gi",
+		DatabaseName:       "keycloak",
+		DatabaseUser:       "keycloak",
+	}
+
+	// Velero backs up the kubernetes resources to a cloud storage bucket and the persistent volumes as compute disk
+	//snapshots with the gcp plugin.
+	Velero = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		KsaName          string
+		//init container image which installs the gcp plugin into the velero server
+		GcpPluginImage string
+		//base of the custom role granting the disk snapshot permissions, the cluster name is appended to it
+		CustomRoleId string
+		//name of both the backup storage location and the volume snapshot location
+		LocationName string
+		//base of the name of the backup bucket, the project id and the cluster name are appended to it
+		BucketNameBase string
+		//backups are deleted from the bucket by its lifecycle rules this many days after their retention, in case
+		//velero did not delete them
+		BucketLifecycleGraceDays int
+		//daily at 03:00 utc
+		DefaultSchedule      string
+		DefaultRetentionDays int
+	}{
+		Namespace:        "velero",
+		HelmChartName:    "velero",
+		HelmChartRepo:    "https://vmware-tanzu.github.io/helm-charts",
+		HelmChartVersion: "7.1.5",
+		KsaName:          "velero",
+		GcpPluginImage:   "velero/velero-plugin-for-gcp:v1.10.0",
+		CustomRoleId:     "velero.server",
+		LocationName:     "default",
+		BucketNameBase:   "velero",
+		//compute disk snapshots are deleted by velero only, they are not covered by the lifecycle rules
+		BucketLifecycleGraceDays: 7,
+		DefaultSchedule:          "0 3 * * *",
+		DefaultRetentionDays:     30,
+	}
+
+	// PolicyEngine installs kyverno and the baseline policy set shipped with the module in the policies directory of
+	//this package.
+	PolicyEngine = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		//replicas of the admission controller, enforced policies block admission while it is unavailable
+		AdmissionControllerReplicas int
+		BaselinePolicySet           string
+		//version of the baseline policy set used unless another version is configured
+		BaselinePolicyVersion string
+		//namespaces of gke and kubernetes which are excluded from the policies along with the addon namespaces
+		SystemNamespaces []string
+	}{
+		Namespace:     "kyverno",
+		HelmChartName: "kyverno",
+		//https://artifacthub.io/packages/helm/kyverno/kyverno
+		HelmChartRepo:               "https://kyverno.github.io/kyverno",
+		HelmChartVersion:            "3.2.6",
+		AdmissionControllerReplicas: 3,
+		BaselinePolicySet:           "baseline",
+		BaselinePolicyVersion:       "v1",
+		SystemNamespaces: []string{
+			"kube-system",
+			"kube-public",
+			"kube-node-lease",
+			"gke-managed-system",
+			"gke-gmp-system",
+			"gmp-system",
+			"gmp-public",
+			"cnrm-system",
+			"configconnector-operator-system",
+		},
+	}
+
+	// OpenTelemetry collector exporting traces to cloud trace and metrics to cloud monitoring.
+	OpenTelemetry = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		KsaName          string
+		FullName         string
+		//the contrib distribution is needed for the googlecloud exporter
+		Image           string
+		OtlpGrpcPort    int
+		GatewayReplicas int
+		//name of the extension provider of the collector in the mesh config of istio
+		IstioProviderName                string
+		DefaultTracingSamplingPercentage float64
+	}{
+		Namespace:     "opentelemetry",
+		HelmChartName: "opentelemetry-collector",
+		//https://artifacthub.io/packages/helm/opentelemetry-helm/opentelemetry-collector
+		HelmChartRepo:                    "https://open-telemetry.github.io/opentelemetry-helm-charts",
+		HelmChartVersion:                 "0.102.1",
+		KsaName:                          "opentelemetry-collector",
+		FullName:                         "opentelemetry-collector",
+		Image:                            "otel/opentelemetry-collector-contrib",
+		OtlpGrpcPort:                     4317,
+		GatewayReplicas:                  2,
+		IstioProviderName:                "otel",
+		DefaultTracingSamplingPercentage: 1,
+	}
+
+	Keda = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		//the service account is shared by the operator, the metrics api server and the admission webhooks
+		KsaName string
+		//cluster-trigger-authentication for the gcp pod identity provider, referenced by the scaled objects
+		GcpClusterTriggerAuthenticationName string
+	}{
+		Namespace:     "keda",
+		HelmChartName: "keda",
+		//https://artifacthub.io/packages/helm/kedacore/keda
+		HelmChartRepo:                       "https://kedacore.github.io/charts",
+		HelmChartVersion:                    "2.14.2",
+		KsaName:                             "keda-operator",
+		GcpClusterTriggerAuthenticationName: "gcp-workload-identity",
+	}
+
+	Vault = struct {
+		Namespace        string
+		HelmChartName    string
+		HelmChartRepo    string
+		HelmChartVersion string
+		KsaName          string
+		Replicas         int
+		StorageSize      string
+		HostnamePrefix   string
+		//port of the api of the vault server, the active service only routes to the leader of the raft cluster
+		Port              int
+		ActiveServiceName string
+		//base of the name of the key ring, the cluster name is appended to it
+		KmsKeyRingName   string
+		KmsCryptoKeyName string
+		//the unseal key is rotated every 90 days, vault keeps using the older versions to decrypt
+		KmsCryptoKeyRotationPeriod string
+		//cluster-secret-store of external-secrets which reads the kv secrets engine of vault with the kubernetes
+		//auth method, configured in vault after its initialization
+		ClusterSecretStoreName   string
+		KvMountPath              string
+		KubernetesAuthMountPath  string
+		ExternalSecretsVaultRole string
+	}{
+		Namespace:     "vault",
+		HelmChartName: "vault",
+		//https://artifacthub.io/packages/helm/hashicorp/vault
+		HelmChartRepo:              "https://helm.releases.hashicorp.com",
+		HelmChartVersion:           "0.28.1",
+		KsaName:                    "vault",
+		Replicas:                   3,
+		StorageSize:                "10Gi",
+		HostnamePrefix:             "vault",
+		Port:                       8200,
+		ActiveServiceName:          "vault-active",
+		KmsKeyRingName:             "vault",
+		KmsCryptoKeyName:           "vault-unseal",
+		KmsCryptoKeyRotationPeriod: "7776000s",
+		ClusterSecretStoreName:     "vault",
+		KvMountPath:                "secret",
+		KubernetesAuthMountPath:    "kubernetes",
+		ExternalSecretsVaultRole:   "external-secrets",
+	}
+
+	ConfigConnector = struct {
+		//namespace of the controllers, managed by the gke addon
+		Namespace string
+		//namespace of the operator, managed by the gke addon
+		OperatorNamespace string
+		//base of the account id of the controller google service account
+		GsaName string
+		//ksa of the controller in cluster mode, suffixed with "-<namespace>" for the controllers in namespaced mode
+		KsaName string
+		//the config-connector resource is a singleton with a fixed name
+		ResourceName           string
+		ApiVersion             string
+		ProjectIdAnnotationKey string
+		DefaultProjectRole     string
+	}{
+		Namespace:              "cnrm-system",
+		OperatorNamespace:      "configconnector-operator-system",
+		GsaName:                "config-connector",
+		KsaName:                "cnrm-controller-manager",
+		ResourceName:           "configconnector.core.cnrm.cloud.google.com",
+		ApiVersion:             "core.cnrm.cloud.google.com/v1beta1",
+		ProjectIdAnnotationKey: "cnrm.cloud.google.com/project-id",
+		//https://cloud.google.com/config-connector/docs/how-to/install-upgrade-uninstall#identity
+		DefaultProjectRole: "roles/editor",
+	}
 )
@@ -3,6 +3,7 @@ package pkg
 import (
 	"fmt"
 	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
 	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
 	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
@@ -15,6 +16,7 @@ import (
 //
 // Parameters:
 // - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
 // - createdCluster: The GKE cluster to which workloads will be deployed.
 //
 // Returns:
@@ -25,17 +27,18 @@ import (
 // 1. Creates a service account with a description and display name for deploying workloads.
 // 2. Exports the email of the created service account.
 // 3. Creates a key for the service account and exports the private key.
-// 4. Creates IAM bindings to grant the service account the roles of container admin and cluster admin.
+// 4. Grants the service account the roles of container admin and cluster admin in the cluster project.
 // 5. Handles errors and returns the created service account key and any errors encountered.
-func workloadDeployer(ctx *pulumi.Context, createdCluster *container.Cluster) (*serviceaccount.Key, error) {
+func workloadDeployer(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster) (*serviceaccount.Key, error) {
 	//create workload deployer service account
 	createdWorkloadDeployerServiceAccount, err := serviceaccount.NewAccount(ctx,
 		vars.WorkloadDeployServiceAccountName,
 		&serviceaccount.AccountArgs{
 			Project:     createdCluster.Project,
 			Description: pulumi.String("service account to deploy workloads"),
-			AccountId:   pulumi.String(vars.WorkloadDeployServiceAccountName),
-			DisplayName: pulumi.String(vars.WorkloadDeployServiceAccountName),
+			AccountId:   pulumi.String(locals.LegacyGsaAccountId(vars.WorkloadDeployServiceAccountName)),
+			DisplayName: pulumi.String(locals.LegacyGsaAccountId(vars.WorkloadDeployServiceAccountName)),
 		}, pulumi.Parent(createdCluster))
 	if err != nil {
 		return nil, errors.Wrapf(err, "failed to create workload deployer service account")
@@ -57,29 +60,52 @@ func workloadDeployer(ctx *pulumi.Context, createdCluster *container.Cluster) (*
 	//export workload deployer google service account key
 	ctx.Export(outputs.WorkloadDeployerGsaKey, createdWorkloadDeployerServiceAccountKey.PrivateKey)
 
-	// create iam-binding for workload-deployer to manage the container cluster itself
-	_, err = projects.NewIAMBinding(ctx,
-		fmt.Sprintf("%s-container-admin", vars.WorkloadDeployServiceAccountName),
-		&projects.IAMBindingArgs{
-			Members: pulumi.StringArray{pulumi.Sprintf("serviceAccount:%s", createdWorkloadDeployerServiceAccount.Email)},
-			Project: createdCluster.Project,
-			Role:    pulumi.String("roles/container.admin"),
-		}, pulumi.Parent(createdWorkloadDeployerServiceAccount))
-	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create container-admin iam binding for workload deployer")
+	// grant workload-deployer the role to manage the container cluster itself
+	if err := workloadDeployerProjectRole(ctx, locals, createdCluster, createdWorkloadDeployerServiceAccount,
+		"container-admin", "roles/container.admin"); err != nil {
+		return nil, errors.Wrap(err, "failed to grant container-admin role to workload deployer")
 	}
 
-	// create iam-binding for workload-deployer to manage resources inside container clusters
-	_, err = projects.NewIAMBinding(ctx,
-		fmt.Sprintf("%s-kube-cluster-admin", vars.WorkloadDeployServiceAccountName),
+	// grant workload-deployer the role to manage resources inside container clusters
+	if err := workloadDeployerProjectRole(ctx, locals, createdCluster, createdWorkloadDeployerServiceAccount,
+		"kube-cluster-admin", "roles/container.clusterAdmin"); err != nil {
+		return nil, errors.Wrap(err, "failed to grant cluster-admin role to workload deployer")
+	}
+
+	return createdWorkloadDeployerServiceAccountKey, nil
+}
+
+// workloadDeployerProjectRole grants the role in the cluster project to the workload-deployer service account.
+//
+// the role is granted with an authoritative iam binding, as it always was, unless cluster scoped naming is enabled in
+// the module options, in which case an iam member is used as the project can be shared with other clusters. the iam
+// binding is retained on delete, so that enabling cluster scoped naming only removes it from the stack instead of
+// removing all the members of the role from the project.
+func workloadDeployerProjectRole(ctx *pulumi.Context, locals *localz.Locals, createdCluster *container.Cluster,
+	createdServiceAccount *serviceaccount.Account, resourceSuffix, role string) error {
+	resourceName := fmt.Sprintf("%s-%s", vars.WorkloadDeployServiceAccountName, resourceSuffix)
+	if locals.Options.Naming.IsClusterScoped() {
+		_, err := projects.NewIAMMember(ctx,
+			resourceName,
+			&projects.IAMMemberArgs{
+				Member:  pulumi.Sprintf("serviceAccount:%s", createdServiceAccount.Email),
+				Project: createdCluster.Project,
+				Role:    pulumi.String(role),
+			}, pulumi.Parent(createdServiceAccount))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create %s iam member", role)
+		}
+		return nil
+	}
+	_, err := projects.NewIAMBinding(ctx,
+		resourceName,
 		&projects.IAMBindingArgs{
-			Members: pulumi.StringArray{pulumi.Sprintf("serviceAccount:%s", createdWorkloadDeployerServiceAccount.Email)},
+			Members: pulumi.StringArray{pulumi.Sprintf("serviceAccount:%s", createdServiceAccount.Email)},
 			Project: createdCluster.Project,
-			Role:    pulumi.String("roles/container.clusterAdmin"),
-		}, pulumi.Parent(createdWorkloadDeployerServiceAccount))
+			Role:    pulumi.String(role),
+		}, pulumi.Parent(createdServiceAccount), pulumi.RetainOnDelete(true))
 	if err != nil {
-		return nil, errors.Wrapf(err, "failed to create cluster-admin iam binding for workload deployer")
+		return errors.Wrapf(err, "failed to create %s iam binding", role)
 	}
-
-	return createdWorkloadDeployerServiceAccountKey, nil
+	return nil
 }
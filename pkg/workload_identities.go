@@ -0,0 +1,116 @@
+package pkg
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/localz"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/options"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/outputs"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/workloadidentity"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/container"
+	pulumikubernetes "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+)
+
+// workloadIdentities sets up workload identity for the kubernetes service accounts of application namespaces
+// configured in the workloadIdentities option.
+//
+// Parameters:
+// - ctx: The Pulumi context used for defining cloud resources.
+// - locals: A struct containing local configuration and metadata.
+// - createdCluster: The GKE cluster in which the application namespaces are created.
+// - gcpProvider: The GCP provider for Pulumi.
+// - kubernetesProvider: The Kubernetes provider for Pulumi.
+//
+// Returns:
+// - error: An error object if there is any issue during the creation of the resources.
+//
+// The function performs the following steps for each configured workload identity:
+// 1. Creates the namespace, once per namespace, and labels it with metadata and pod-security labels from locals.
+// 2. Creates the workload identity component, i.e. a Google Service Account (GSA) unless an existing GSA email is
+// provided, the role grants in the cluster project or in the project specified for the role, the Workload Identity
+// binding and the Kubernetes Service Account (KSA) annotated with the GSA email.
+// 3. Exports the GSA emails keyed by "<namespace>/<ksa-name>".
+func workloadIdentities(ctx *pulumi.Context, locals *localz.Locals,
+	createdCluster *container.Cluster,
+	gcpProvider *gcp.Provider,
+	kubernetesProvider *pulumikubernetes.Provider) error {
+	createdNamespaces := make(map[string]*corev1.Namespace)
+	gsaAccountIds := make(map[string]string)
+	gsaEmails := pulumi.StringMap{}
+
+	for _, w := range locals.Options.WorkloadIdentities {
+		//create namespace resource only once for all the ksa in the same namespace
+		createdNamespace, ok := createdNamespaces[w.Namespace]
+		if !ok {
+			var err error
+			createdNamespace, err = corev1.NewNamespace(ctx,
+				fmt.Sprintf("workload-identity-%s", w.Namespace),
+				&corev1.NamespaceArgs{
+					Metadata: metav1.ObjectMetaPtrInput(
+						&metav1.ObjectMetaArgs{
+							Name:   pulumi.String(w.Namespace),
+							Labels: pulumi.ToStringMap(locals.NamespaceLabels(w.Namespace)),
+						}),
+				},
+				pulumi.Provider(kubernetesProvider))
+			if err != nil {
+				return errors.Wrapf(err, "failed to create %s namespace", w.Namespace)
+			}
+			createdNamespaces[w.Namespace] = createdNamespace
+		}
+
+		gsaAccountId := ""
+		if w.ExistingGsaEmail == "" {
+			gsaAccountId = w.GsaAccountId
+			if gsaAccountId == "" {
+				gsaAccountId = locals.GsaAccountId(w.KsaName)
+			}
+			//the derived account id is only known with the cluster name, ex: it is too short for short names
+			if err := options.ValidateGsaAccountId(gsaAccountId); err != nil {
+				return errors.Wrapf(err, "%s: invalid gsa account id, set gsaAccountId", w.Key())
+			}
+			//account ids derived from ksa names could collide when the same ksa name is used in two namespaces
+			if otherKey, ok := gsaAccountIds[gsaAccountId]; ok {
+				return errors.Errorf("%s and %s resolve to the same gsa account id %s, set gsaAccountId for one of them",
+					otherKey, w.Key(), gsaAccountId)
+			}
+			gsaAccountIds[gsaAccountId] = w.Key()
+		}
+
+		projectRoles := make([]*workloadidentity.ProjectRole, 0)
+		for _, r := range w.ProjectRoles {
+			projectRoles = append(projectRoles, &workloadidentity.ProjectRole{Role: r.Role, ProjectId: r.ProjectId})
+		}
+
+		createdWorkloadIdentity, err := workloadidentity.New(ctx,
+			fmt.Sprintf("workload-identity-%s-%s", w.Namespace, w.KsaName),
+			&workloadidentity.Args{
+				ProjectId:        createdCluster.Project,
+				Namespace:        createdNamespace.Metadata.Name().Elem(),
+				KsaName:          pulumi.String(w.KsaName),
+				KsaLabels:        locals.KubernetesLabels,
+				GsaAccountId:     gsaAccountId,
+				GsaDescription:   fmt.Sprintf("workload identity for %s kubernetes service account", w.Key()),
+				ExistingGsaEmail: w.ExistingGsaEmail,
+				ProjectRoles:     projectRoles,
+				MovedFrom: &workloadidentity.MovedFrom{
+					GsaParent: createdCluster,
+					KsaParent: createdNamespace,
+				},
+			}, pulumi.Providers(gcpProvider, kubernetesProvider))
+		if err != nil {
+			return errors.Wrapf(err, "failed to create workload identity for %s", w.Key())
+		}
+
+		gsaEmails[w.Key()] = createdWorkloadIdentity.GsaEmail
+	}
+
+	//export gsa emails of all the workload identities
+	ctx.Export(outputs.WorkloadIdentityGsaEmails, gsaEmails)
+
+	return nil
+}
@@ -0,0 +1,304 @@
+// Package workloadidentity provides a pulumi component that lets a kubernetes service account (KSA) act as a google
+// service account (GSA) through GKE workload identity.
+//
+// the component needs a gcp and a kubernetes provider, which are passed with pulumi.Providers, ex:
+//
+//	workloadidentity.New(ctx, "orders-api", &workloadidentity.Args{...}, pulumi.Providers(gcpProvider, kubernetesProvider))
+package workloadidentity
+
+import (
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/projects"
+	"github.com/pulumi/pulumi-gcp/sdk/v7/go/gcp/serviceaccount"
+	corev1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/core/v1"
+	metav1 "github.com/pulumi/pulumi-kubernetes/sdk/v4/go/kubernetes/meta/v1"
+	"github.com/pulumi/pulumi/sdk/v3/go/pulumi"
+	"strings"
+)
+
+const (
+	// ResourceType is the pulumi type token of the component.
+	ResourceType = "planton:gke:WorkloadIdentity"
+	// WorkloadIdentityUserRole allows the KSA to act as the GSA.
+	WorkloadIdentityUserRole = "roles/iam.workloadIdentityUser"
+)
+
+// Args are the inputs of the workload identity component.
+type Args struct {
+	// ProjectId is the project of the GKE cluster, which is also the project of the workload identity pool and of
+	// the created GSA.
+	ProjectId pulumi.StringInput
+	// Namespace of the KSA.
+	Namespace pulumi.StringInput
+	// CreateNamespace creates the namespace as part of the component. the namespace must exist otherwise.
+	CreateNamespace bool
+	// NamespaceLabels are added to the namespace when it is created by the component.
+	NamespaceLabels map[string]string
+	KsaName         pulumi.StringInput
+	// KsaLabels are added to the KSA.
+	KsaLabels map[string]string
+
+	// GsaAccountId is the account id of the GSA to be created. required unless ExistingGsaEmail is set.
+	GsaAccountId   string
+	GsaDescription string
+	// GsaDisplayName defaults to the account id.
+	GsaDisplayName string
+	// ExistingGsaEmail is the email of an existing user managed GSA to bind the KSA to. no GSA is created when set.
+	ExistingGsaEmail string
+
+	// ProjectRoles are granted to the GSA.
+	ProjectRoles []*ProjectRole
+
+	// MovedFrom adopts resources that were created before the component was used, without replacing them.
+	MovedFrom *MovedFrom
+}
+
+// MovedFrom has the parents of resources created without the component. the resources must have been named the
+// same way as the component names them.
+type MovedFrom struct {
+	// GsaParent is the previous parent of the GSA, or of the iam resources when an existing GSA is used.
+	GsaParent pulumi.Resource
+	// KsaParent is the previous parent of the KSA.
+	KsaParent pulumi.Resource
+	// KsaParentUrn is the urn of the previous parent of the KSA, for a parent that has itself moved since.
+	//takes precedence over KsaParent.
+	KsaParentUrn pulumi.URNInput
+}
+
+// ProjectRole is an iam role to be granted to the GSA in a project.
+type ProjectRole struct {
+	Role string
+	// ProjectId defaults to the project of the component.
+	ProjectId string
+}
+
+// WorkloadIdentity is a KSA that acts as a GSA through workload identity.
+type WorkloadIdentity struct {
+	pulumi.ResourceState
+
+	GsaEmail pulumi.StringOutput
+	// GsaId is the fully qualified id of the GSA, ex: "projects/my-project/serviceAccounts/app@my-project.iam.gserviceaccount.com".
+	GsaId     pulumi.StringOutput
+	Namespace pulumi.StringOutput
+	KsaName   pulumi.StringOutput
+
+	// GoogleServiceAccount is the created GSA, nil when an existing GSA is used.
+	GoogleServiceAccount *serviceaccount.Account
+	// CreatedNamespace is nil unless the namespace is created by the component.
+	CreatedNamespace         *corev1.Namespace
+	KubernetesServiceAccount *corev1.ServiceAccount
+}
+
+// New creates the workload identity component.
+//
+// The function performs the following steps:
+// 1. Creates a Google Service Account (GSA) unless an existing GSA email is provided.
+// 2. Grants the GSA the project roles, in the project of the component or in the project specified for the role.
+// 3. Creates a Workload Identity binding for the GSA to allow it to act as the Kubernetes Service Account (KSA).
+// the binding is authoritative for a created GSA and additive for an existing GSA.
+// 4. Creates the namespace when CreateNamespace is set.
+// 5. Creates the KSA and adds the Google Workload Identity annotation with the GSA email.
+//
+// the resources are named after the component, ex: "<name>" for the GSA and KSA and "<name>-workload-identity"
+// for the binding.
+func New(ctx *pulumi.Context, name string, args *Args, opts ...pulumi.ResourceOption) (*WorkloadIdentity, error) {
+	if err := args.validate(); err != nil {
+		return nil, errors.Wrapf(err, "invalid args for %s workload identity", name)
+	}
+
+	w := &WorkloadIdentity{}
+	if err := ctx.RegisterComponentResource(ResourceType, name, w, opts...); err != nil {
+		return nil, errors.Wrapf(err, "failed to register %s workload identity", name)
+	}
+
+	//iam resources are created as children of the created gsa to keep them grouped with it
+	var iamParent pulumi.Resource = w
+	if args.ExistingGsaEmail != "" {
+		w.GsaEmail = pulumi.String(args.ExistingGsaEmail).ToStringOutput()
+		w.GsaId = pulumi.Sprintf("projects/%s/serviceAccounts/%s",
+			existingGsaProjectId(args.ExistingGsaEmail), args.ExistingGsaEmail)
+	} else {
+		displayName := args.GsaDisplayName
+		if displayName == "" {
+			displayName = args.GsaAccountId
+		}
+		createdGoogleServiceAccount, err := serviceaccount.NewAccount(ctx,
+			name,
+			&serviceaccount.AccountArgs{
+				Project:     args.ProjectId,
+				Description: pulumi.String(args.GsaDescription),
+				AccountId:   pulumi.String(args.GsaAccountId),
+				DisplayName: pulumi.String(displayName),
+			}, pulumi.Parent(w), args.movedFromGsaParent())
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create google service account")
+		}
+		w.GoogleServiceAccount = createdGoogleServiceAccount
+		w.GsaEmail = createdGoogleServiceAccount.Email
+		w.GsaId = createdGoogleServiceAccount.Name
+		iamParent = createdGoogleServiceAccount
+	}
+
+	//iam resources of a created gsa move along with the gsa, the ones of an existing gsa need their own aliases
+	iamAliases := pulumi.Aliases(nil)
+	if w.GoogleServiceAccount == nil {
+		iamAliases = args.movedFromGsaParent()
+	}
+
+	//grant project roles to the google service account.
+	//iam-member is used instead of iam-binding as the roles can be granted to others as well.
+	for _, r := range args.ProjectRoles {
+		project := args.ProjectId
+		iamMemberName := fmt.Sprintf("%s-%s", name, strings.TrimPrefix(r.Role, "roles/"))
+		if r.ProjectId != "" {
+			project = pulumi.String(r.ProjectId)
+			iamMemberName = fmt.Sprintf("%s-%s", iamMemberName, r.ProjectId)
+		}
+		_, err := projects.NewIAMMember(ctx,
+			iamMemberName,
+			&projects.IAMMemberArgs{
+				Member:  pulumi.Sprintf("serviceAccount:%s", w.GsaEmail),
+				Project: project,
+				Role:    pulumi.String(r.Role),
+			}, pulumi.Parent(iamParent), iamAliases)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to grant %s role", r.Role)
+		}
+	}
+
+	//create workload-identity binding
+	workloadIdentityMember := pulumi.Sprintf("serviceAccount:%s.svc.id.goog[%s/%s]",
+		args.ProjectId, args.Namespace, args.KsaName)
+	if w.GoogleServiceAccount != nil {
+		//the gsa is owned by the component, so the binding can be authoritative
+		_, err := serviceaccount.NewIAMBinding(ctx,
+			fmt.Sprintf("%s-workload-identity", name),
+			&serviceaccount.IAMBindingArgs{
+				ServiceAccountId: w.GsaId,
+				Role:             pulumi.String(WorkloadIdentityUserRole),
+				Members:          pulumi.StringArray{workloadIdentityMember},
+			}, pulumi.Parent(iamParent))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create workload-identity binding")
+		}
+	} else {
+		_, err := serviceaccount.NewIAMMember(ctx,
+			fmt.Sprintf("%s-workload-identity", name),
+			&serviceaccount.IAMMemberArgs{
+				ServiceAccountId: w.GsaId,
+				Role:             pulumi.String(WorkloadIdentityUserRole),
+				Member:           workloadIdentityMember,
+			}, pulumi.Parent(iamParent), iamAliases)
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create workload-identity binding")
+		}
+	}
+
+	var ksaParent pulumi.Resource = w
+	ksaAliases := args.movedFromKsaParent()
+	namespace := args.Namespace
+	if args.CreateNamespace {
+		createdNamespace, err := corev1.NewNamespace(ctx,
+			name,
+			&corev1.NamespaceArgs{
+				Metadata: metav1.ObjectMetaPtrInput(
+					&metav1.ObjectMetaArgs{
+						Name:   args.Namespace,
+						Labels: optionalStringMap(args.NamespaceLabels),
+					}),
+			}, pulumi.Parent(w))
+		if err != nil {
+			return nil, errors.Wrap(err, "failed to create namespace")
+		}
+		w.CreatedNamespace = createdNamespace
+		namespace = createdNamespace.Metadata.Name().Elem()
+		ksaParent = createdNamespace
+		ksaAliases = pulumi.Aliases(nil)
+	}
+
+	//create kubernetes service account with the workload identity annotation
+	createdKubernetesServiceAccount, err := corev1.NewServiceAccount(ctx,
+		name,
+		&corev1.ServiceAccountArgs{
+			Metadata: metav1.ObjectMetaPtrInput(
+				&metav1.ObjectMetaArgs{
+					Name:      args.KsaName,
+					Namespace: namespace,
+					Labels:    optionalStringMap(args.KsaLabels),
+					Annotations: pulumi.StringMap{
+						vars.WorkloadIdentityKubeAnnotationKey: w.GsaEmail,
+					},
+				}),
+		}, pulumi.Parent(ksaParent), ksaAliases)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to create kubernetes service account")
+	}
+	w.KubernetesServiceAccount = createdKubernetesServiceAccount
+	w.Namespace = createdKubernetesServiceAccount.Metadata.Namespace().Elem()
+	w.KsaName = createdKubernetesServiceAccount.Metadata.Name().Elem()
+
+	if err := ctx.RegisterResourceOutputs(w, pulumi.Map{
+		"gsaEmail":  w.GsaEmail,
+		"gsaId":     w.GsaId,
+		"namespace": w.Namespace,
+		"ksaName":   w.KsaName,
+	}); err != nil {
+		return nil, errors.Wrap(err, "failed to register outputs")
+	}
+	return w, nil
+}
+
+func (a *Args) validate() error {
+	if a.ProjectId == nil || a.Namespace == nil || a.KsaName == nil {
+		return errors.New("projectId, namespace and ksaName are required")
+	}
+	if a.ExistingGsaEmail == "" && a.GsaAccountId == "" {
+		return errors.New("one of gsaAccountId or existingGsaEmail is required")
+	}
+	if a.ExistingGsaEmail != "" && a.GsaAccountId != "" {
+		return errors.New("gsaAccountId and existingGsaEmail are mutually exclusive")
+	}
+	if a.ExistingGsaEmail != "" && !strings.HasSuffix(a.ExistingGsaEmail, ".iam.gserviceaccount.com") {
+		return errors.Errorf("existingGsaEmail %q is not a user managed service account email", a.ExistingGsaEmail)
+	}
+	for _, r := range a.ProjectRoles {
+		if r.Role == "" {
+			return errors.New("role is required for project roles")
+		}
+	}
+	return nil
+}
+
+func (a *Args) movedFromGsaParent() pulumi.ResourceOption {
+	if a.MovedFrom == nil || a.MovedFrom.GsaParent == nil {
+		return pulumi.Aliases(nil)
+	}
+	return pulumi.Aliases([]pulumi.Alias{{Parent: a.MovedFrom.GsaParent}})
+}
+
+func (a *Args) movedFromKsaParent() pulumi.ResourceOption {
+	if a.MovedFrom != nil && a.MovedFrom.KsaParentUrn != nil {
+		return pulumi.Aliases([]pulumi.Alias{{ParentURN: a.MovedFrom.KsaParentUrn}})
+	}
+	if a.MovedFrom == nil || a.MovedFrom.KsaParent == nil {
+		return pulumi.Aliases(nil)
+	}
+	return pulumi.Aliases([]pulumi.Alias{{Parent: a.MovedFrom.KsaParent}})
+}
+
+// optionalStringMap returns nil for empty maps so that unset labels are not added as empty maps.
+func optionalStringMap(m map[string]string) pulumi.StringMapInput {
+	if len(m) == 0 {
+		return nil
+	}
+	return pulumi.ToStringMap(m)
+}
+
+// existingGsaProjectId returns the project id from the email of a user managed google service account,
+// ex: "app@my-project.iam.gserviceaccount.com" returns "my-project".
+func existingGsaProjectId(gsaEmail string) string {
+	domain := gsaEmail[strings.Index(gsaEmail, "@")+1:]
+	return strings.TrimSuffix(domain, ".iam.gserviceaccount.com")
+}
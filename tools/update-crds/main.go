@@ -0,0 +1,153 @@
+// update-crds vendors the crd manifests that are embedded into the module and records their checksums.
+//
+// the manifests are downloaded from the urls in the vars package. a manifest whose checksum is already recorded must
+// download with the same checksum, which catches content that changed under a fixed url. with -verify, nothing is
+// downloaded and the vendored manifests are only checked against the recorded checksums.
+//
+//	go run ./tools/update-crds [-verify]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"github.com/pkg/errors"
+	"github.com/plantoncloud/gke-cluster-pulumi-module/pkg/vars"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// crdsDir is the directory of the vendored manifests relative to the root of the module.
+var crdsDir = filepath.Join("pkg", "vars", vars.CrdManifestsDir)
+
+func main() {
+	verify := flag.Bool("verify", false, "only verify the vendored manifests against the recorded checksums")
+	flag.Parse()
+
+	var err error
+	if *verify {
+		err = verifyManifests()
+	} else {
+		err = updateManifests()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// updateManifests downloads the manifests, verifies the recorded checksums, removes manifests that are no longer
+// referenced and rewrites the checksums file.
+func updateManifests() error {
+	recorded, err := readChecksums()
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 60 * time.Second}
+
+	checksums := make(map[string]string)
+	for _, m := range vars.CrdManifests() {
+		content, err := download(client, m.DownloadUrl)
+		if err != nil {
+			return errors.Wrapf(err, "failed to download %s", m.DownloadUrl)
+		}
+		checksum := vars.Checksum(content)
+		if expected, ok := recorded[m.Path]; ok && expected != checksum {
+			return errors.Errorf("content of %s changed, checksum is %s but %s is recorded for %s",
+				m.DownloadUrl, checksum, expected, m.Path)
+		}
+		file := filepath.Join(crdsDir, filepath.FromSlash(m.Path))
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			return errors.Wrapf(err, "failed to create directory for %s", m.Path)
+		}
+		if err := os.WriteFile(file, content, 0644); err != nil {
+			return errors.Wrapf(err, "failed to write %s", m.Path)
+		}
+		checksums[m.Path] = checksum
+		log.Printf("vendored %s", m.Path)
+	}
+
+	if err := pruneManifests(checksums); err != nil {
+		return err
+	}
+	return writeChecksums(checksums)
+}
+
+// verifyManifests checks that every manifest is vendored and matches its recorded checksum.
+func verifyManifests() error {
+	recorded, err := readChecksums()
+	if err != nil {
+		return err
+	}
+	for _, m := range vars.CrdManifests() {
+		expected, ok := recorded[m.Path]
+		if !ok {
+			return errors.Errorf("no checksum is recorded for %s", m.Path)
+		}
+		content, err := os.ReadFile(filepath.Join(crdsDir, filepath.FromSlash(m.Path)))
+		if err != nil {
+			return errors.Wrapf(err, "failed to read %s", m.Path)
+		}
+		if checksum := vars.Checksum(content); checksum != expected {
+			return errors.Errorf("checksum of %s is %s but %s is recorded", m.Path, checksum, expected)
+		}
+	}
+	log.Printf("verified %d crd manifests", len(recorded))
+	return nil
+}
+
+func download(client *http.Client, url string) ([]byte, error) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, errors.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(resp.Body)
+}
+
+// pruneManifests removes vendored manifests that are not in the checksums, ex: manifests of a previous version.
+func pruneManifests(checksums map[string]string) error {
+	return filepath.Walk(crdsDir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() || info.Name() == vars.CrdManifestChecksumsFile {
+			return err
+		}
+		relativePath, err := filepath.Rel(crdsDir, file)
+		if err != nil {
+			return err
+		}
+		if _, ok := checksums[filepath.ToSlash(relativePath)]; ok {
+			return nil
+		}
+		log.Printf("removing %s", relativePath)
+		return os.Remove(file)
+	})
+}
+
+func readChecksums() (map[string]string, error) {
+	content, err := os.ReadFile(filepath.Join(crdsDir, vars.CrdManifestChecksumsFile))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, errors.Wrap(err, "failed to read checksums")
+	}
+	return vars.ParseChecksums(content), nil
+}
+
+func writeChecksums(checksums map[string]string) error {
+	paths := make([]string, 0)
+	for p := range checksums {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+	var b strings.Builder
+	for _, p := range paths {
+		fmt.Fprintf(&b, "%s  %s\n", checksums[p], p)
+	}
+	return errors.Wrap(os.WriteFile(filepath.Join(crdsDir, vars.CrdManifestChecksumsFile), []byte(b.String()), 0644),
+		"failed to write checksums")
+}